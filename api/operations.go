@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	microTypes "github.com/canonical/microcluster/v3/microcluster/types"
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/microcloud/microcloud/service"
+)
+
+// OperationCmd represents the /1.0/operations/{id} API on MicroCloud.
+var OperationCmd = func(sh *service.Handler) microTypes.Endpoint {
+	return microTypes.Endpoint{
+		AllowedBeforeInit: true,
+		Name:              "operations/{id}",
+		Path:              "operations/{id}",
+
+		Get:    microTypes.EndpointAction{Handler: authHandlerMTLS(sh, operationGet(sh))},
+		Delete: microTypes.EndpointAction{Handler: authHandlerMTLS(sh, operationDelete(sh))},
+	}
+}
+
+// OperationWaitCmd represents the /1.0/operations/{id}/wait API on MicroCloud.
+var OperationWaitCmd = func(sh *service.Handler) microTypes.Endpoint {
+	return microTypes.Endpoint{
+		AllowedBeforeInit: true,
+		Name:              "operations/{id}/wait",
+		Path:              "operations/{id}/wait",
+
+		Get: microTypes.EndpointAction{Handler: authHandlerMTLS(sh, operationWaitGet(sh))},
+	}
+}
+
+// operationFromRequest returns the operation referenced by the request's path.
+func operationFromRequest(sh *service.Handler, r *http.Request) (*service.Operation, error) {
+	id, err := url.PathUnescape(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	return sh.Operations.Get(id)
+}
+
+// operationGet returns the current state of an operation.
+func operationGet(sh *service.Handler) func(state microTypes.State, r *http.Request) microTypes.Response {
+	return func(state microTypes.State, r *http.Request) microTypes.Response {
+		op, err := operationFromRequest(sh, r)
+		if err != nil {
+			return microTypes.SmartError(err)
+		}
+
+		return microTypes.SyncResponse(true, op.Get())
+	}
+}
+
+// operationDelete cancels an operation.
+func operationDelete(sh *service.Handler) func(state microTypes.State, r *http.Request) microTypes.Response {
+	return func(state microTypes.State, r *http.Request) microTypes.Response {
+		op, err := operationFromRequest(sh, r)
+		if err != nil {
+			return microTypes.SmartError(err)
+		}
+
+		op.Cancel()
+
+		return microTypes.EmptySyncResponse
+	}
+}
+
+// operationWaitGet waits until an operation finished and returns its state.
+// The timeout query parameter limits the number of seconds to wait, after which the current state is returned.
+func operationWaitGet(sh *service.Handler) func(state microTypes.State, r *http.Request) microTypes.Response {
+	return func(state microTypes.State, r *http.Request) microTypes.Response {
+		op, err := operationFromRequest(sh, r)
+		if err != nil {
+			return microTypes.SmartError(err)
+		}
+
+		ctx := r.Context()
+		timeout := r.URL.Query().Get("timeout")
+		if timeout != "" {
+			seconds, err := strconv.Atoi(timeout)
+			if err != nil || seconds < 0 {
+				return microTypes.BadRequest(fmt.Errorf("Invalid timeout %q", timeout))
+			}
+
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(seconds)*time.Second)
+			defer cancel()
+		}
+
+		// The operation's error is part of its state.
+		result, _ := op.Wait(ctx)
+
+		return microTypes.SyncResponse(true, result)
+	}
+}
+
+// operationResponse returns the state of the given operation if the request sets the async query parameter.
+// Otherwise it waits for the operation to finish and returns its result, which keeps the API compatible with older clients.
+// Such clients cannot track the operation anymore once they went away, so the operation gets cancelled in that case.
+func operationResponse(r *http.Request, op *service.Operation) microTypes.Response {
+	if r.URL.Query().Get("async") == "1" {
+		return microTypes.SyncResponse(true, op.Get())
+	}
+
+	select {
+	case <-op.Done():
+	case <-r.Context().Done():
+		op.Cancel()
+		return microTypes.SmartError(context.Cause(r.Context()))
+	}
+
+	err := op.Err()
+	if err != nil {
+		return microTypes.SmartError(err)
+	}
+
+	return microTypes.EmptySyncResponse
+}
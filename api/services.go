@@ -4,10 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/canonical/lxd/lxd/util"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
 	microTypes "github.com/canonical/microcluster/v3/microcluster/types"
 
 	"github.com/canonical/microcloud/microcloud/api/types"
@@ -21,53 +26,189 @@ var ServicesCmd = func(sh *service.Handler) microTypes.Endpoint {
 		Name:              "services",
 		Path:              "services",
 
-		Put: microTypes.EndpointAction{Handler: authHandlerMTLS(sh, servicesPut), ProxyTarget: true},
+		Put: microTypes.EndpointAction{Handler: authHandlerMTLS(sh, servicesPut(sh)), ProxyTarget: true},
 	}
 }
 
 // servicesPut updates the cluster status of the MicroCloud peer.
-func servicesPut(state microTypes.State, r *http.Request) microTypes.Response {
-	// Parse the request.
-	req := types.ServicesPut{}
+// The services are joined in the background as an operation, see operationResponse.
+func servicesPut(sh *service.Handler) func(state microTypes.State, r *http.Request) microTypes.Response {
+	return func(state microTypes.State, r *http.Request) microTypes.Response {
+		// Parse the request.
+		req := types.ServicesPut{}
 
-	err := json.NewDecoder(r.Body).Decode(&req)
-	if err != nil {
-		return microTypes.BadRequest(err)
-	}
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			return microTypes.BadRequest(err)
+		}
 
-	joinConfigs := map[types.ServiceType]service.JoinConfig{}
-	services := make([]types.ServiceType, len(req.Tokens))
-	for i, cfg := range req.Tokens {
-		services[i] = types.ServiceType(cfg.Service)
-		joinConfigs[cfg.Service] = service.JoinConfig{Token: cfg.JoinToken, LXDConfig: req.LXDConfig, CephConfig: req.CephConfig, OVNConfig: req.OVNConfig}
-	}
+		joinConfigs := map[types.ServiceType]service.JoinConfig{}
+		services := make([]types.ServiceType, len(req.Tokens))
+		for i, cfg := range req.Tokens {
+			services[i] = types.ServiceType(cfg.Service)
+			joinConfigs[cfg.Service] = service.JoinConfig{Token: cfg.JoinToken, LXDConfig: req.LXDConfig, CephConfig: req.CephConfig, OVNConfig: req.OVNConfig}
+		}
 
-	// Default to the first iface if none specified.
-	addr := util.NetworkInterfaceAddress()
-	if req.Address != "" {
-		addr = req.Address
-	}
+		// Default to the first iface if none specified.
+		addr := util.NetworkInterfaceAddress()
+		if req.Address != "" {
+			addr = req.Address
+		}
 
-	sh, err := service.NewHandler(state.Name(), addr, state.FileSystem().StateDir(), services...)
-	if err != nil {
-		return microTypes.SmartError(err)
+		joinHandler, err := service.NewHandler(state.Name(), addr, state.FileSystem().StateDir(), services...)
+		if err != nil {
+			return microTypes.SmartError(err)
+		}
+
+		// The system which requested the join is preferred to roll it back.
+		// Requests through the unix socket, e.g. when using an offline join bundle, don't have one.
+		requester := ""
+		if r.RemoteAddr != "@" {
+			requester = requestSource(r)
+		}
+
+		op := sh.Operations.Start("Joining services", services, func(ctx context.Context, op *service.Operation) error {
+			return joinServices(ctx, sh, joinHandler, requester, joinConfigs, op)
+		})
+
+		return operationResponse(r, op)
 	}
+}
+
+// joinServices joins each service of the given handler using the respective join config.
+// If any service fails to join, the system is removed again from all services it joined or tried to join.
+// The removal is requested through the given system which requested the join if it's a MicroCloud cluster member,
+// otherwise through any other member of the cluster.
+func joinServices(ctx context.Context, sh *service.Handler, joinHandler *service.Handler, requester string, joinConfigs map[types.ServiceType]service.JoinConfig, op *service.Operation) error {
+	// Keep track of the services which the system tried to join, and whether it succeeded, so they can be rolled back
+	// in case any other service fails. Services which failed to join are rolled back too as their cluster might already
+	// know about the system.
+	attemptedMu := sync.Mutex{}
+	attempted := make(map[types.ServiceType]bool, len(joinHandler.Services))
+	err := joinHandler.RunConcurrent(service.RunDependenciesFirst, func(s service.Service) error {
+		op.SetStage(s.Type(), types.OperationStageRunning)
+
+		attemptedMu.Lock()
+		attempted[s.Type()] = false
+		attemptedMu.Unlock()
 
-	err = sh.RunConcurrent(types.MicroCloud, types.LXD, func(s service.Service) error {
 		// set a 5 minute context for completing the join request in case the system is very slow.
-		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 		defer cancel()
 
-		err = s.Join(ctx, joinConfigs[s.Type()])
+		err := s.Join(ctx, joinConfigs[s.Type()])
 		if err != nil {
+			op.SetStage(s.Type(), types.OperationStageFailed)
 			return fmt.Errorf("Failed to join %q cluster: %w", s.Type(), err)
 		}
 
+		op.SetStage(s.Type(), types.OperationStageDone)
+
+		attemptedMu.Lock()
+		attempted[s.Type()] = true
+		attemptedMu.Unlock()
+
+		return nil
+	})
+	if err != nil {
+		rollbackErr := rollbackJoin(sh, joinHandler, requester, attempted, op)
+		if rollbackErr != nil {
+			return fmt.Errorf("%w. Failed to roll back joined services, the system might be left as a dangling cluster member and has to be removed using \"microcloud remove %s --force\" on another cluster member: %w", err, joinHandler.Name, rollbackErr)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// rollbackJoin removes the local system from the given services through another member of the MicroCloud cluster.
+// The given services map to whether the system succeeded to join them.
+// The local system cannot remove itself as its membership might be incomplete after failing to join.
+// Services are removed in the reverse order of their dependencies, and a service is kept if removing the system
+// from any service depending on it failed. The returned error contains every service on which the rollback failed.
+func rollbackJoin(sh *service.Handler, joinHandler *service.Handler, requester string, attempted map[types.ServiceType]bool, op *service.Operation) error {
+	// The operation might already be cancelled, so use a new context to not leave the system half-clustered.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	address, err := rollbackAddress(ctx, sh, requester)
+	if err != nil {
+		return err
+	}
+
+	return joinHandler.RunConcurrent(service.RunDependentsFirst, func(s service.Service) error {
+		joined, ok := attempted[s.Type()]
+		if !ok {
+			return nil
+		}
+
+		logger.Warn("Rolling back join of service", logger.Ctx{"service": s.Type(), "name": s.Name(), "address": address})
+
+		err := leaveCluster(ctx, s, address)
+		if err != nil {
+			return fmt.Errorf("Failed to remove %q from the %s cluster: %w", s.Name(), s.Type(), err)
+		}
+
+		// Keep the stage of the service which failed to join.
+		if joined {
+			op.SetStage(s.Type(), types.OperationStageRolledBack)
+		}
+
 		return nil
 	})
+}
+
+// rollbackAddress returns the address of the MicroCloud cluster member through which the local system gets removed
+// from the services it tried to join. The system which requested the join is preferred.
+func rollbackAddress(ctx context.Context, sh *service.Handler, requester string) (string, error) {
+	members, err := sh.Services[types.MicroCloud].ClusterMembers(ctx)
 	if err != nil {
-		return microTypes.SmartError(err)
+		return "", fmt.Errorf("Failed to get %s cluster members: %w", types.MicroCloud, err)
+	}
+
+	addresses := make([]string, 0, len(members))
+	for name, address := range members {
+		if name == sh.Name {
+			continue
+		}
+
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return "", fmt.Errorf("Invalid address %q of cluster member %q: %w", address, name, err)
+		}
+
+		if host == requester {
+			return host, nil
+		}
+
+		addresses = append(addresses, host)
+	}
+
+	if len(addresses) == 0 {
+		return "", fmt.Errorf("There isn't any other %s cluster member to remove the system through", types.MicroCloud)
+	}
+
+	slices.Sort(addresses)
+
+	return addresses[0], nil
+}
+
+// leaveCluster removes the local system from the cluster of the given service through the MicroCloud at the given address.
+// A member which cannot be removed gracefully, e.g. because it never finished joining, is removed forcefully.
+// It's not an error if the service's cluster doesn't know about the system.
+func leaveCluster(ctx context.Context, s service.Service, address string) error {
+	err := s.DeleteClusterMember(ctx, s.Name(), address, false)
+	if err == nil || api.StatusErrorCheck(err, http.StatusNotFound) {
+		return nil
+	}
+
+	logger.Warn("Forcefully removing dangling cluster member", logger.Ctx{"service": s.Type(), "name": s.Name(), "err": err})
+
+	forceErr := s.DeleteClusterMember(ctx, s.Name(), address, true)
+	if forceErr == nil || api.StatusErrorCheck(forceErr, http.StatusNotFound) {
+		return nil
 	}
 
-	return microTypes.EmptySyncResponse
+	return fmt.Errorf("%w (forced removal failed too: %w)", err, forceErr)
 }
@@ -3,13 +3,17 @@ package api
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/canonical/lxd/lxd/util"
+	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/trust"
 	"github.com/canonical/microcluster/v3/microcluster/types"
 
+	apiTypes "github.com/canonical/microcloud/microcloud/api/types"
 	"github.com/canonical/microcloud/microcloud/service"
 )
 
@@ -51,29 +55,31 @@ func authHandlerMTLS(sh *service.Handler, f endpointHandler) endpointHandler {
 // authHandlerHMAC ensures a request has been authenticated using the HMAC in the Authorization header.
 func authHandlerHMAC(sh *service.Handler, f endpointHandler) endpointHandler {
 	return func(s types.State, r *http.Request) types.Response {
+		source := requestSource(r)
+
+		var failures []apiTypes.SessionLogEntry
 		sessionFunc := func(session *service.Session) error {
-			h, err := trust.NewHMACArgon2([]byte(session.Passphrase()), nil, trust.NewDefaultHMACConf(HMACMicroCloud10))
+			// Reject banned sources before verifying the HMAC which is expensive to compute.
+			// Their attempts don't count towards the session's total number of failed attempts.
+			err := checkSourceBanned(session, source)
 			if err != nil {
 				return err
 			}
 
-			err = trust.HMACEqual(h, r)
+			// A session restored after a restart only knows the hash of its passphrase until a client resumes it.
+			passphrase := session.Passphrase()
+			if passphrase == "" {
+				return api.NewStatusError(http.StatusServiceUnavailable, "The interrupted session has to be resumed before accepting joiners")
+			}
+
+			h, err := trust.NewHMACArgon2([]byte(passphrase), nil, trust.NewDefaultHMACConf(HMACMicroCloud10))
 			if err != nil {
-				attemptErr := session.RegisterFailedAttempt()
-				if attemptErr != nil {
-					errorCause := errors.New("Stopping session after too many failed attempts")
-
-					// Immediately stop the session to not allow further join attempts.
-					stopErr := session.Stop(errorCause)
-					if stopErr != nil {
-						return fmt.Errorf("Cannot stop session after too many failed attempts: %w", stopErr)
-					}
-
-					// Log the error and return it to the caller
-					logger.Warn(errorCause.Error())
-					return errorCause
-				}
+				return err
+			}
 
+			err = trust.HMACEqual(h, r)
+			if err != nil {
+				failures, err = registerFailedAttempt(session, source, r.RemoteAddr, err)
 				return err
 			}
 
@@ -82,6 +88,12 @@ func authHandlerHMAC(sh *service.Handler, f endpointHandler) endpointHandler {
 
 		// Run a r/w transaction against the session as we might stop it due to too many failed attempts.
 		err := sh.SessionTransaction(false, sessionFunc)
+
+		// Record the failures outside of the transaction to not block the session while writing to the database.
+		for _, failure := range failures {
+			sh.SessionLog.Record(r.Context(), s, failure)
+		}
+
 		if err != nil {
 			return types.SmartError(err)
 		}
@@ -89,3 +101,63 @@ func authHandlerHMAC(sh *service.Handler, f endpointHandler) endpointHandler {
 		return f(s, r)
 	}
 }
+
+// requestSource returns the host which sent the given request.
+// Failed attempts are tracked per source host as the port changes with each connection.
+func requestSource(r *http.Request) string {
+	source, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return source
+}
+
+// checkSourceBanned returns an error if the given source is banned from the session due to too many failed attempts.
+func checkSourceBanned(session *service.Session, source string) error {
+	bannedUntil, banned := session.SourceBannedUntil(source)
+	if banned {
+		return api.StatusErrorf(http.StatusTooManyRequests, "Too many failed attempts, retry after %s", bannedUntil.UTC().Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// registerFailedAttempt registers the failed authentication of the given source with the session.
+// It returns the entries to record in the session log together with the error to return to the caller.
+// The session is stopped if the total number of failed attempts is exceeded.
+func registerFailedAttempt(session *service.Session, source string, remoteAddr string, cause error) ([]apiTypes.SessionLogEntry, error) {
+	failures := []apiTypes.SessionLogEntry{{
+		Role:    session.Role(),
+		Event:   apiTypes.SessionEventHMACFailure,
+		Address: remoteAddr,
+		Message: cause.Error(),
+	}}
+
+	banned, attemptErr := session.RegisterFailedAttempt(source)
+	if banned {
+		logger.Warn("Banning source after too many failed attempts", logger.Ctx{"source": source, "duration": service.SourceBanDuration})
+		failures = append(failures, apiTypes.SessionLogEntry{
+			Role:    session.Role(),
+			Event:   apiTypes.SessionEventSourceBanned,
+			Address: source,
+			Message: "Banned for " + service.SourceBanDuration.String(),
+		})
+	}
+
+	if attemptErr != nil {
+		errorCause := errors.New("Stopping session after too many failed attempts")
+
+		// Immediately stop the session to not allow further join attempts.
+		stopErr := session.Stop(errorCause)
+		if stopErr != nil {
+			return failures, fmt.Errorf("Cannot stop session after too many failed attempts: %w", stopErr)
+		}
+
+		// Log the error and return it to the caller
+		logger.Warn(errorCause.Error())
+		return failures, errorCause
+	}
+
+	return failures, cause
+}
@@ -1,10 +1,12 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"net/http"
 	"net/url"
+	"sync/atomic"
 
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
@@ -23,141 +25,158 @@ var ServicesClusterCmd = func(sh *service.Handler) microTypes.Endpoint {
 		Name:              "services/cluster/{name}",
 		Path:              "services/cluster/{name}",
 
-		Delete: microTypes.EndpointAction{Handler: authHandlerMTLS(sh, removeClusterMember)},
+		Delete: microTypes.EndpointAction{Handler: authHandlerMTLS(sh, removeClusterMember(sh))},
 	}
 }
 
 // removeClusterMember removes the given cluster member from all services that it exists in.
-func removeClusterMember(state microTypes.State, r *http.Request) microTypes.Response {
-	force := r.URL.Query().Get("force") == "1"
-	name, err := url.PathUnescape(mux.Vars(r)["name"])
-	if err != nil {
-		return microTypes.BadRequest(err)
-	}
-
-	supportedServices := map[types.ServiceType]string{
-		types.MicroOVN:  MicroOVNDir,
-		types.MicroCeph: MicroCephDir,
-		types.LXD:       LXDDir,
-	}
-
-	existingServices := []types.ServiceType{types.MicroCloud}
-	for serviceType, stateDir := range supportedServices {
-		if service.Exists(serviceType, stateDir) {
-			existingServices = append(existingServices, serviceType)
+// The member is removed in the background as an operation, see operationResponse.
+func removeClusterMember(sh *service.Handler) func(state microTypes.State, r *http.Request) microTypes.Response {
+	return func(state microTypes.State, r *http.Request) microTypes.Response {
+		force := r.URL.Query().Get("force") == "1"
+		name, err := url.PathUnescape(mux.Vars(r)["name"])
+		if err != nil {
+			return microTypes.BadRequest(err)
 		}
-	}
 
-	addr, _, err := net.SplitHostPort(state.Address().Host)
-	if err != nil {
-		return microTypes.SmartError(fmt.Errorf("State address %q is invalid: %w", state.Address().String(), err))
-	}
+		existingServices := service.InstalledServices()
 
-	sh, err := service.NewHandler(state.Name(), addr, state.FileSystem().StateDir(), existingServices...)
-	if err != nil {
-		return microTypes.SmartError(err)
-	}
+		addr, _, err := net.SplitHostPort(state.Address().Host)
+		if err != nil {
+			return microTypes.SmartError(fmt.Errorf("State address %q is invalid: %w", state.Address().String(), err))
+		}
 
-	ceph := sh.Services[types.MicroCeph]
-	if ceph != nil {
-		// If we got a 503 error back, that means the service is installed, but hasn't been set up yet, so there are no cluster members to remove.
-		cluster, err := ceph.ClusterMembers(r.Context())
-		if err != nil && !api.StatusErrorCheck(err, http.StatusServiceUnavailable) {
+		memberHandler, err := service.NewHandler(state.Name(), addr, state.FileSystem().StateDir(), existingServices...)
+		if err != nil {
 			return microTypes.SmartError(err)
 		}
 
-		// We can't remove nodes from a 2 node MicroCeph cluster if that node is still in the monmap,
-		// because MicroCeph does not clean it up properly, thus leaving the cluster broken as it tries to reach the removed node.
-		if err == nil && len(cluster) == 2 && cluster[name] != "" {
-			cephService := ceph.(*service.CephService)
-
-			cephServices, err := cephService.GetServices(r.Context(), "")
-			if err != nil {
+		ceph := memberHandler.Services[types.MicroCeph]
+		if ceph != nil {
+			// If we got a 503 error back, that means the service is installed, but hasn't been set up yet, so there are no cluster members to remove.
+			cluster, err := ceph.ClusterMembers(r.Context())
+			if err != nil && !api.StatusErrorCheck(err, http.StatusServiceUnavailable) {
 				return microTypes.SmartError(err)
 			}
 
-			for _, service := range cephServices {
-				if service.Location == name && service.Service == "mon" {
-					return microTypes.SmartError(fmt.Errorf("%q must be removed from the Ceph monmap before it can be removed from MicroCloud", name))
+			// We can't remove nodes from a 2 node MicroCeph cluster if that node is still in the monmap,
+			// because MicroCeph does not clean it up properly, thus leaving the cluster broken as it tries to reach the removed node.
+			if err == nil && len(cluster) == 2 && cluster[name] != "" {
+				cephService := ceph.(*service.CephService)
+
+				cephServices, err := cephService.GetServices(r.Context(), "")
+				if err != nil {
+					return microTypes.SmartError(err)
+				}
+
+				for _, service := range cephServices {
+					if service.Location == name && service.Service == "mon" {
+						return microTypes.SmartError(fmt.Errorf("%q must be removed from the Ceph monmap before it can be removed from MicroCloud", name))
+					}
 				}
 			}
 		}
+
+		op := sh.Operations.Start(fmt.Sprintf("Removing cluster member %q", name), existingServices, func(ctx context.Context, op *service.Operation) error {
+			return removeServicesMember(ctx, memberHandler, name, force, op)
+		})
+
+		return operationResponse(r, op)
 	}
+}
 
-	// Remove the node from services in the following order:
+// removeServicesMember removes the given cluster member from each service of the given handler.
+func removeServicesMember(ctx context.Context, sh *service.Handler, name string, force bool, op *service.Operation) error {
+	// Remove the node from services before removing it from their dependencies:
 	// 1. Remove from LXD first as it may have storage & networks that depend on the others for cleanup.
 	// 2. Remove from MicroCeph and MicroOVN next, concurrently.
 	// 3. Remove from MicroCloud last so that if there were any errors causing the other services to fail, MicroCloud will still know about the node.
-	var memberExists bool
-	err = sh.RunConcurrent(types.LXD, types.MicroCloud, func(s service.Service) error {
-		existingMembers, err := s.ClusterMembers(r.Context())
-		if err != nil && !api.StatusErrorCheck(err, http.StatusServiceUnavailable) {
-			return err
+	var memberExists atomic.Bool
+	err := sh.RunConcurrent(service.RunDependentsFirst, func(s service.Service) error {
+		op.SetStage(s.Type(), types.OperationStageRunning)
+
+		removed, err := removeServiceMember(ctx, s, name, force)
+		if removed {
+			memberExists.Store(true)
 		}
 
-		// If we got a 503 error back, that means the service is installed, but hasn't been set up yet, so there are no cluster members to remove.
 		if err != nil {
-			return nil
+			op.SetStage(s.Type(), types.OperationStageFailed)
+			return err
 		}
 
-		// The cluster member may not exist for this service if it was removed manually, so skip removal for that service.
-		_, ok := existingMembers[name]
-		if !ok {
-			logger.Warn("Cluster member not found for service", logger.Ctx{"service": s.Type(), "member": name})
-			return nil
-		}
+		op.SetStage(s.Type(), types.OperationStageDone)
 
-		if !memberExists && ok {
-			memberExists = ok
-		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-		if s.Type() == types.MicroCeph {
-			cephService := ceph.(*service.CephService)
+	if !memberExists.Load() {
+		return api.StatusErrorf(http.StatusNotFound, "Cluster member %q not found on any service", name)
+	}
 
-			disks, err := cephService.GetDisks(r.Context(), "", nil)
-			if err != nil {
-				return err
-			}
+	return nil
+}
 
-			diskCount := 0
-			for _, disk := range disks {
-				if disk.Location != name {
-					diskCount++
-				}
-			}
+// removeServiceMember removes the given cluster member from the given service if it's part of the service's cluster.
+// It returns true if the member is part of the service's cluster.
+func removeServiceMember(ctx context.Context, s service.Service, name string, force bool) (bool, error) {
+	existingMembers, err := s.ClusterMembers(ctx)
+	if err != nil && !api.StatusErrorCheck(err, http.StatusServiceUnavailable) {
+		return false, err
+	}
 
-			pools, err := cephService.GetPools(r.Context(), "")
-			if err != nil {
-				return err
-			}
+	// If we got a 503 error back, that means the service is installed, but hasn't been set up yet, so there are no cluster members to remove.
+	if err != nil {
+		return false, nil
+	}
 
-			poolsToUpdate := []string{}
-			for _, pool := range pools {
-				if pool.Size > int64(diskCount) {
-					poolsToUpdate = append(poolsToUpdate, pool.Pool)
-				}
-			}
+	// The cluster member may not exist for this service if it was removed manually, so skip removal for that service.
+	_, ok := existingMembers[name]
+	if !ok {
+		logger.Warn("Cluster member not found for service", logger.Ctx{"service": s.Type(), "member": name})
+		return false, nil
+	}
+
+	if s.Type() == types.MicroCeph {
+		cephService := s.(*service.CephService)
 
-			// MicroCeph requires to pass an empty string to set the default pool size.
-			if len(poolsToUpdate) == 0 {
-				poolsToUpdate = []string{""}
+		disks, err := cephService.GetDisks(ctx, "", nil)
+		if err != nil {
+			return true, err
+		}
+
+		diskCount := 0
+		for _, disk := range disks {
+			if disk.Location != name {
+				diskCount++
 			}
+		}
+
+		pools, err := cephService.GetPools(ctx, "")
+		if err != nil {
+			return true, err
+		}
 
-			err = cephService.PoolSetReplicationFactor(r.Context(), cephTypes.PoolPut{Pools: poolsToUpdate, Size: int64(diskCount)}, "")
-			if err != nil {
-				return err
+		poolsToUpdate := []string{}
+		for _, pool := range pools {
+			if pool.Size > int64(diskCount) {
+				poolsToUpdate = append(poolsToUpdate, pool.Pool)
 			}
 		}
 
-		return s.DeleteClusterMember(r.Context(), name, force)
-	})
-	if err != nil {
-		return microTypes.SmartError(err)
-	}
+		// MicroCeph requires to pass an empty string to set the default pool size.
+		if len(poolsToUpdate) == 0 {
+			poolsToUpdate = []string{""}
+		}
 
-	if !memberExists {
-		return microTypes.NotFound(fmt.Errorf("Cluster member %q not found on any service", name))
+		err = cephService.PoolSetReplicationFactor(ctx, cephTypes.PoolPut{Pools: poolsToUpdate, Size: int64(diskCount)}, "")
+		if err != nil {
+			return true, err
+		}
 	}
 
-	return microTypes.EmptySyncResponse
+	return true, s.DeleteClusterMember(ctx, name, "", force)
 }
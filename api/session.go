@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/canonical/lxd/lxd/response"
@@ -28,6 +30,10 @@ import (
 // HMACMicroCloud10 is the HMAC format version used during trust establishment.
 const HMACMicroCloud10 trust.HMACVersion = "MicroCloud-1.0"
 
+// multicastHMACSalt is the salt used to derive the key for signing multicast discovery responses.
+// A static salt allows deriving the key only once per session instead of for every received response.
+const multicastHMACSalt = "MicroCloud multicast discovery"
+
 // SessionInitiatingCmd represents the /1.0/session/initiating API on MicroCloud.
 var SessionInitiatingCmd = func(sh *service.Handler) microTypes.Endpoint {
 	return microTypes.Endpoint{
@@ -53,7 +59,8 @@ var SessionJoiningCmd = func(sh *service.Handler) microTypes.Endpoint {
 // sessionGet returns a MicroCloud join session.
 func sessionGet(sh *service.Handler, sessionRole types.SessionRole) func(state microTypes.State, r *http.Request) microTypes.Response {
 	return func(state microTypes.State, r *http.Request) microTypes.Response {
-		if sh.ActiveSession() {
+		// The session interrupted by a restart is either resumed or discarded when starting the new session.
+		if sh.ActiveSession() && !sh.InterruptedSession() {
 			return response.BadRequest(errors.New("There already is an active session"))
 		}
 
@@ -92,6 +99,13 @@ func sessionGet(sh *service.Handler, sessionRole types.SessionRole) func(state m
 			defer cancel()
 
 			gw := cloudClient.NewWebsocketGateway(sessionCtx, conn)
+			gw.Observe(sh.SessionObservers.PublishMessage)
+
+			sh.SessionLog.Record(sessionCtx, state, types.SessionLogEntry{
+				Role:    sessionRole,
+				Event:   types.SessionEventStart,
+				Message: "Session timeout is " + sessionTimeout.String(),
+			})
 
 			switch sessionRole {
 			case types.SessionInitiating:
@@ -100,11 +114,25 @@ func sessionGet(sh *service.Handler, sessionRole types.SessionRole) func(state m
 				err = handleJoiningSession(state, sh, gw)
 			}
 
+			stopEntry := types.SessionLogEntry{
+				Role:  sessionRole,
+				Event: types.SessionEventStop,
+			}
+
+			if err != nil {
+				stopEntry.Message = err.Error()
+			}
+
+			// The session's context might already be cancelled.
+			sh.SessionLog.Record(context.Background(), state, stopEntry)
+
 			// Any errors occurring after the connection got upgraded have to be handled
 			// within the websocket.
 			// When writing a response to the original HTTP connection the server will
 			// complain with "http: connection has been hijacked".
 			if err != nil {
+				sh.SessionObservers.Publish(types.Session{Error: err.Error()})
+
 				controlErr := gw.WriteClose(err)
 				if controlErr != nil {
 					logger.Error("Failed to write close control message", logger.Ctx{"err": controlErr, "controlErr": err})
@@ -132,6 +160,14 @@ func confirmedIntents(sh *service.Handler, gw *cloudClient.WebsocketGateway) ([]
 				return nil, fmt.Errorf("Failed to forward join intent: %w", err)
 			}
 
+		case system := <-sh.Session.IncompatibleSystemCh():
+			err := gw.Write(types.Session{
+				IncompatibleSystem: &system,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("Failed to report incompatible system %q: %w", system.Name, err)
+			}
+
 		case bytes := <-gw.Receive():
 			var session types.Session
 			err := json.Unmarshal(bytes, &session)
@@ -146,6 +182,26 @@ func confirmedIntents(sh *service.Handler, gw *cloudClient.WebsocketGateway) ([]
 	}
 }
 
+// generateSessionPassphrase returns a passphrase consisting of the given number of words from the given wordlist.
+// The default number of words is used if the count is zero.
+func generateSessionPassphrase(stateDir string, wordlist string, count uint8) (string, error) {
+	if count == 0 {
+		count = service.PassphraseWordCount
+	}
+
+	err := service.ValidatePassphraseWordCount(count)
+	if err != nil {
+		return "", err
+	}
+
+	words, err := service.LoadWordlist(stateDir, wordlist)
+	if err != nil {
+		return "", err
+	}
+
+	return service.GeneratePassphrase(words, count)
+}
+
 func handleInitiatingSession(state microTypes.State, sh *service.Handler, gw *cloudClient.WebsocketGateway) error {
 	session := types.Session{}
 	err := gw.ReceiveWithContext(gw.Context(), &session)
@@ -153,7 +209,17 @@ func handleInitiatingSession(state microTypes.State, sh *service.Handler, gw *cl
 		return fmt.Errorf("Failed to read session start message: %w", err)
 	}
 
-	err = sh.StartSession(types.SessionInitiating, session.Passphrase, gw)
+	// Generate the passphrase using the requested wordlist and strength unless it was already given.
+	// When resuming the interrupted session its passphrase is used instead.
+	passphrase := session.Passphrase
+	if passphrase == "" && !session.Resume {
+		passphrase, err = generateSessionPassphrase(state.FileSystem().StateDir(), session.Wordlist, session.PassphraseWords)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = sh.StartSession(types.SessionInitiating, passphrase, session, gw)
 	if err != nil {
 		return fmt.Errorf("Failed to start session: %w", err)
 	}
@@ -165,12 +231,29 @@ func handleInitiatingSession(state microTypes.State, sh *service.Handler, gw *cl
 		}
 	}()
 
-	err = sh.Session.MulticastDiscovery(state.Name(), session.Address, session.Interface)
+	cloud := sh.Services[types.MicroCloud].(*service.CloudService)
+	sessionPassphrase := sh.Session.Passphrase()
+
+	var info multicast.ServerInfo
+	if session.Resume {
+		// Announce the interrupted session again using its configuration now that its passphrase is known.
+		session, info = sh.Session.Announcement()
+	} else {
+		info, err = sessionInfo(gw.Context(), state, sh, session, sessionPassphrase)
+		if err != nil {
+			return err
+		}
+
+		// Reject any intents which aren't pre-approved already when they are registered.
+		sh.Session.AllowFingerprints(session.AllowedFingerprints)
+		sh.Session.SetAnnouncement(session, info)
+	}
+
+	err = startDiscovery(sh, session, info, sessionPassphrase)
 	if err != nil {
-		return fmt.Errorf("Failed to start multicast discovery: %w", err)
+		return err
 	}
 
-	sessionPassphrase := sh.Session.Passphrase()
 	err = gw.Write(types.Session{
 		Passphrase: sessionPassphrase,
 	})
@@ -178,6 +261,16 @@ func handleInitiatingSession(state microTypes.State, sh *service.Handler, gw *cl
 		return fmt.Errorf("Failed to send session details: %w", err)
 	}
 
+	// Offer the join intents received by the interrupted session before it got resumed.
+	for _, intent := range sh.Session.RestoredIntents() {
+		err = gw.Write(types.Session{
+			Intent: intent,
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to forward restored join intent: %w", err)
+		}
+	}
+
 	confirmedIntents, err := confirmedIntents(sh, gw)
 	if err != nil {
 		return fmt.Errorf("Failed waiting for the confirmed intents: %w", err)
@@ -194,15 +287,24 @@ func handleInitiatingSession(state microTypes.State, sh *service.Handler, gw *cl
 
 		// Add system to temporary truststore.
 		sh.Session.Allow(intent.Name, *remoteCert)
+		sh.SessionLog.Record(gw.Context(), state, types.SessionLogEntry{
+			Role:        types.SessionInitiating,
+			Event:       types.SessionEventAccepted,
+			Name:        intent.Name,
+			Address:     intent.Address,
+			Fingerprint: shared.CertFingerprint(remoteCert),
+			Version:     intent.Version,
+		})
 
-		cloud := sh.Services[types.MicroCloud].(*service.CloudService)
 		cert, err := cloud.ServerCert()
 		if err != nil {
 			return fmt.Errorf("Failed to get certificate of %q: %w", types.MicroCloud, err)
 		}
 
+		// Confirm using the version negotiated by the joiner.
 		joinIntent := types.SessionJoinPost{
-			Version:     multicast.Version,
+			Version:     intent.Version,
+			Realm:       info.Realm,
 			Name:        state.Name(),
 			Address:     session.Address,
 			Certificate: string(cert.PublicKey()),
@@ -259,7 +361,16 @@ func handleJoiningSession(state microTypes.State, sh *service.Handler, gw *cloud
 		return fmt.Errorf("Failed to read session start message: %w", err)
 	}
 
-	err = sh.StartSession(types.SessionJoining, session.Passphrase, gw)
+	// Without a passphrase, find the initiator first so the passphrase can be entered using its passphrase settings.
+	var beacon *multicast.Beacon
+	if session.Passphrase == "" {
+		session.Passphrase, beacon, err = requestPassphrase(gw.Context(), state, gw, sh, session)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = sh.StartSession(types.SessionJoining, session.Passphrase, session, gw)
 	if err != nil {
 		return fmt.Errorf("Failed to start session: %w", err)
 	}
@@ -272,28 +383,57 @@ func handleJoiningSession(state microTypes.State, sh *service.Handler, gw *cloud
 	}()
 
 	// No address selected, try to lookup system.
+	// Without lookup, the initiator's versions aren't known so the current version is used.
+	version := multicast.Version
+	var initiatorCert *x509.Certificate
 	if session.InitiatorAddress == "" {
 		lookupCtx, cancel := context.WithTimeoutCause(gw.Context(), session.LookupTimeout, errors.New("Lookup timeout exceeded"))
 		defer cancel()
 
-		discovery := multicast.NewDiscovery(session.Interface, service.CloudMulticastPort)
-		peer, err := discovery.Lookup(lookupCtx, multicast.Version)
+		// Resolve the initiator found while requesting the passphrase instead of waiting for another lookup.
+		// Using static discovery, the initiator wasn't found yet as it has to probe this system.
+		var peer *multicast.LookupEvent
+		if beacon != nil && beacon.Nonce != "" {
+			peer, err = resolveInitiator(lookupCtx, state, gw, sh, session, *beacon)
+		} else {
+			peer, err = lookupInitiator(lookupCtx, state, gw, sh, session)
+		}
+
 		if err != nil {
 			return fmt.Errorf("Failed to lookup eligible system: %w", err)
 		}
 
-		session.InitiatorAddress = peer.Address
+		version = peer.Version
+
+		session.InitiatorAddress = peer.Info.Address
+		initiatorCert = peer.Info.Certificate
 	}
 
 	// Get the remotes name.
 	cloud := sh.Services[types.MicroCloud].(*service.CloudService)
+
+	// If the initiator's address and fingerprint were provided (e.g. using a join URI),
+	// verify the initiator's certificate before sending our intent.
+	if initiatorCert == nil && session.InitiatorFingerprint != "" {
+		initiatorCert, err = cloud.RemoteCertificate(gw.Context(), session.InitiatorAddress)
+		if err != nil {
+			return fmt.Errorf("Failed to get certificate of %q: %w", session.InitiatorAddress, err)
+		}
+
+		fingerprint := shared.CertFingerprint(initiatorCert)
+		if fingerprint != session.InitiatorFingerprint {
+			return fmt.Errorf("Fingerprint %q of %q doesn't match the expected fingerprint %q", fingerprint, session.InitiatorAddress, session.InitiatorFingerprint)
+		}
+	}
+
 	cert, err := cloud.ServerCert()
 	if err != nil {
 		return fmt.Errorf("Failed to get certificate of %q: %w", types.MicroCloud, err)
 	}
 
 	joinIntent := types.SessionJoinPost{
-		Version:     multicast.Version,
+		Version:     version,
+		Realm:       sessionRealm(sh, session),
 		Name:        state.Name(),
 		Address:     session.Address,
 		Certificate: string(cert.PublicKey()),
@@ -312,8 +452,14 @@ func handleJoiningSession(state microTypes.State, sh *service.Handler, gw *cloud
 
 	conf := cloudClient.AuthConfig{
 		HMAC: header,
-		// The certificate of the initiator isn't yet known so we have to skip any TLS verification.
-		InsecureSkipVerify: true,
+	}
+
+	// The certificate of the initiator is known if its signed info was fetched during lookup.
+	// Otherwise we have to skip any TLS verification.
+	if initiatorCert != nil {
+		conf.TLSServerCertificate = initiatorCert
+	} else {
+		conf.InsecureSkipVerify = true
 	}
 
 	peerCert, err := cloud.RequestJoinIntent(context.Background(), session.InitiatorAddress, conf, joinIntent)
@@ -380,6 +526,14 @@ func handleJoiningSession(state microTypes.State, sh *service.Handler, gw *cloud
 
 	// Add system to temporary truststore.
 	sh.Session.Allow(confirmedIntent.Name, *remoteCert)
+	sh.SessionLog.Record(gw.Context(), state, types.SessionLogEntry{
+		Role:        types.SessionJoining,
+		Event:       types.SessionEventAccepted,
+		Name:        confirmedIntent.Name,
+		Address:     confirmedIntent.Address,
+		Fingerprint: shared.CertFingerprint(remoteCert),
+		Version:     confirmedIntent.Version,
+	})
 
 	var errStr string
 	select {
@@ -398,3 +552,493 @@ func handleJoiningSession(state microTypes.State, sh *service.Handler, gw *cloud
 
 	return nil
 }
+
+// sessionInfo returns the info announced during discovery by the initiator of the given session.
+func sessionInfo(ctx context.Context, state microTypes.State, sh *service.Handler, session types.Session, passphrase string) (multicast.ServerInfo, error) {
+	cloud := sh.Services[types.MicroCloud].(*service.CloudService)
+	initialized, err := cloud.IsInitialized(ctx)
+	if err != nil {
+		return multicast.ServerInfo{}, fmt.Errorf("Failed to check if %q is initialized: %w", types.MicroCloud, err)
+	}
+
+	// If the cluster is already bootstrapped the cluster certificate is presented by the API instead.
+	var discoveryCert *shared.CertInfo
+	if initialized {
+		discoveryCert, err = cloud.ClusterCert()
+	} else {
+		discoveryCert, err = cloud.ServerCert()
+	}
+
+	if err != nil {
+		return multicast.ServerInfo{}, fmt.Errorf("Failed to get certificate of %q: %w", types.MicroCloud, err)
+	}
+
+	// Announce the passphrase settings so joiners can complete the words of the passphrase using the same wordlist.
+	return multicast.ServerInfo{
+		Version:         multicast.Version,
+		MinVersion:      multicast.MinVersion,
+		Name:            state.Name(),
+		Realm:           sessionRealm(sh, session),
+		Address:         session.Address,
+		Services:        session.Services,
+		Fingerprint:     discoveryCert.Fingerprint(),
+		PassphraseWords: uint8(min(len(strings.Fields(passphrase)), 255)),
+		Wordlist:        session.Wordlist,
+	}, nil
+}
+
+// startDiscovery responds to discovery on each of the interfaces of the given session with the given info.
+// The info is signed using an HMAC derived from the session's passphrase.
+func startDiscovery(sh *service.Handler, session types.Session, info multicast.ServerInfo, passphrase string) error {
+	signer, err := multicastHMAC(passphrase)
+	if err != nil {
+		return err
+	}
+
+	addresses, err := responderAddresses(session)
+	if err != nil {
+		return err
+	}
+
+	// Respond on each interface with the address of the respective interface.
+	for iface, address := range addresses {
+		ifaceSession := session
+		ifaceSession.Interface = iface
+
+		backend, err := discoveryBackend(sh, ifaceSession, signer)
+		if err != nil {
+			return err
+		}
+
+		ifaceInfo := info
+		ifaceInfo.Address = address
+
+		err = sh.Session.MulticastDiscovery(ifaceInfo, service.CloudPort, backend, signer)
+		if err != nil {
+			return fmt.Errorf("Failed to start multicast discovery on interface %q: %w", iface, err)
+		}
+	}
+
+	return nil
+}
+
+// RestoreSession restores the initiating session which got interrupted by a restart of the daemon.
+// As only the hash of its passphrase was persisted, the session is announced again once a client resumes it
+// using its passphrase. Until then it keeps the join intents registered before the restart, or until it times out.
+func RestoreSession(state microTypes.State, sh *service.Handler) error {
+	session, err := sh.RestoreSession(state.FileSystem().StateDir())
+	if err != nil {
+		return err
+	}
+
+	if session != nil {
+		deadline, _ := session.Deadline()
+		logger.Info("Restored interrupted session, resume it to continue", logger.Ctx{"deadline": deadline})
+	}
+
+	return nil
+}
+
+// multicastHMAC returns the HMAC used to sign and verify multicast discovery responses.
+func multicastHMAC(passphrase string) (trust.HMACFormatter, error) {
+	h, err := trust.NewHMACArgon2([]byte(passphrase), []byte(multicastHMACSalt), trust.NewDefaultHMACConf(HMACMicroCloud10))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create a new HMAC instance for multicast discovery using argon2: %w", err)
+	}
+
+	return h, nil
+}
+
+// discoveryBackend returns the backend for the discovery mode of the given session.
+// Static discovery probes the session's seed addresses when initiating and
+// receives the probes of the initiator when joining.
+// Each probe is authorized using the HMAC of the probed system's nonce derived from the given signer.
+func discoveryBackend(sh *service.Handler, session types.Session, signer trust.HMACFormatter) (multicast.Backend, error) {
+	switch session.Discovery {
+	case "", types.DiscoveryMulticast:
+		config, err := discoveryGroup(sh, session)
+		if err != nil {
+			return nil, err
+		}
+
+		return multicast.NewGroupBackend(session.Interface, config), nil
+	case types.DiscoveryMDNS:
+		return multicast.NewMDNSBackend(session.Interface), nil
+	case types.DiscoveryStatic:
+		cloud := sh.Services[types.MicroCloud].(*service.CloudService)
+		probe := func(ctx context.Context, address string, beacon multicast.Beacon) (*multicast.ServerInfo, error) {
+			peer, err := cloud.SessionBeacon(ctx, address)
+			if err != nil {
+				return nil, err
+			}
+
+			authorization, err := multicast.Authorization(signer, peer.Nonce)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to create HMAC for beacon: %w", err)
+			}
+
+			return cloud.ProbeSession(ctx, address, peer.Nonce, authorization, beacon)
+		}
+
+		return multicast.NewStaticBackend(session.SeedAddresses, probe, sh.Session.ProbeCh()), nil
+	}
+
+	return nil, fmt.Errorf("Unsupported discovery mode %q", session.Discovery)
+}
+
+// responderAddresses returns the address announced on each interface on which the initiator responds to discovery.
+// Next to the session's interface, the session can list additional interfaces or select all interfaces having
+// a global unicast address.
+func responderAddresses(session types.Session) (map[string]string, error) {
+	addresses := map[string]string{session.Interface: session.Address}
+	if len(session.DiscoveryInterfaces) == 0 {
+		return addresses, nil
+	}
+
+	if session.Discovery == types.DiscoveryStatic {
+		return nil, fmt.Errorf("Discovery interfaces cannot be used with %q discovery", types.DiscoveryStatic)
+	}
+
+	networks, err := multicast.GetNetworkInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	all := slices.Contains(session.DiscoveryInterfaces, types.DiscoveryAllInterfaces)
+	sessionIPv4 := isIPv4(session.Address)
+	for _, network := range networks {
+		name := network.Interface.Name
+		if name == session.Interface || (!all && !slices.Contains(session.DiscoveryInterfaces, name)) {
+			continue
+		}
+
+		// The responder answers on both address families of the interface, but the announced address is the one the joiner connects to.
+		// Prefer the family of the session's address, as the cluster is formed using that family.
+		current, ok := addresses[name]
+		if ok && (isIPv4(current) == sessionIPv4 || isIPv4(network.Address) != sessionIPv4) {
+			continue
+		}
+
+		addresses[name] = network.Address
+	}
+
+	for _, name := range session.DiscoveryInterfaces {
+		if name != types.DiscoveryAllInterfaces && addresses[name] == "" {
+			return nil, fmt.Errorf("Interface %q doesn't have any global unicast address", name)
+		}
+	}
+
+	return addresses, nil
+}
+
+// lookupNetworks returns the networks from which the joiner fetches the info of the systems found during lookup.
+// Fetching the info reveals an HMAC derived from the passphrase, so it's limited to the subnets of the lookup interface
+// unless the joiner explicitly chose other systems, either using seed addresses or by letting multicast queries cross routers.
+func lookupNetworks(sh *service.Handler, session types.Session) ([]*net.IPNet, error) {
+	if session.Discovery == types.DiscoveryStatic {
+		return nil, nil
+	}
+
+	if session.Discovery == "" || session.Discovery == types.DiscoveryMulticast {
+		config, err := discoveryGroup(sh, session)
+		if err != nil {
+			return nil, err
+		}
+
+		if config.TTL > 1 {
+			return nil, nil
+		}
+	}
+
+	networkInfo, err := multicast.GetNetworkInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	networks := make([]*net.IPNet, 0, len(networkInfo))
+	for _, network := range networkInfo {
+		if network.Interface.Name == session.Interface {
+			networks = append(networks, network.Subnet)
+		}
+	}
+
+	if len(networks) == 0 {
+		return nil, fmt.Errorf("Interface %q doesn't have any global unicast address", session.Interface)
+	}
+
+	return networks, nil
+}
+
+// isIPv4 returns whether the given address is an IPv4 address.
+func isIPv4(address string) bool {
+	return net.ParseIP(address).To4() != nil
+}
+
+// discoveryGroup returns the multicast group configuration of the given session.
+// Settings which aren't part of the session fall back to the defaults of the daemon.
+func discoveryGroup(sh *service.Handler, session types.Session) (multicast.GroupConfig, error) {
+	config := sh.DiscoveryGroup
+	if session.MulticastGroup != "" {
+		group := net.ParseIP(session.MulticastGroup)
+		if group == nil || !group.IsMulticast() {
+			return multicast.GroupConfig{}, fmt.Errorf("Invalid multicast group %q", session.MulticastGroup)
+		}
+
+		config.Group = group
+	}
+
+	if session.MulticastPort > 0 {
+		config.Port = session.MulticastPort
+	}
+
+	if session.MulticastTTL > 0 {
+		config.TTL = session.MulticastTTL
+	}
+
+	return config, nil
+}
+
+// sessionRealm returns the realm of the given session.
+// It falls back to the default realm of the daemon if the session doesn't set any.
+func sessionRealm(sh *service.Handler, session types.Session) string {
+	if session.Realm != "" {
+		return session.Realm
+	}
+
+	return sh.DiscoveryRealm
+}
+
+// requestPassphrase finds the beacon of an initiator and sends its passphrase settings to the client.
+// The client replies with the passphrase entered by the user which is returned together with the beacon.
+// The beacon isn't authenticated, so its settings are only used to help entering the passphrase.
+func requestPassphrase(ctx context.Context, state microTypes.State, gw *cloudClient.WebsocketGateway, sh *service.Handler, session types.Session) (string, *multicast.Beacon, error) {
+	lookupCtx, cancel := context.WithTimeoutCause(ctx, session.LookupTimeout, errors.New("Lookup timeout exceeded"))
+	defer cancel()
+
+	beacon, err := lookupInitiatorBeacon(lookupCtx, state, sh, session)
+	if err != nil {
+		return "", nil, fmt.Errorf("Failed to lookup eligible system: %w", err)
+	}
+
+	err = gw.Write(types.Session{
+		InitiatorName:    beacon.Name,
+		InitiatorAddress: beacon.Address,
+		PassphraseWords:  beacon.PassphraseWords,
+		Wordlist:         beacon.Wordlist,
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("Failed to send passphrase settings: %w", err)
+	}
+
+	reply := types.Session{}
+	err = gw.ReceiveWithContext(ctx, &reply)
+	if err != nil {
+		return "", nil, fmt.Errorf("Failed to read passphrase: %w", err)
+	}
+
+	if reply.Passphrase == "" {
+		return "", nil, errors.New("Passphrase cannot be empty")
+	}
+
+	return reply.Passphrase, beacon, nil
+}
+
+// lookupInitiatorBeacon returns the beacon of the first initiator found which supports one of our versions.
+// If the initiator's address is known, its beacon is fetched from its API instead.
+// Using static discovery without the initiator's address, an empty beacon is returned as the initiator has to probe this system.
+func lookupInitiatorBeacon(ctx context.Context, state microTypes.State, sh *service.Handler, session types.Session) (*multicast.Beacon, error) {
+	if session.InitiatorAddress != "" {
+		cloud := sh.Services[types.MicroCloud].(*service.CloudService)
+		beacon, err := cloud.SessionBeacon(ctx, session.InitiatorAddress)
+		if err != nil {
+			// The passphrase can still be entered without knowing its settings.
+			logger.Warn("Failed to get passphrase settings of initiator", logger.Ctx{"address": session.InitiatorAddress, "err": err})
+			return &multicast.Beacon{Address: session.InitiatorAddress}, nil
+		}
+
+		return beacon, nil
+	}
+
+	// Using static discovery, the initiator probes this system which requires the passphrase to be known already.
+	if session.Discovery == types.DiscoveryStatic {
+		return &multicast.Beacon{}, nil
+	}
+
+	// Stop probing as soon as an eligible system is found.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	backend, err := discoveryBackend(sh, session, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := initiatorQuery(state, sh, session)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := multicast.NewDiscoveryWithBackend(backend, nil, nil).Probe(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	for event := range events {
+		if event.Err != nil {
+			return nil, event.Err
+		}
+
+		// Incompatible systems are reported once the passphrase is known and their info can be fetched.
+		_, err := query.Versions.Negotiate(event.Beacon.Versions())
+		if err != nil {
+			continue
+		}
+
+		return &event.Beacon, nil
+	}
+
+	return nil, fmt.Errorf("Failed to look up the initiator: %w", context.Cause(ctx))
+}
+
+// lookupInitiator looks up the first system sharing a version with this system using the discovery mode of the given session.
+// It returns the system's info together with the highest version supported by both systems.
+// The full info of each system is fetched from its API and only info signed using the session's passphrase is considered.
+// Any system found using a different version is reported to the client so that the user can be informed.
+// The query carries this system's name and address which allows the initiator to report this system in case of a version mismatch too.
+func lookupInitiator(ctx context.Context, state microTypes.State, gw *cloudClient.WebsocketGateway, sh *service.Handler, session types.Session) (*multicast.LookupEvent, error) {
+	// Stop the lookup as soon as an eligible system is found.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	signer, err := multicastHMAC(sh.Session.Passphrase())
+	if err != nil {
+		return nil, err
+	}
+
+	backend, err := discoveryBackend(sh, session, signer)
+	if err != nil {
+		return nil, err
+	}
+
+	// Using static discovery, initiators fetch this system's beacon and hand over their own beacon
+	// using a request authorized by the beacon's nonce.
+	if session.Discovery == types.DiscoveryStatic {
+		info, err := sessionInfo(ctx, state, sh, session, sh.Session.Passphrase())
+		if err != nil {
+			return nil, err
+		}
+
+		err = sh.Session.MulticastDiscovery(info, service.CloudPort, backend, signer)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to start static discovery: %w", err)
+		}
+	}
+
+	cloud := sh.Services[types.MicroCloud].(*service.CloudService)
+	discovery := multicast.NewDiscoveryWithBackend(backend, signer, cloud.FetchSessionInfo)
+	query, err := initiatorQuery(state, sh, session)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := discovery.LookupAll(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	for event := range events {
+		var mismatchErr *multicast.VersionMismatchError
+		if errors.As(event.Err, &mismatchErr) {
+			err := reportIncompatibleSystem(gw, event.Info)
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if event.Err != nil {
+			return nil, event.Err
+		}
+
+		return &event, nil
+	}
+
+	return nil, fmt.Errorf("Failed to look up the initiator: %w", context.Cause(ctx))
+}
+
+// resolveInitiator fetches the info of the initiator announced by the given beacon which was found while requesting the passphrase.
+// It returns the initiator's info together with the highest version supported by both systems.
+// Only info signed using the session's passphrase is accepted.
+// If the initiator uses a different version, it's reported to the client so that the user can be informed.
+func resolveInitiator(ctx context.Context, state microTypes.State, gw *cloudClient.WebsocketGateway, sh *service.Handler, session types.Session, beacon multicast.Beacon) (*multicast.LookupEvent, error) {
+	signer, err := multicastHMAC(sh.Session.Passphrase())
+	if err != nil {
+		return nil, err
+	}
+
+	backend, err := discoveryBackend(sh, session, signer)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := initiatorQuery(state, sh, session)
+	if err != nil {
+		return nil, err
+	}
+
+	cloud := sh.Services[types.MicroCloud].(*service.CloudService)
+	discovery := multicast.NewDiscoveryWithBackend(backend, signer, cloud.FetchSessionInfo)
+	event, err := discovery.LookupBeacon(ctx, query, beacon)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to resolve the initiator %q: %w", beacon.Address, err)
+	}
+
+	var mismatchErr *multicast.VersionMismatchError
+	if errors.As(event.Err, &mismatchErr) {
+		err := reportIncompatibleSystem(gw, event.Info)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if event.Err != nil {
+		return nil, event.Err
+	}
+
+	return event, nil
+}
+
+// initiatorQuery returns the query used by joiners to look up the initiator.
+// The query carries this system's name and address which allows the initiator to report this system in case of a version mismatch.
+func initiatorQuery(state microTypes.State, sh *service.Handler, session types.Session) (multicast.Query, error) {
+	networks, err := lookupNetworks(sh, session)
+	if err != nil {
+		return multicast.Query{}, err
+	}
+
+	return multicast.Query{
+		Versions: multicast.SupportedVersions,
+		Realm:    sessionRealm(sh, session),
+		Name:     state.Name(),
+		Address:  session.Address,
+		Networks: networks,
+	}, nil
+}
+
+// reportIncompatibleSystem sends the given system which doesn't share any version with this system to the client.
+func reportIncompatibleSystem(gw *cloudClient.WebsocketGateway, info multicast.ServerInfo) error {
+	err := gw.Write(types.Session{
+		IncompatibleSystem: &types.SessionSystem{
+			Name:       info.Name,
+			Address:    info.Address,
+			Version:    info.Version,
+			MinVersion: info.MinVersion,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to report incompatible system %q: %w", info.Name, err)
+	}
+
+	return nil
+}
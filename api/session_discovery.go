@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	microTypes "github.com/canonical/microcluster/v3/microcluster/types"
+
+	"github.com/canonical/microcloud/microcloud/api/types"
+	"github.com/canonical/microcloud/microcloud/multicast"
+	"github.com/canonical/microcloud/microcloud/service"
+)
+
+// SessionDiscoveryCmd represents the /1.0/session/discovery API on MicroCloud.
+var SessionDiscoveryCmd = func(sh *service.Handler) microTypes.Endpoint {
+	return microTypes.Endpoint{
+		AllowedBeforeInit: true,
+		Name:              "session/discovery",
+		Path:              "session/discovery",
+
+		Get: microTypes.EndpointAction{Handler: sessionDiscoveryGet(sh), AllowUntrusted: true},
+	}
+}
+
+// sessionDiscoveryGet returns the info announced by the session's beacon.
+// The request has to present the beacon's nonce together with its HMAC derived from the session's passphrase.
+// Without a nonce, the beacon itself is returned which allows fetching the info of a system whose address is already known.
+// The beacon doesn't contain anything which isn't sent to everyone on the network when using multicast discovery.
+// Initiators using static discovery probe joiners by handing over their own beacon along with an authorized request,
+// which is then reported by the lookup of the joining session.
+func sessionDiscoveryGet(sh *service.Handler) func(state microTypes.State, r *http.Request) microTypes.Response {
+	return func(state microTypes.State, r *http.Request) microTypes.Response {
+		// Apply delay right at the beginning before doing any validation.
+		// This limits the number of attempts that can be made by an attacker to guess the HMAC.
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-r.Context().Done():
+			return microTypes.InternalError(errors.New("Request cancelled"))
+		}
+
+		source := requestSource(r)
+
+		var response any
+		var failures []types.SessionLogEntry
+
+		// Run a r/w transaction against the session as we might stop it due to too many failed attempts.
+		err := sh.SessionTransaction(false, func(session *service.Session) error {
+			if session.Role() != types.SessionInitiating && session.Role() != types.SessionJoining {
+				return api.NewStatusError(http.StatusBadRequest, "No active session")
+			}
+
+			// Reject banned sources before verifying the HMAC.
+			err := checkSourceBanned(session, source)
+			if err != nil {
+				return err
+			}
+
+			nonce := r.URL.Query().Get("nonce")
+			if nonce == "" {
+				beacon, err := session.DiscoveryBeacon()
+				if err != nil {
+					return api.StatusErrorf(http.StatusServiceUnavailable, "Failed to get discovery beacon: %w", err)
+				}
+
+				response = beacon
+				return nil
+			}
+
+			info, err := session.DiscoveryInfo(nonce, r.Header.Get("Authorization"))
+			if err != nil {
+				// Each wrong HMAC allows verifying a guessed passphrase, so count it as a failed join attempt.
+				failures, err = registerFailedAttempt(session, source, r.RemoteAddr, api.StatusErrorf(http.StatusUnauthorized, "Failed to get discovery info: %w", err))
+				return err
+			}
+
+			probe := r.URL.Query().Get("beacon")
+			if probe != "" {
+				if session.Role() != types.SessionJoining {
+					return api.NewStatusError(http.StatusBadRequest, "No active joining session")
+				}
+
+				beacon := multicast.Beacon{}
+				err = json.Unmarshal([]byte(probe), &beacon)
+				if err != nil {
+					return api.StatusErrorf(http.StatusBadRequest, "Failed to parse beacon: %w", err)
+				}
+
+				// Don't block if the lookup isn't running (anymore), e.g. because the initiator's address was provided.
+				// The initiator retries the probe until it succeeds.
+				select {
+				case session.ProbeCh() <- beacon:
+				default:
+					return api.NewStatusError(http.StatusServiceUnavailable, "No active lookup in the joining session")
+				}
+			}
+
+			response = info
+			return nil
+		})
+
+		// Record the failures outside of the transaction to not block the session while writing to the database.
+		for _, failure := range failures {
+			sh.SessionLog.Record(r.Context(), state, failure)
+		}
+
+		if err != nil {
+			return microTypes.SmartError(err)
+		}
+
+		return microTypes.SyncResponse(true, response)
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/ws"
+	microTypes "github.com/canonical/microcluster/v3/microcluster/types"
+
+	cloudClient "github.com/canonical/microcloud/microcloud/client"
+	"github.com/canonical/microcloud/microcloud/service"
+)
+
+// SessionEventsCmd represents the /1.0/session/events API on MicroCloud.
+var SessionEventsCmd = func(sh *service.Handler) microTypes.Endpoint {
+	return microTypes.Endpoint{
+		AllowedBeforeInit: true,
+		Name:              "session/events",
+		Path:              "session/events",
+
+		Get: microTypes.EndpointAction{Handler: authHandlerMTLS(sh, sessionEventsGet(sh))},
+	}
+}
+
+// sessionEventsGet streams the messages of all trust establishment sessions over a websocket.
+// The websocket is read-only, any messages sent by the observer are discarded.
+func sessionEventsGet(sh *service.Handler) func(state microTypes.State, r *http.Request) microTypes.Response {
+	return func(state microTypes.State, r *http.Request) microTypes.Response {
+		return response.ManualResponse(func(w http.ResponseWriter) error {
+			conn, err := ws.Upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return err
+			}
+
+			defer func() {
+				err := conn.Close()
+				if err != nil && !errors.Is(err, net.ErrClosed) {
+					logger.Error("Failed to close the websocket connection", logger.Ctx{"err": err})
+				}
+			}()
+
+			gw := cloudClient.NewWebsocketGateway(r.Context(), conn)
+
+			events, unsubscribe := sh.SessionObservers.Subscribe()
+			defer unsubscribe()
+
+			for {
+				select {
+				case event := <-events:
+					err := gw.Write(event)
+					if err != nil {
+						logger.Debug("Failed to write session event", logger.Ctx{"err": err})
+						return nil
+					}
+
+				case <-gw.Receive():
+				case <-gw.Context().Done():
+					return nil
+				}
+			}
+		})
+	}
+}
@@ -13,6 +13,7 @@ import (
 	microTypes "github.com/canonical/microcluster/v3/microcluster/types"
 
 	"github.com/canonical/microcloud/microcloud/api/types"
+	"github.com/canonical/microcloud/microcloud/multicast"
 	"github.com/canonical/microcloud/microcloud/service"
 )
 
@@ -46,22 +47,34 @@ func sessionJoinPost(sh *service.Handler) func(state microTypes.State, r *http.R
 			return microTypes.BadRequest(err)
 		}
 
+		// Record the intent using the address it was received from.
+		entry := types.SessionLogEntry{
+			Event:   types.SessionEventIntent,
+			Name:    req.Name,
+			Address: r.RemoteAddr,
+			Version: req.Version,
+		}
+
 		err = sh.SessionTransaction(true, func(session *service.Session) error {
+			entry.Role = session.Role()
+
+			fingerprint, err := shared.CertFingerprintStr(req.Certificate)
+			if err != nil {
+				return api.StatusErrorf(http.StatusBadRequest, "Failed to get fingerprint: %w", err)
+			}
+
+			entry.Fingerprint = fingerprint
+
 			// Only validate the intent (services) on the initiator.
 			// The joiner has to accept the services from the initiator.
 			if session.Role() == types.SessionInitiating {
-				err = validateIntent(r.Context(), sh, req)
+				err = validateIntent(r.Context(), sh, session.Realm(), req)
 				if err != nil {
 					return api.NewStatusError(http.StatusBadRequest, err.Error())
 				}
 			}
 
-			fingerprint, err := shared.CertFingerprintStr(req.Certificate)
-			if err != nil {
-				return api.StatusErrorf(http.StatusBadRequest, "Failed to get fingerprint: %w", err)
-			}
-
-			err = session.RegisterIntent(fingerprint)
+			err = session.RegisterIntent(fingerprint, req)
 			if err != nil {
 				return api.StatusErrorf(http.StatusBadRequest, "Failed to register join intent: %w", err)
 			}
@@ -82,6 +95,12 @@ func sessionJoinPost(sh *service.Handler) func(state microTypes.State, r *http.R
 				return errors.New("Timeout waiting for an active consumer of the join intent")
 			}
 		})
+		if err != nil {
+			entry.Event = types.SessionEventIntentRejected
+			entry.Message = err.Error()
+		}
+
+		sh.SessionLog.Record(r.Context(), state, entry)
 
 		return microTypes.SmartError(err)
 	}
@@ -90,8 +109,19 @@ func sessionJoinPost(sh *service.Handler) func(state microTypes.State, r *http.R
 // validateIntent validates the given join intent.
 // It checks whether or not the peer is missing any of our services and returns an error if one is missing.
 // Also compares each service's daemon version between the joiner and initiator.
-func validateIntent(ctx context.Context, sh *service.Handler, intent types.SessionJoinPost) error {
-	// Reject any peers that are missing our services.
+// Peers of a different realm than the one of the session are rejected too.
+func validateIntent(ctx context.Context, sh *service.Handler, realm string, intent types.SessionJoinPost) error {
+	// Reject any peers using a version which isn't supported anymore.
+	if !multicast.SupportedVersions.Contains(intent.Version) {
+		return fmt.Errorf("Rejecting peer %q due to unsupported version %q (supported versions are %q)", intent.Name, intent.Version, multicast.SupportedVersions.String())
+	}
+
+	// Reject any peers belonging to a different deployment.
+	if intent.Realm != realm {
+		return fmt.Errorf("Rejecting peer %q due to different realm %q (want %q)", intent.Name, intent.Realm, realm)
+	}
+
+	// Reject any peers that are missing our services or whose versions violate the version policy.
 	for _, service := range sh.Services {
 		intentVersion, ok := intent.Services[service.Type()]
 		if !ok {
@@ -103,8 +133,9 @@ func validateIntent(ctx context.Context, sh *service.Handler, intent types.Sessi
 			return fmt.Errorf("Unable to determine initiator's %s version: %w", service.Type(), err)
 		}
 
-		if intentVersion != version {
-			return fmt.Errorf("Rejecting peer %q due to invalid %s version. (Want: %q, Detected: %q)", intent.Name, service.Type(), version, intentVersion)
+		err = sh.VersionPolicy.Check(service.Type(), version, intentVersion)
+		if err != nil {
+			return fmt.Errorf("Rejecting peer %q due to incompatible version: %w", intent.Name, err)
 		}
 	}
 
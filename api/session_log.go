@@ -0,0 +1,52 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	microTypes "github.com/canonical/microcluster/v3/microcluster/types"
+
+	"github.com/canonical/microcloud/microcloud/api/types"
+	"github.com/canonical/microcloud/microcloud/service"
+)
+
+// sessionEvents contains all the events recorded in the session log.
+var sessionEvents = []types.SessionEvent{
+	types.SessionEventStart,
+	types.SessionEventStop,
+	types.SessionEventIntent,
+	types.SessionEventIntentRejected,
+	types.SessionEventHMACFailure,
+	types.SessionEventSourceBanned,
+	types.SessionEventAccepted,
+}
+
+// SessionLogCmd represents the /1.0/session/log API on MicroCloud.
+var SessionLogCmd = func(sh *service.Handler) microTypes.Endpoint {
+	return microTypes.Endpoint{
+		AllowedBeforeInit: true,
+		Name:              "session/log",
+		Path:              "session/log",
+
+		Get: microTypes.EndpointAction{Handler: authHandlerMTLS(sh, sessionLogGet(sh))},
+	}
+}
+
+// sessionLogGet returns the recorded events of all trust establishment sessions.
+// The events can be filtered using the event query parameter.
+func sessionLogGet(sh *service.Handler) func(state microTypes.State, r *http.Request) microTypes.Response {
+	return func(state microTypes.State, r *http.Request) microTypes.Response {
+		event := types.SessionEvent(r.URL.Query().Get("event"))
+		if event != "" && !slices.Contains(sessionEvents, event) {
+			return microTypes.BadRequest(fmt.Errorf("Invalid session event %q", event))
+		}
+
+		entries, err := sh.SessionLog.Entries(r.Context(), state, event)
+		if err != nil {
+			return microTypes.SmartError(err)
+		}
+
+		return microTypes.SyncResponse(true, entries)
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	microTypes "github.com/canonical/microcluster/v3/microcluster/types"
+
+	"github.com/canonical/microcloud/microcloud/api/types"
+	"github.com/canonical/microcloud/microcloud/service"
+)
+
+// SessionCmd represents the /1.0/session API on MicroCloud.
+var SessionCmd = func(sh *service.Handler) microTypes.Endpoint {
+	return microTypes.Endpoint{
+		AllowedBeforeInit: true,
+		Name:              "session",
+		Path:              "session",
+
+		Get: microTypes.EndpointAction{Handler: authHandlerMTLS(sh, sessionStatusGet(sh))},
+	}
+}
+
+// sessionStatusGet returns the state of the current session.
+// The session's passphrase isn't part of the response.
+func sessionStatusGet(sh *service.Handler) func(state microTypes.State, r *http.Request) microTypes.Response {
+	return func(state microTypes.State, r *http.Request) microTypes.Response {
+		var status types.SessionStatus
+		err := sh.SessionTransaction(true, func(session *service.Session) error {
+			status = types.SessionStatus{
+				Role:                   session.Role(),
+				JoinIntentFingerprints: session.JoinIntentFingerprints(),
+				FailedAttempts:         session.FailedAttempts(),
+			}
+
+			deadline, ok := session.Deadline()
+			if ok {
+				status.ExpiresAt = deadline
+				status.RemainingTimeout = int64(time.Until(deadline).Seconds())
+			}
+
+			return nil
+		})
+		if err != nil {
+			return microTypes.SmartError(err)
+		}
+
+		return microTypes.SyncResponse(true, status)
+	}
+}
@@ -80,9 +80,10 @@ func statusGet(sh *service.Handler) endpointHandler {
 			OSDs:         []cephTypes.Disk{},
 			CephServices: []cephTypes.Service{},
 			OVNServices:  []ovnTypes.Service{},
+			Health:       make(map[types.ServiceType]types.HealthReport, len(sh.Services)),
 		}
 
-		err = sh.RunConcurrent("", "", func(s service.Service) error {
+		err = sh.RunConcurrent(service.RunParallel, func(s service.Service) error {
 			switch s.Type() {
 			case types.LXD:
 				clusterMembers, err := lxdStatus(r.Context(), s)
@@ -129,6 +130,21 @@ func statusGet(sh *service.Handler) endpointHandler {
 				statusMu.Unlock()
 			}
 
+			// If we got a 503 error back, that means the service is installed, but hasn't been set up yet, so there is no health to report.
+			health, err := s.Health(r.Context())
+			if err != nil && lxdAPI.StatusErrorCheck(err, http.StatusServiceUnavailable) {
+				return nil
+			}
+
+			if err != nil {
+				logger.Error("Failed to get service health", logger.Ctx{"type": s.Type(), "name": sh.Name, "err": err})
+				health.Add(types.HealthUnhealthy, fmt.Sprintf("Failed to get health: %v", err))
+			}
+
+			statusMu.Lock()
+			status.Health[s.Type()] = health
+			statusMu.Unlock()
+
 			return nil
 		})
 		if err != nil {
@@ -0,0 +1,76 @@
+package types
+
+import (
+	"time"
+)
+
+// OperationStatus represents the status of a MicroCloud operation.
+type OperationStatus string
+
+const (
+	// OperationRunning represents an operation which is still running.
+	OperationRunning OperationStatus = "Running"
+
+	// OperationSuccess represents an operation which finished successfully.
+	OperationSuccess OperationStatus = "Success"
+
+	// OperationFailure represents an operation which failed.
+	OperationFailure OperationStatus = "Failure"
+
+	// OperationCancelled represents an operation which got cancelled.
+	OperationCancelled OperationStatus = "Cancelled"
+)
+
+// OperationStage represents the progress of an operation on a single service.
+type OperationStage string
+
+const (
+	// OperationStagePending represents a service which the operation didn't yet reach.
+	OperationStagePending OperationStage = "Pending"
+
+	// OperationStageRunning represents a service which the operation is currently working on.
+	OperationStageRunning OperationStage = "Running"
+
+	// OperationStageDone represents a service which the operation finished successfully.
+	OperationStageDone OperationStage = "Done"
+
+	// OperationStageFailed represents a service on which the operation failed.
+	OperationStageFailed OperationStage = "Failed"
+
+	// OperationStageRolledBack represents a service on which the changes of the operation got reverted.
+	OperationStageRolledBack OperationStage = "Rolled back"
+
+	// OperationStageSkipped represents a service which the operation didn't reach before it failed,
+	// for example because the operation failed on a service it depends on.
+	OperationStageSkipped OperationStage = "Skipped"
+)
+
+// Operation represents a long-running MicroCloud operation such as joining or removing a cluster member.
+// If the operation failed with an API error, its HTTP status code is set too.
+type Operation struct {
+	ID          string                         `json:"id" yaml:"id"`
+	Description string                         `json:"description" yaml:"description"`
+	Status      OperationStatus                `json:"status" yaml:"status"`
+	Services    map[ServiceType]OperationStage `json:"services" yaml:"services"`
+	Error       string                         `json:"error" yaml:"error"`
+	StatusCode  int                            `json:"status_code,omitempty" yaml:"status_code,omitempty"`
+	CreatedAt   time.Time                      `json:"created_at" yaml:"created_at"`
+	UpdatedAt   time.Time                      `json:"updated_at" yaml:"updated_at"`
+}
+
+// Done returns true if the operation isn't running anymore.
+func (o Operation) Done() bool {
+	return o.Status != OperationRunning
+}
+
+// Progress returns the number of services which the operation already finished and the total number of services.
+func (o Operation) Progress() (int, int) {
+	done := 0
+	for _, stage := range o.Services {
+		if stage != OperationStagePending && stage != OperationStageRunning {
+			done++
+		}
+	}
+
+	return done, len(o.Services)
+}
@@ -15,6 +15,27 @@ const (
 	SessionJoining SessionRole = "joining"
 )
 
+// DiscoveryMode indicates the mechanism used to find other systems during a trust establishment session.
+type DiscoveryMode string
+
+const (
+	// DiscoveryMulticast finds other systems using datagrams on MicroCloud's own multicast group.
+	DiscoveryMulticast DiscoveryMode = "multicast"
+
+	// DiscoveryMDNS finds other systems using multicast DNS.
+	DiscoveryMDNS DiscoveryMode = "mdns"
+
+	// DiscoveryStatic doesn't use multicast. Either the initiator probes a list of seed addresses
+	// or the joiners have to know the initiator's address.
+	DiscoveryStatic DiscoveryMode = "static"
+)
+
+// DiscoveryModes contains all the supported discovery modes.
+var DiscoveryModes = []DiscoveryMode{DiscoveryMulticast, DiscoveryMDNS, DiscoveryStatic}
+
+// DiscoveryAllInterfaces selects all interfaces with a global unicast address to respond on during discovery.
+const DiscoveryAllInterfaces = "all"
+
 // Session represents the websocket protocol used during trust establishment between the client and server.
 // Empty fields are omitted to require sending only the necessary information.
 type Session struct {
@@ -23,24 +44,95 @@ type Session struct {
 	InitiatorName        string                 `json:"initiator_name,omitempty"`
 	InitiatorFingerprint string                 `json:"initiator_fingerprint,omitempty"`
 	Interface            string                 `json:"interface,omitempty"`
+	DiscoveryInterfaces  []string               `json:"discovery_interfaces,omitempty"`
+	Discovery            DiscoveryMode          `json:"discovery,omitempty"`
+	SeedAddresses        []string               `json:"seed_addresses,omitempty"`
+	AllowedFingerprints  []string               `json:"allowed_fingerprints,omitempty"`
+	MulticastGroup       string                 `json:"multicast_group,omitempty"`
+	MulticastPort        int64                  `json:"multicast_port,omitempty"`
+	MulticastTTL         int                    `json:"multicast_ttl,omitempty"`
+	Realm                string                 `json:"realm,omitempty"`
 	Passphrase           string                 `json:"passphrase,omitempty"`
+	PassphraseWords      uint8                  `json:"passphrase_words,omitempty"`
+	Wordlist             string                 `json:"wordlist,omitempty"`
+	Resume               bool                   `json:"resume,omitempty"`
+	DiscardInterrupted   bool                   `json:"discard_interrupted,omitempty"`
 	Services             map[ServiceType]string `json:"services,omitempty"`
 	Intent               SessionJoinPost        `json:"intent,omitempty"`
 	ConfirmedIntents     []SessionJoinPost      `json:"confirmed_intents,omitempty"`
 	Accepted             bool                   `json:"accepted,omitempty"`
 	LookupTimeout        time.Duration          `json:"lookup_timeout,omitempty"`
+	IncompatibleSystem   *SessionSystem         `json:"incompatible_system,omitempty"`
 	Error                string                 `json:"error,omitempty"`
 }
 
+// SessionSystem represents a system found during lookup.
+// The system supports all discovery versions from MinVersion up to Version.
+type SessionSystem struct {
+	Name       string `json:"name"`
+	Address    string `json:"address"`
+	Version    string `json:"version"`
+	MinVersion string `json:"min_version,omitempty"`
+}
+
 // SessionJoinPost represents a request made to join an active session.
 type SessionJoinPost struct {
 	Name        string                 `json:"name" yaml:"name"`
 	Version     string                 `json:"version" yaml:"version"`
+	Realm       string                 `json:"realm,omitempty" yaml:"realm,omitempty"`
 	Address     string                 `json:"address" yaml:"address"`
 	Certificate string                 `json:"certificate" yaml:"certificate"`
 	Services    map[ServiceType]string `json:"services" yaml:"services"`
 }
 
+// SessionStatus represents the state of an active trust establishment session.
+type SessionStatus struct {
+	Role                   SessionRole `json:"role" yaml:"role"`
+	ExpiresAt              time.Time   `json:"expires_at" yaml:"expires_at"`
+	RemainingTimeout       int64       `json:"remaining_timeout" yaml:"remaining_timeout"`
+	JoinIntentFingerprints []string    `json:"join_intent_fingerprints" yaml:"join_intent_fingerprints"`
+	FailedAttempts         uint8       `json:"failed_attempts" yaml:"failed_attempts"`
+}
+
+// SessionEvent indicates the kind of an entry in the session log.
+type SessionEvent string
+
+const (
+	// SessionEventStart is recorded when a session gets started.
+	SessionEventStart SessionEvent = "start"
+
+	// SessionEventStop is recorded when a session gets stopped.
+	SessionEventStop SessionEvent = "stop"
+
+	// SessionEventIntent is recorded when a peer registers its intent to join.
+	SessionEventIntent SessionEvent = "intent"
+
+	// SessionEventIntentRejected is recorded when the intent of a peer gets rejected.
+	SessionEventIntentRejected SessionEvent = "intent-rejected"
+
+	// SessionEventHMACFailure is recorded when a request presents an invalid HMAC.
+	SessionEventHMACFailure SessionEvent = "hmac-failure"
+
+	// SessionEventSourceBanned is recorded when a source gets banned temporarily after too many failed attempts.
+	SessionEventSourceBanned SessionEvent = "source-banned"
+
+	// SessionEventAccepted is recorded when a peer gets added to the session's temporary truststore.
+	SessionEventAccepted SessionEvent = "accepted"
+)
+
+// SessionLogEntry represents an event of a trust establishment session.
+// Depending on the event the details of the peer are empty.
+type SessionLogEntry struct {
+	Time        time.Time    `json:"time" yaml:"time"`
+	Role        SessionRole  `json:"role" yaml:"role"`
+	Event       SessionEvent `json:"event" yaml:"event"`
+	Name        string       `json:"name" yaml:"name"`
+	Address     string       `json:"address" yaml:"address"`
+	Fingerprint string       `json:"fingerprint" yaml:"fingerprint"`
+	Version     string       `json:"version" yaml:"version"`
+	Message     string       `json:"message" yaml:"message"`
+}
+
 // SessionStopPut represents a request made to stop an active session.
 type SessionStopPut struct {
 	Reason string `json:"reason"`
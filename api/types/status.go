@@ -25,4 +25,52 @@ type Status struct {
 
 	// OVNServices is a list of all ovn services running on this member.
 	OVNServices ovnTypes.Services `json:"ovn_services" yaml:"ovn_services"`
+
+	// Health is the health report of each service installed on the member.
+	Health map[ServiceType]HealthReport `json:"health" yaml:"health"`
+}
+
+// HealthStatus represents the health of a service.
+type HealthStatus string
+
+const (
+	// HealthOK represents a service without any known issues.
+	HealthOK HealthStatus = "OK"
+
+	// HealthDegraded represents a service which is functional but has issues that need attention.
+	HealthDegraded HealthStatus = "Degraded"
+
+	// HealthUnhealthy represents a service which isn't functional.
+	HealthUnhealthy HealthStatus = "Unhealthy"
+)
+
+// HealthReport is the health of a service on a cluster member.
+type HealthReport struct {
+	// Status is the overall health of the service.
+	Status HealthStatus `json:"status" yaml:"status"`
+
+	// Messages describes each issue found with the service.
+	Messages []string `json:"messages" yaml:"messages"`
+
+	// Unsupported describes each check which cannot be done using the API of the installed service.
+	Unsupported []string `json:"unsupported,omitempty" yaml:"unsupported,omitempty"`
+}
+
+// NewHealthReport returns a report of a service without any known issues.
+func NewHealthReport() HealthReport {
+	return HealthReport{Status: HealthOK, Messages: []string{}}
+}
+
+// Add records the given issue, and lowers the overall health of the report to the given status if it's worse than the current one.
+func (h *HealthReport) Add(status HealthStatus, message string) {
+	h.Messages = append(h.Messages, message)
+
+	if status == HealthUnhealthy || h.Status == HealthOK {
+		h.Status = status
+	}
+}
+
+// AddUnsupported records a check which cannot be done, without lowering the overall health of the report.
+func (h *HealthReport) AddUnsupported(message string) {
+	h.Unsupported = append(h.Unsupported, message)
 }
@@ -7,6 +7,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/canonical/lxd/shared/api"
@@ -14,6 +16,22 @@ import (
 	"github.com/gorilla/websocket"
 
 	"github.com/canonical/microcloud/microcloud/api/types"
+	"github.com/canonical/microcloud/microcloud/multicast"
+)
+
+// ErrInterrupted is the cause of a context cancelled by the user, for example by pressing Ctrl+C.
+// Operations watched using such a context get cancelled.
+var ErrInterrupted = errors.New("Interrupted by the user")
+
+const (
+	// operationRetryBackoff is the initial delay before retrying to wait for an operation after a transient error.
+	operationRetryBackoff = time.Second
+
+	// maxOperationRetryBackoff is the maximum delay before retrying to wait for an operation after a transient error.
+	maxOperationRetryBackoff = 30 * time.Second
+
+	// operationRetryTimeout is the duration after which waiting for an operation fails if the errors persist.
+	operationRetryTimeout = 5 * time.Minute
 )
 
 // GetStatus fetches a set of status information for the whole cluster.
@@ -41,6 +59,22 @@ func DeleteToken(ctx context.Context, name string, c microTypes.Client) error {
 	return c.Query(queryCtx, "DELETE", microTypes.PublicEndpoint, &api.NewURL().Path("tokens", name).URL, nil, nil)
 }
 
+// RemoveClusterMember removes the given cluster member.
+// We don't use Microcluster's own cluster member removal func from the app to allow this func being called both on
+// the local Microcluster member as well as a remote member.
+// It's using Microcluster's stable API.
+func RemoveClusterMember(ctx context.Context, name string, force bool, c microTypes.Client) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	url := api.NewURL().Path("cluster", name)
+	if force {
+		url = url.WithQuery("force", "1")
+	}
+
+	return c.Query(queryCtx, "DELETE", microTypes.PublicEndpoint, &url.URL, nil, nil)
+}
+
 // GetClusterMembers returns a list of cluster members.
 // We don't use Microcluster's own cluster member list func from the app to allow this func being called both on
 // the local Microcluster member as well as a remote member.
@@ -72,6 +106,53 @@ func StartSession(ctx context.Context, c microTypes.Client, role string, session
 	return conn, nil
 }
 
+// WatchSessions returns a websocket connection receiving the messages of all trust establishment sessions.
+func WatchSessions(ctx context.Context, c microTypes.Client) (*websocket.Conn, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	conn, err := c.Websocket(queryCtx, types.APIVersion, &api.NewURL().Path("session", "events").URL)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to watch sessions: %w", err)
+	}
+
+	return conn, nil
+}
+
+// GetSession returns the state of the active session.
+func GetSession(ctx context.Context, c microTypes.Client) (*types.SessionStatus, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	var status types.SessionStatus
+	err := c.Query(queryCtx, "GET", types.APIVersion, &api.NewURL().Path("session").URL, nil, &status)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get session: %w", err)
+	}
+
+	return &status, nil
+}
+
+// GetSessionLog returns the recorded events of all sessions.
+// If event isn't empty, only the entries of the given event are returned.
+func GetSessionLog(ctx context.Context, c microTypes.Client, event types.SessionEvent) ([]types.SessionLogEntry, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	url := api.NewURL().Path("session", "log")
+	if event != "" {
+		url = url.WithQuery("event", string(event))
+	}
+
+	var entries []types.SessionLogEntry
+	err := c.Query(queryCtx, "GET", types.APIVersion, &url.URL, nil, &entries)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get session log: %w", err)
+	}
+
+	return entries, nil
+}
+
 // StopSession is called from the initiator to stop a joiner session.
 func StopSession(ctx context.Context, c microTypes.Client, stopMsg string) error {
 	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
@@ -86,12 +167,95 @@ func StopSession(ctx context.Context, c microTypes.Client, stopMsg string) error
 	return nil
 }
 
+// GetSessionBeacon fetches the beacon announced by the session of a remote system.
+func GetSessionBeacon(ctx context.Context, c microTypes.Client) (*multicast.Beacon, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	var beacon multicast.Beacon
+	err := c.Query(queryCtx, "GET", types.APIVersion, &api.NewURL().Path("session", "discovery").URL, nil, &beacon)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get session beacon: %w", err)
+	}
+
+	return &beacon, nil
+}
+
+// ProbeSession hands the given beacon to the joining session of a remote system using the nonce of the remote system's beacon.
+// The remote system only accepts the beacon if the request is authorized using the nonce, and returns its own info.
+func ProbeSession(ctx context.Context, c microTypes.Client, nonce string, beacon multicast.Beacon) (*multicast.ServerInfo, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	data, err := json.Marshal(beacon)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal beacon: %w", err)
+	}
+
+	path := api.NewURL().Path("session", "discovery").WithQuery("nonce", nonce).WithQuery("beacon", string(data))
+
+	var remoteInfo multicast.ServerInfo
+	err = c.Query(queryCtx, "GET", types.APIVersion, &path.URL, nil, &remoteInfo)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to probe session: %w", err)
+	}
+
+	return &remoteInfo, nil
+}
+
+// GetSessionInfo fetches the info announced by the session of a remote system using the nonce of its beacon.
+// The info's certificate is set to the certificate presented by the remote system.
+func GetSessionInfo(ctx context.Context, c microTypes.Client, nonce string) (*multicast.ServerInfo, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	path := api.NewURL().Path("session", "discovery").WithQuery("nonce", nonce)
+
+	// Use the raw query to get access to the peer's certificate.
+	resp, err := c.QueryRaw(queryCtx, "GET", types.APIVersion, &path.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get session info: %w", err)
+	}
+
+	response, err := microTypes.ParseResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	info := multicast.ServerInfo{}
+	err = response.MetadataAsStruct(&info)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse session info: %w", err)
+	}
+
+	if len(resp.TLS.PeerCertificates) == 0 {
+		return nil, errors.New("Peer's certificate is missing")
+	}
+
+	info.Certificate = resp.TLS.PeerCertificates[0]
+
+	return &info, nil
+}
+
 // JoinServices sends join information to initiate the cluster join process.
-func JoinServices(ctx context.Context, c microTypes.Client, data types.ServicesPut) error {
+// The join runs as an operation on the remote system and the given progress function is called whenever its state gets refreshed.
+func JoinServices(ctx context.Context, c microTypes.Client, data types.ServicesPut, progress func(op types.Operation)) error {
 	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 	defer cancel()
 
-	err := c.Query(queryCtx, "PUT", types.APIVersion, &api.NewURL().Path("services").URL, data, nil)
+	var op types.Operation
+	path := api.NewURL().Path("services").WithQuery("async", "1")
+	err := c.Query(queryCtx, "PUT", types.APIVersion, &path.URL, data, &op)
+	if err != nil {
+		return fmt.Errorf("Failed to update cluster status of services: %w", err)
+	}
+
+	// Systems without support for operations already finished joining when responding.
+	if op.ID == "" {
+		return nil
+	}
+
+	err = WatchOperation(ctx, c, op.ID, progress)
 	if err != nil {
 		return fmt.Errorf("Failed to update cluster status of services: %w", err)
 	}
@@ -152,14 +316,147 @@ func RemoteIssueToken(ctx context.Context, c microTypes.Client, serviceType type
 }
 
 // DeleteClusterMember removes the cluster member from any service that it is part of.
-func DeleteClusterMember(ctx context.Context, c microTypes.Client, memberName string, force bool) error {
+// The removal runs as an operation and the given progress function is called whenever its state gets refreshed.
+func DeleteClusterMember(ctx context.Context, c microTypes.Client, memberName string, force bool, progress func(op types.Operation)) error {
 	queryCtx, cancel := context.WithTimeout(ctx, time.Minute)
 	defer cancel()
 
-	path := api.NewURL().Path("services", "cluster", memberName)
+	path := api.NewURL().Path("services", "cluster", memberName).WithQuery("async", "1")
 	if force {
 		path = path.WithQuery("force", "1")
 	}
 
-	return c.Query(queryCtx, "DELETE", types.APIVersion, &path.URL, nil, nil)
+	var op types.Operation
+	err := c.Query(queryCtx, "DELETE", types.APIVersion, &path.URL, nil, &op)
+	if err != nil {
+		return err
+	}
+
+	// Systems without support for operations already finished the removal when responding.
+	if op.ID == "" {
+		return nil
+	}
+
+	return WatchOperation(ctx, c, op.ID, progress)
+}
+
+// GetOperation returns the current state of the operation with the given ID.
+func GetOperation(ctx context.Context, c microTypes.Client, id string) (*types.Operation, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	var op types.Operation
+	err := c.Query(queryCtx, "GET", types.APIVersion, &api.NewURL().Path("operations", id).URL, nil, &op)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get operation: %w", err)
+	}
+
+	return &op, nil
+}
+
+// WaitOperation waits for the operation with the given ID to finish, or until the timeout is reached, and returns its state.
+func WaitOperation(ctx context.Context, c microTypes.Client, id string, timeout time.Duration) (*types.Operation, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, timeout+time.Minute)
+	defer cancel()
+
+	var op types.Operation
+	path := api.NewURL().Path("operations", id, "wait").WithQuery("timeout", strconv.Itoa(int(timeout.Seconds())))
+	err := c.Query(queryCtx, "GET", types.APIVersion, &path.URL, nil, &op)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to wait for operation: %w", err)
+	}
+
+	return &op, nil
+}
+
+// CancelOperation cancels the operation with the given ID.
+func CancelOperation(ctx context.Context, c microTypes.Client, id string) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	err := c.Query(queryCtx, "DELETE", types.APIVersion, &api.NewURL().Path("operations", id).URL, nil, nil)
+	if err != nil {
+		return fmt.Errorf("Failed to cancel operation: %w", err)
+	}
+
+	return nil
+}
+
+// WatchOperation waits for the operation with the given ID to finish and returns its error.
+// The given progress function, if set, is called each time the state of the operation gets refreshed.
+// Transient errors while waiting, e.g. when the remote daemon restarts, are retried with an increasing backoff.
+// The operation is only cancelled if the context gets cancelled with ErrInterrupted, otherwise it keeps running
+// on the remote system when the context is done.
+func WatchOperation(ctx context.Context, c microTypes.Client, id string, progress func(op types.Operation)) error {
+	backoff := operationRetryBackoff
+	var failingSince time.Time
+	for {
+		op, err := WaitOperation(ctx, c, id, 2*time.Second)
+		if err != nil {
+			if ctx.Err() != nil {
+				if !errors.Is(context.Cause(ctx), ErrInterrupted) {
+					return err
+				}
+
+				cancelCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+				defer cancel()
+
+				cancelErr := CancelOperation(cancelCtx, c, id)
+				if cancelErr != nil {
+					return fmt.Errorf("%w. %w", err, cancelErr)
+				}
+
+				return err
+			}
+
+			if failingSince.IsZero() {
+				failingSince = time.Now()
+			}
+
+			if !transientError(err) || time.Since(failingSince) > operationRetryTimeout {
+				return err
+			}
+
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+			}
+
+			backoff = min(backoff*2, maxOperationRetryBackoff)
+			continue
+		}
+
+		backoff = operationRetryBackoff
+		failingSince = time.Time{}
+
+		if progress != nil {
+			progress(*op)
+		}
+
+		switch op.Status {
+		case types.OperationRunning:
+			continue
+		case types.OperationSuccess:
+			return nil
+		case types.OperationCancelled:
+			return fmt.Errorf("Operation %q was cancelled: %s", op.Description, op.Error)
+		default:
+			if op.StatusCode != 0 {
+				return api.NewStatusError(op.StatusCode, op.Error)
+			}
+
+			return errors.New(op.Error)
+		}
+	}
+}
+
+// transientError returns true if the given error of a request might not occur anymore when retrying it,
+// for example if the connection dropped or the remote daemon is restarting.
+func transientError(err error) bool {
+	code, ok := api.StatusErrorMatch(err)
+	if !ok {
+		return true
+	}
+
+	return code >= http.StatusInternalServerError
 }
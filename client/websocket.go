@@ -87,6 +87,7 @@ func NewWebsocketGateway(ctx context.Context, conn *websocket.Conn) *WebsocketGa
 				return
 			}
 
+			gw.notify(reader)
 			gw.reader <- reader
 		}
 	}()
@@ -126,8 +127,44 @@ func (w *WebsocketGateway) Context() context.Context {
 	return w.ctx
 }
 
+// Observe sets a function which gets called with each message written to or read from the websocket connection.
+// Control close messages aren't passed to the observer.
+func (w *WebsocketGateway) Observe(f func(data []byte)) {
+	w.observerLock.Lock()
+	defer w.observerLock.Unlock()
+
+	w.observer = f
+}
+
+// notify passes the given message to the observer if there is any.
+func (w *WebsocketGateway) notify(data []byte) {
+	w.observerLock.RLock()
+	defer w.observerLock.RUnlock()
+
+	if w.observer != nil {
+		w.observer(data)
+	}
+}
+
 // Write writes the given data onto the websocket connection.
 func (w *WebsocketGateway) Write(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	err = w.write(data)
+	if err != nil {
+		return err
+	}
+
+	w.notify(data)
+
+	return nil
+}
+
+// write writes the given JSON encoded message onto the websocket connection.
+func (w *WebsocketGateway) write(data []byte) error {
 	w.writeLock.Lock()
 	defer w.writeLock.Unlock()
 
@@ -135,7 +172,7 @@ func (w *WebsocketGateway) Write(v any) error {
 		return context.Cause(w.ctx)
 	}
 
-	return w.conn.WriteJSON(v)
+	return w.conn.WriteMessage(websocket.TextMessage, data)
 }
 
 // WriteClose sends our websocket control close message.
@@ -143,9 +180,14 @@ func (w *WebsocketGateway) Write(v any) error {
 // as well as special characters.
 // It waits for the other side to hang up or the gateway's context being cancelled.
 func (w *WebsocketGateway) WriteClose(err error) error {
-	writeErr := w.Write(ControlClose{
+	data, marshalErr := json.Marshal(ControlClose{
 		ControlMessage: err.Error(),
 	})
+	if marshalErr != nil {
+		return fmt.Errorf("Failed to marshal control message: %w", marshalErr)
+	}
+
+	writeErr := w.write(data)
 	if writeErr != nil {
 		return fmt.Errorf("Failed to write control message: %w", writeErr)
 	}
@@ -2,18 +2,23 @@ package main
 
 import (
 	"context"
+	"crypto"
 	"errors"
 	"fmt"
 	"net"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/canonical/lxd/lxd/util"
+	lxdAPI "github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/revert"
+	cephTypes "github.com/canonical/microceph/microceph/api/types"
 	"github.com/canonical/microcluster/v3/microcluster"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 
-	"github.com/canonical/microcloud/microcloud/api"
 	"github.com/canonical/microcloud/microcloud/api/types"
 	cloudClient "github.com/canonical/microcloud/microcloud/client"
 	"github.com/canonical/microcloud/microcloud/cmd/tui"
@@ -24,7 +29,19 @@ import (
 type cmdAdd struct {
 	common *CmdControl
 
-	flagSessionTimeout int64
+	flagSessionTimeout      int64
+	flagDiscovery           string
+	flagSeedAddresses       []string
+	flagDiscoveryInterfaces []string
+	flagAllowFingerprints   []string
+	flagAllowCertificates   string
+	flagPassphraseWords     uint8
+	flagWordlist            string
+	flagResume              bool
+	flagDiscardInterrupted  bool
+	flagBundle              string
+	flagBundleSystem        string
+	flagBundleConfig        string
 }
 
 // command returns the subcommand to add new systems to MicroCloud.
@@ -36,6 +53,18 @@ func (c *cmdAdd) command() *cobra.Command {
 	}
 
 	cmd.Flags().Int64Var(&c.flagSessionTimeout, "session-timeout", 0, "Amount of seconds to wait for the trust establishment session. Defaults: 60m")
+	cmd.Flags().StringVar(&c.flagDiscovery, "discovery", string(types.DiscoveryMulticast), "Mechanism used to find other systems (multicast|mdns|static)")
+	cmd.Flags().StringSliceVar(&c.flagSeedAddresses, "seed-address", nil, "Address of a system to probe using static discovery (implies --discovery=static)")
+	cmd.Flags().StringSliceVar(&c.flagDiscoveryInterfaces, "discovery-interface", nil, "Additional interface on which other systems can find this system (\"all\" for every interface)")
+	cmd.Flags().StringSliceVar(&c.flagAllowFingerprints, "allow-fingerprint", nil, "Certificate fingerprint of a system to confirm automatically (rejects all other systems)")
+	cmd.Flags().StringVar(&c.flagAllowCertificates, "allow-certificates", "", "Directory of PEM certificates of the systems to confirm automatically (rejects all other systems)"+"``")
+	cmd.Flags().Uint8Var(&c.flagPassphraseWords, "passphrase-words", service.PassphraseWordCount, "Number of words in the session passphrase (4 to 8)"+"``")
+	cmd.Flags().StringVar(&c.flagWordlist, "wordlist", service.DefaultWordlist, "Name of the wordlist used for the session passphrase"+"``")
+	cmd.Flags().BoolVar(&c.flagResume, "resume", false, "Resume the trust establishment session interrupted by a restart using its passphrase")
+	cmd.Flags().BoolVar(&c.flagDiscardInterrupted, "discard-interrupted", false, "Discard the trust establishment session interrupted by a restart and start a new one")
+	cmd.Flags().StringVar(&c.flagBundle, "bundle", "", "Write an offline join bundle for a single system to the given file instead of running a session"+"``")
+	cmd.Flags().StringVar(&c.flagBundleSystem, "bundle-system", "", "Name of the system allowed to join using the offline join bundle"+"``")
+	cmd.Flags().StringVar(&c.flagBundleConfig, "bundle-config", "", "YAML file with the address, disks and uplink interface of the system joining using the offline join bundle, using the format of a preseed system"+"``")
 
 	return cmd
 }
@@ -46,6 +75,22 @@ func (c *cmdAdd) run(cmd *cobra.Command, args []string) error {
 		return cmd.Help()
 	}
 
+	if (c.flagBundle == "") != (c.flagBundleSystem == "") {
+		return errors.New("Offline join bundles require both --bundle and --bundle-system")
+	}
+
+	if c.flagBundleConfig != "" && c.flagBundle == "" {
+		return errors.New("The configuration of a joining system can only be used with --bundle")
+	}
+
+	if c.flagResume && c.flagDiscardInterrupted {
+		return errors.New("The interrupted session cannot be both resumed and discarded")
+	}
+
+	if c.flagBundle != "" && c.flagResume {
+		return errors.New("Offline join bundles cannot be written when resuming a session")
+	}
+
 	fmt.Println("Waiting for services to start ...")
 	err := checkInitialized(c.common.FlagMicroCloudDir, true, false)
 	if err != nil {
@@ -61,11 +106,35 @@ func (c *cmdAdd) run(cmd *cobra.Command, args []string) error {
 		state:     map[string]service.SystemInformation{},
 	}
 
+	cfg.resume = c.flagResume
+	cfg.discardInterrupted = c.flagDiscardInterrupted
 	cfg.sessionTimeout = DefaultSessionTimeout
 	if c.flagSessionTimeout > 0 {
 		cfg.sessionTimeout = time.Duration(c.flagSessionTimeout) * time.Second
 	}
 
+	cfg.discovery, err = parseDiscoveryMode(c.flagDiscovery)
+	if err != nil {
+		return err
+	}
+
+	err = cfg.setSeedAddresses(c.flagSeedAddresses, cmd.Flags().Changed("discovery"))
+	if err != nil {
+		return err
+	}
+
+	cfg.discoveryInterfaces = c.flagDiscoveryInterfaces
+
+	err = cfg.setAllowedFingerprints(c.flagAllowFingerprints, c.flagAllowCertificates)
+	if err != nil {
+		return err
+	}
+
+	err = cfg.setPassphraseStrength(c.flagPassphraseWords, c.flagWordlist)
+	if err != nil {
+		return err
+	}
+
 	cloudApp, err := microcluster.App(microcluster.Args{StateDir: c.common.FlagMicroCloudDir})
 	if err != nil {
 		return err
@@ -78,18 +147,20 @@ func (c *cmdAdd) run(cmd *cobra.Command, args []string) error {
 
 	cfg.name = status.Name
 	cfg.address = status.Address.Addr().String()
+
+	// Offline join bundles replace the trust establishment session.
+	if c.flagBundle != "" {
+		return c.writeBundle(cfg)
+	}
+
 	err = cfg.askAddress("")
 	if err != nil {
 		return err
 	}
 
-	installedServices := []types.ServiceType{types.MicroCloud, types.LXD}
-	optionalServices := map[types.ServiceType]string{
-		types.MicroCeph: api.MicroCephDir,
-		types.MicroOVN:  api.MicroOVNDir,
-	}
+	installedServices := service.RequiredServices()
 
-	installedServices, err = cfg.askMissingServices(installedServices, optionalServices)
+	installedServices, err = cfg.askMissingServices(installedServices)
 	if err != nil {
 		return err
 	}
@@ -262,3 +333,200 @@ func (c *cmdAdd) run(cmd *cobra.Command, args []string) error {
 	fmt.Println(tui.SuccessColor("MicroCloud is ready", true))
 	return nil
 }
+
+// writeBundle issues a join token of each local service for the system given by --bundle-system, and writes them to
+// an offline join bundle signed by the cluster certificate.
+// The bundle also contains the member specific configuration of the cluster's storage pools and networks given by --bundle-config.
+func (c *cmdAdd) writeBundle(cfg initConfig) error {
+	system := System{Name: c.flagBundleSystem}
+	if c.flagBundleConfig != "" {
+		content, err := os.ReadFile(c.flagBundleConfig)
+		if err != nil {
+			return fmt.Errorf("Failed to read configuration of system %q: %w", c.flagBundleSystem, err)
+		}
+
+		err = yaml.Unmarshal(content, &system)
+		if err != nil {
+			return fmt.Errorf("Failed to parse configuration of system %q: %w", c.flagBundleSystem, err)
+		}
+
+		if system.Name != c.flagBundleSystem {
+			return fmt.Errorf("Configuration is for system %q instead of %q", system.Name, c.flagBundleSystem)
+		}
+	}
+
+	// Only issue tokens for the services which are installed.
+	cfg.autoSetup = true
+	installedServices, err := cfg.askMissingServices(service.RequiredServices())
+	if err != nil {
+		return err
+	}
+
+	s, err := service.NewHandler(cfg.name, cfg.address, c.common.FlagMicroCloudDir, installedServices...)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	localInfo, err := s.CollectSystemInformation(ctx, multicast.ServerInfo{Name: cfg.name, Address: cfg.address})
+	if err != nil {
+		return err
+	}
+
+	joinConfig, err := bundleJoinConfig(s, localInfo, system)
+	if err != nil {
+		return err
+	}
+
+	reverter := revert.New()
+	defer reverter.Fail()
+
+	for _, s := range s.Services {
+		members, err := s.ClusterMembers(ctx)
+		if err != nil {
+			return fmt.Errorf("Failed to get %s cluster members: %w", s.Type(), err)
+		}
+
+		if members[c.flagBundleSystem] != "" {
+			return fmt.Errorf("System %q is already part of the %s cluster", c.flagBundleSystem, s.Type())
+		}
+
+		token, err := s.IssueToken(ctx, c.flagBundleSystem)
+		if err != nil {
+			return fmt.Errorf("Failed to issue %s token for peer %q: %w", s.Type(), c.flagBundleSystem, err)
+		}
+
+		reverter.Add(func() {
+			err := s.DeleteToken(ctx, c.flagBundleSystem, "")
+			if err != nil {
+				logger.Error("Failed to clean up join token", logger.Ctx{"service": s.Type(), "error": err})
+			}
+		})
+
+		joinConfig.Tokens = append(joinConfig.Tokens, types.ServiceToken{Service: s.Type(), JoinToken: token})
+	}
+
+	cloud := s.Services[types.MicroCloud].(*service.CloudService)
+	clusterCert, err := cloud.ClusterCert()
+	if err != nil {
+		return fmt.Errorf("Failed to get cluster certificate: %w", err)
+	}
+
+	signer, ok := clusterCert.KeyPair().PrivateKey.(crypto.Signer)
+	if !ok {
+		return errors.New("Cluster certificate key cannot be used for signing")
+	}
+
+	// The bundle can only be used as long as all of its tokens are valid.
+	now := time.Now()
+	bundle := joinBundle{
+		Version:            joinBundleVersion,
+		Name:               c.flagBundleSystem,
+		ClusterAddress:     cfg.address,
+		ClusterCertificate: string(clusterCert.PublicKey()),
+		Issued:             now,
+		Expires:            now.Add(service.ServiceJoinTokenLifetime),
+		Config:             joinConfig,
+	}
+
+	// The bundle contains secrets so only the owner can read it.
+	f, err := os.OpenFile(c.flagBundle, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("Failed to create join bundle: %w", err)
+	}
+
+	defer f.Close()
+
+	reverter.Add(func() { _ = os.Remove(c.flagBundle) })
+
+	err = writeJoinBundle(f, bundle, signer)
+	if err != nil {
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("Failed to write join bundle: %w", err)
+	}
+
+	reverter.Success()
+
+	fmt.Println(tui.SummarizeResult("Wrote join bundle for system %s to %s", c.flagBundleSystem, c.flagBundle))
+	fmt.Printf("Copy the bundle to %s and run %q before %s\n", c.flagBundleSystem, "microcloud join --bundle "+filepath.Base(c.flagBundle)+" --fingerprint "+clusterCert.Fingerprint(), bundle.Expires.Format(time.RFC3339))
+	fmt.Println("Pass the fingerprint separately from the bundle, so the joining system can verify who issued the bundle")
+
+	return nil
+}
+
+// bundleJoinConfig returns the configuration which the given system requires to join the cluster using an offline join bundle.
+// The joining system cannot be inspected when issuing the bundle, so the member specific configuration of the cluster's
+// storage pools and networks is taken from the given system. The tokens are added by the caller.
+func bundleJoinConfig(sh *service.Handler, info *service.SystemInformation, system System) (types.ServicesPut, error) {
+	joinConfig := types.ServicesPut{
+		Tokens:     make([]types.ServiceToken, 0, len(sh.Services)),
+		Address:    system.Address,
+		LXDConfig:  []lxdAPI.ClusterMemberConfigKey{},
+		CephConfig: []cephTypes.DisksPost{},
+	}
+
+	if system.Address != "" && net.ParseIP(system.Address) == nil {
+		return types.ServicesPut{}, fmt.Errorf("Invalid address %q of system %q", system.Address, system.Name)
+	}
+
+	lxd := sh.Services[types.LXD].(*service.LXDService)
+
+	// Each cluster member requires its own disk for the local storage pool.
+	hasLocalPool, _ := info.SupportsLocalPool()
+	if hasLocalPool {
+		if system.Storage.Local.Path == "" {
+			return types.ServicesPut{}, fmt.Errorf("System %q requires a disk for the %q storage pool", system.Name, service.DefaultZFSPool)
+		}
+
+		joinConfig.LXDConfig = append(joinConfig.LXDConfig, lxd.DefaultZFSStoragePoolJoinConfig(system.Storage.Local.Wipe, system.Storage.Local.Path)...)
+	} else if system.Storage.Local.Path != "" {
+		return types.ServicesPut{}, fmt.Errorf("Cluster doesn't have a %q storage pool", service.DefaultZFSPool)
+	}
+
+	// Each cluster member requires its own parent interface for the uplink network.
+	hasOVN, _ := info.SupportsOVNNetwork()
+	if hasOVN {
+		if system.UplinkInterface == "" {
+			return types.ServicesPut{}, fmt.Errorf("System %q requires an interface for the %q network", system.Name, service.DefaultUplinkNetwork)
+		}
+
+		joinConfig.LXDConfig = append(joinConfig.LXDConfig, lxd.DefaultOVNNetworkJoinConfig(system.UplinkInterface))
+	} else if system.UplinkInterface != "" || system.UnderlayIP != "" {
+		return types.ServicesPut{}, fmt.Errorf("Cluster doesn't have a %q network", service.DefaultUplinkNetwork)
+	}
+
+	if system.UnderlayIP != "" {
+		underlayIP := net.ParseIP(system.UnderlayIP)
+		if underlayIP == nil {
+			return types.ServicesPut{}, fmt.Errorf("Invalid OVN underlay IP %q of system %q", system.UnderlayIP, system.Name)
+		}
+
+		joinConfig.OVNConfig = map[string]string{"ovn-encap-ip": underlayIP.String()}
+	}
+
+	if len(system.Storage.Ceph) > 0 && sh.Services[types.MicroCeph] == nil {
+		return types.ServicesPut{}, fmt.Errorf("Cannot add disks of system %q to %s as it isn't installed", system.Name, types.MicroCeph)
+	}
+
+	for _, disk := range system.Storage.Ceph {
+		joinConfig.CephConfig = append(joinConfig.CephConfig, cephTypes.DisksPost{Path: []string{disk.Path}, Wipe: disk.Wipe, Encrypt: disk.Encrypt})
+	}
+
+	hasRemoteFSPool, _ := info.SupportsRemoteFSPool()
+	if hasRemoteFSPool {
+		req, err := lxd.DefaultCephFSStoragePoolJoinConfig()
+		if err != nil {
+			return types.ServicesPut{}, err
+		}
+
+		if req != nil {
+			joinConfig.LXDConfig = append(joinConfig.LXDConfig, *req)
+		}
+	}
+
+	return joinConfig, nil
+}
@@ -24,7 +24,6 @@ import (
 	cephTypes "github.com/canonical/microceph/microceph/api/types"
 	microTypes "github.com/canonical/microcluster/v3/microcluster/types"
 
-	cloudAPI "github.com/canonical/microcloud/microcloud/api"
 	"github.com/canonical/microcloud/microcloud/api/types"
 	cloudClient "github.com/canonical/microcloud/microcloud/client"
 	"github.com/canonical/microcloud/microcloud/cmd/tui"
@@ -45,19 +44,15 @@ func (a askRetryWarningError) Error() string {
 func checkInitialized(stateDir string, expectInitialized bool, preseed bool) error {
 	cfg := initConfig{autoSetup: true}
 
-	installedServices := []types.ServiceType{types.MicroCloud, types.LXD}
+	installedServices := service.RequiredServices()
 
 	// MicroCloud will automatically set up previously-uninitialized services,
 	// and incorporate already-initialized services in interactive setup,
 	// so we can ignore optional services unless using preseed.
 	if preseed {
-		optionalServices := map[types.ServiceType]string{
-			types.MicroCeph: cloudAPI.MicroCephDir,
-			types.MicroOVN:  cloudAPI.MicroOVNDir,
-		}
 
 		var err error
-		installedServices, err = cfg.askMissingServices(installedServices, optionalServices)
+		installedServices, err = cfg.askMissingServices(installedServices)
 		if err != nil {
 			return err
 		}
@@ -68,7 +63,7 @@ func checkInitialized(stateDir string, expectInitialized bool, preseed bool) err
 		return err
 	}
 
-	return s.RunConcurrent("", "", func(s service.Service) error {
+	return s.RunConcurrent(service.RunParallel, func(s service.Service) error {
 		// Create initialization context with timeout defined in LXDInitializationTimeout.
 		initializationCtx, cancel := context.WithTimeout(context.Background(), service.LXDInitializationTimeout)
 		defer cancel()
@@ -183,13 +178,19 @@ func (c *initConfig) askRetry(question string, f func() error) error {
 	}
 }
 
-func (c *initConfig) askMissingServices(services []types.ServiceType, stateDirs map[types.ServiceType]string) ([]types.ServiceType, error) {
+// askMissingServices adds the registered optional services installed on the local system to the given services.
+// If any of them aren't installed, it asks whether to continue without them.
+func (c *initConfig) askMissingServices(services []types.ServiceType) ([]types.ServiceType, error) {
 	missingServices := []string{}
-	for serviceType, stateDir := range stateDirs {
-		if service.Exists(serviceType, stateDir) {
-			services = append(services, serviceType)
+	for _, registration := range service.OptionalServices() {
+		if slices.Contains(services, registration.Type) {
+			continue
+		}
+
+		if registration.Installed() {
+			services = append(services, registration.Type)
 		} else {
-			missingServices = append(missingServices, string(serviceType))
+			missingServices = append(missingServices, string(registration.Type))
 		}
 	}
 
@@ -1698,7 +1699,16 @@ func (c *initConfig) shortFingerprint(fingerprint string) (string, error) {
 	return fingerprint[0:12], nil
 }
 
-func (c *initConfig) askPassphrase() (string, error) {
+// askPassphrase asks for a passphrase generated using the wordlist with the given name.
+// If the number of words is known, the passphrase has to consist of exactly that many words.
+func (c *initConfig) askPassphrase(msg string, wordlistName string, words uint8) (string, error) {
+	wordlist, err := c.loadWordlist(wordlistName)
+	if err != nil {
+		// The wordlist is only used to complete the words, so the passphrase can still be entered without it.
+		tui.PrintWarning(fmt.Sprintf("Passphrase words cannot be completed as the wordlist %q isn't available: %v", wordlistName, err))
+		wordlist = []string{}
+	}
+
 	format := func(password string) (string, error) {
 		passwordSplit := strings.Split(password, " ")
 
@@ -1706,8 +1716,16 @@ func (c *initConfig) askPassphrase() (string, error) {
 			return element == ""
 		})
 
-		if len(passwordClean) != 4 {
-			return "", errors.New("Passphrase has to contain exactly four elements")
+		// Passphrases given to the initiator aren't generated, so they can consist of any number of words.
+		if words > 0 {
+			if len(passwordClean) != int(words) {
+				return "", fmt.Errorf("Passphrase has to contain %d words", words)
+			}
+		} else {
+			err := service.ValidatePassphraseWordCount(uint8(min(len(passwordClean), 255)))
+			if err != nil {
+				return "", err
+			}
 		}
 
 		return strings.Join(passwordClean, " "), nil
@@ -1722,8 +1740,13 @@ func (c *initConfig) askPassphrase() (string, error) {
 		return err
 	}
 
-	msg := "Specify the passphrase for joining the system"
-	password, err := c.asker.AskPassphrase(msg, service.Wordlist, validator, service.PassphraseWordCount)
+	// Complete up to the maximum number of words if the initiator didn't announce its number of words.
+	maxWords := words
+	if maxWords == 0 {
+		maxWords = service.MaxPassphraseWordCount
+	}
+
+	password, err := c.asker.AskPassphrase(msg, wordlist, validator, maxWords)
 	if err != nil {
 		return "", err
 	}
@@ -1738,10 +1761,20 @@ func (c *initConfig) askJoinIntents(gw *cloudClient.WebsocketGateway, expectedSy
 
 	joinIntents := make(map[string]types.SessionJoinPost)
 
+	// Pre-approved systems are confirmed automatically.
+	// Without a preseed, each of the allowed fingerprints is expected to reach out.
+	intentFingerprints := make(map[string]string, len(c.allowedFingerprints))
+	expectedIntents := len(expectedSystems)
+	if expectedIntents == 0 {
+		expectedIntents = len(c.allowedFingerprints)
+	}
+
 	renderCtx, renderCancel := context.WithCancel(gw.Context())
 	defer renderCancel()
 
 	renderIntentsInteractive := func() {
+		// Rows are only ever appended so their index is the number of rows inserted before.
+		rowCount := 0
 		for {
 			select {
 			case bytes := <-gw.Receive():
@@ -1752,6 +1785,16 @@ func (c *initConfig) askJoinIntents(gw *cloudClient.WebsocketGateway, expectedSy
 					break
 				}
 
+				// Show incompatible systems too so the user knows why they cannot be selected.
+				if session.IncompatibleSystem != nil {
+					system := session.IncompatibleSystem
+					versions := multicast.NewVersionRange(system.MinVersion, system.Version)
+					table.SendUpdate(tui.InsertMsg{system.Name, system.Address, fmt.Sprintf("Incompatible versions %q", versions.String())})
+					table.SendUpdate(tui.DisableMsg(rowCount))
+					rowCount++
+					continue
+				}
+
 				joinIntents[session.Intent.Name] = session.Intent
 
 				remoteCert, err := shared.ParseCert([]byte(session.Intent.Certificate))
@@ -1765,6 +1808,7 @@ func (c *initConfig) askJoinIntents(gw *cloudClient.WebsocketGateway, expectedSy
 				}
 
 				table.SendUpdate(tui.InsertMsg{session.Intent.Name, session.Intent.Address, fingerprint})
+				rowCount++
 
 			case <-renderCtx.Done():
 				return
@@ -1783,13 +1827,30 @@ func (c *initConfig) askJoinIntents(gw *cloudClient.WebsocketGateway, expectedSy
 					break
 				}
 
+				if session.IncompatibleSystem != nil {
+					tui.PrintWarning(incompatibleSystemWarning(*session.IncompatibleSystem))
+					continue
+				}
+
 				// Skip systems which aren't listed in the preseed.
-				if !slices.Contains(expectedSystems, session.Intent.Name) {
+				if len(expectedSystems) > 0 && !slices.Contains(expectedSystems, session.Intent.Name) {
 					continue
 				}
 
+				// Skip systems whose certificate isn't pre-approved.
+				// The name isn't enough to identify a pre-approved system as any system can pick it.
+				if len(c.allowedFingerprints) > 0 {
+					fingerprint, err := shared.CertFingerprintStr(session.Intent.Certificate)
+					if err != nil || !slices.Contains(c.allowedFingerprints, fingerprint) {
+						logger.Warn("Ignoring join intent of system with unknown certificate", logger.Ctx{"name": session.Intent.Name, "address": session.Intent.Address})
+						continue
+					}
+
+					intentFingerprints[fingerprint] = session.Intent.Name
+				}
+
 				joinIntents[session.Intent.Name] = session.Intent
-				if len(joinIntents) == len(expectedSystems) {
+				if len(joinIntents) == expectedIntents {
 					renderCancel()
 				}
 
@@ -1800,7 +1861,7 @@ func (c *initConfig) askJoinIntents(gw *cloudClient.WebsocketGateway, expectedSy
 	}
 
 	var systems []types.SessionJoinPost
-	if !c.autoSetup {
+	if !c.autoSetup && len(c.allowedFingerprints) == 0 {
 		go renderIntentsInteractive()
 		var answers []map[string]string
 		err := c.askRetry("Retry selecting systems?", func() error {
@@ -1831,8 +1892,14 @@ func (c *initConfig) askJoinIntents(gw *cloudClient.WebsocketGateway, expectedSy
 	} else {
 		go renderIntents()
 
+		// Without a lookup timeout wait until the session ends.
+		var lookupTimeout <-chan time.Time
+		if c.lookupTimeout > 0 {
+			lookupTimeout = time.After(c.lookupTimeout)
+		}
+
 		select {
-		case <-time.After(c.lookupTimeout):
+		case <-lookupTimeout:
 		case <-renderCtx.Done():
 		}
 
@@ -1843,6 +1910,15 @@ func (c *initConfig) askJoinIntents(gw *cloudClient.WebsocketGateway, expectedSy
 			}
 		}
 
+		if len(expectedSystems) == 0 {
+			for _, fingerprint := range c.allowedFingerprints {
+				_, ok := intentFingerprints[fingerprint]
+				if !ok {
+					return nil, fmt.Errorf("System with fingerprint %q hasn't reached out", fingerprint)
+				}
+			}
+		}
+
 		for _, intent := range joinIntents {
 			systems = append(systems, intent)
 		}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"archive/tar"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/canonical/lxd/shared"
+
+	"github.com/canonical/microcloud/microcloud/api/types"
+)
+
+// joinBundleVersion is the version of the join bundle format.
+const joinBundleVersion = 1
+
+const (
+	// joinBundleFile is the name of the file containing the join bundle inside of its archive.
+	joinBundleFile = "bundle.json"
+
+	// joinBundleSignatureFile is the name of the file containing the join bundle's signature inside of its archive.
+	joinBundleSignatureFile = "bundle.json.sig"
+
+	// maxJoinBundleFileSize is the maximum size of each file inside of a join bundle archive.
+	maxJoinBundleFileSize = 1024 * 1024
+)
+
+// joinBundle contains everything a system needs to join an existing MicroCloud without a trust establishment session.
+type joinBundle struct {
+	Version            int               `json:"version"`
+	Name               string            `json:"name"`
+	ClusterAddress     string            `json:"cluster_address"`
+	ClusterCertificate string            `json:"cluster_certificate"`
+	Issued             time.Time         `json:"issued"`
+	Expires            time.Time         `json:"expires"`
+	Config             types.ServicesPut `json:"config"`
+}
+
+// writeJoinBundle writes the given join bundle and its signature made using the given cluster key as a tar archive.
+func writeJoinBundle(w io.Writer, bundle joinBundle, signer crypto.Signer) error {
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Failed to encode join bundle: %w", err)
+	}
+
+	signature, err := signJoinBundle(data, signer)
+	if err != nil {
+		return fmt.Errorf("Failed to sign join bundle: %w", err)
+	}
+
+	tw := tar.NewWriter(w)
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{name: joinBundleFile, content: data},
+		{name: joinBundleSignatureFile, content: signature},
+	}
+
+	for _, file := range files {
+		err = tw.WriteHeader(&tar.Header{Name: file.name, Mode: 0600, Size: int64(len(file.content)), ModTime: bundle.Issued})
+		if err != nil {
+			return fmt.Errorf("Failed to write join bundle: %w", err)
+		}
+
+		_, err = tw.Write(file.content)
+		if err != nil {
+			return fmt.Errorf("Failed to write join bundle: %w", err)
+		}
+	}
+
+	return tw.Close()
+}
+
+// readJoinBundle reads the join bundle archive at the given path.
+func readJoinBundle(path string, fingerprint string, now time.Time) (*joinBundle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open join bundle: %w", err)
+	}
+
+	defer f.Close()
+
+	return parseJoinBundle(f, fingerprint, now)
+}
+
+// parseJoinBundle parses a join bundle archive and verifies it wasn't modified since it got issued.
+// The cluster certificate contained in the bundle has to match the given fingerprint which the user obtained out-of-band,
+// as anyone can create a self-signed bundle. The bundle has to be signed by that certificate, and the MicroCloud join
+// token has to belong to the cluster of that certificate.
+func parseJoinBundle(r io.Reader, fingerprint string, now time.Time) (*joinBundle, error) {
+	files := map[string][]byte{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read join bundle: %w", err)
+		}
+
+		if hdr.Name != joinBundleFile && hdr.Name != joinBundleSignatureFile {
+			return nil, fmt.Errorf("Unexpected file %q in join bundle", hdr.Name)
+		}
+
+		if hdr.Size > maxJoinBundleFileSize {
+			return nil, fmt.Errorf("File %q in join bundle is too large", hdr.Name)
+		}
+
+		files[hdr.Name], err = io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read join bundle: %w", err)
+		}
+	}
+
+	data, ok := files[joinBundleFile]
+	if !ok {
+		return nil, errors.New("Join bundle is missing its configuration")
+	}
+
+	signature, ok := files[joinBundleSignatureFile]
+	if !ok {
+		return nil, errors.New("Join bundle is missing its signature")
+	}
+
+	bundle := joinBundle{}
+	err := json.Unmarshal(data, &bundle)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse join bundle: %w", err)
+	}
+
+	if bundle.Version != joinBundleVersion {
+		return nil, fmt.Errorf("Unsupported join bundle version %d", bundle.Version)
+	}
+
+	block, _ := pem.Decode([]byte(bundle.ClusterCertificate))
+	if block == nil {
+		return nil, errors.New("Join bundle contains an invalid cluster certificate")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse cluster certificate of join bundle: %w", err)
+	}
+
+	if shared.CertFingerprint(cert) != fingerprint {
+		return nil, fmt.Errorf("Join bundle wasn't issued by the cluster with fingerprint %q", fingerprint)
+	}
+
+	err = verifyJoinBundle(data, signature, cert)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid join bundle signature: %w", err)
+	}
+
+	var cloudToken string
+	for _, token := range bundle.Config.Tokens {
+		if token.Service == types.MicroCloud {
+			cloudToken = token.JoinToken
+			break
+		}
+	}
+
+	if cloudToken == "" {
+		return nil, fmt.Errorf("Join bundle is missing a %s join token", types.MicroCloud)
+	}
+
+	tokenFingerprint, err := joinTokenFingerprint(cloudToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if tokenFingerprint != fingerprint {
+		return nil, fmt.Errorf("Join bundle contains a %s join token of a different cluster", types.MicroCloud)
+	}
+
+	if now.After(bundle.Expires) {
+		return nil, fmt.Errorf("Join bundle expired at %s", bundle.Expires.Format(time.RFC3339))
+	}
+
+	return &bundle, nil
+}
+
+// signJoinBundle signs the given data using the key of the cluster certificate.
+func signJoinBundle(data []byte, signer crypto.Signer) ([]byte, error) {
+	_, isEd25519 := signer.Public().(ed25519.PublicKey)
+	if isEd25519 {
+		return signer.Sign(rand.Reader, data, crypto.Hash(0))
+	}
+
+	digest := sha256.Sum256(data)
+
+	return signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+// verifyJoinBundle verifies the signature of the given data using the public key of the cluster certificate.
+func verifyJoinBundle(data []byte, signature []byte, cert *x509.Certificate) error {
+	var algorithm x509.SignatureAlgorithm
+	switch cert.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		algorithm = x509.ECDSAWithSHA256
+	case *rsa.PublicKey:
+		algorithm = x509.SHA256WithRSA
+	case ed25519.PublicKey:
+		algorithm = x509.PureEd25519
+	default:
+		return fmt.Errorf("Unsupported cluster certificate key type %T", cert.PublicKey)
+	}
+
+	return cert.CheckSignature(algorithm, data, signature)
+}
+
+// joinTokenFingerprint returns the fingerprint of the cluster certificate contained in a MicroCloud join token.
+func joinTokenFingerprint(token string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return "", fmt.Errorf("Failed to decode %s join token: %w", types.MicroCloud, err)
+	}
+
+	decoded := struct {
+		Fingerprint string `json:"fingerprint"`
+	}{}
+
+	err = json.Unmarshal(data, &decoded)
+	if err != nil {
+		return "", fmt.Errorf("Failed to parse %s join token: %w", types.MicroCloud, err)
+	}
+
+	if decoded.Fingerprint == "" {
+		return "", fmt.Errorf("%s join token is missing the cluster certificate fingerprint", types.MicroCloud)
+	}
+
+	return decoded.Fingerprint, nil
+}
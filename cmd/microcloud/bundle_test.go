@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/canonical/microcloud/microcloud/api/types"
+)
+
+type bundleSuite struct {
+	suite.Suite
+}
+
+func TestBundleSuite(t *testing.T) {
+	suite.Run(t, new(bundleSuite))
+}
+
+// newClusterCert returns a self-signed certificate and its key to sign join bundles.
+func (s *bundleSuite) newClusterCert() (*ecdsa.PrivateKey, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	s.Require().NoError(err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "microcloud"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	s.Require().NoError(err)
+
+	cert, err := x509.ParseCertificate(der)
+	s.Require().NoError(err)
+
+	return key, cert
+}
+
+// newCloudToken returns a MicroCloud join token for the cluster of the given certificate.
+func (s *bundleSuite) newCloudToken(cert *x509.Certificate) string {
+	data, err := json.Marshal(map[string]string{"secret": "secret", "fingerprint": shared.CertFingerprint(cert)})
+	s.Require().NoError(err)
+
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func (s *bundleSuite) Test_parseJoinBundle() {
+	key, cert := s.newClusterCert()
+	otherKey, otherCert := s.newClusterCert()
+
+	now := time.Now()
+	newBundle := func(cert *x509.Certificate, tokenCert *x509.Certificate) joinBundle {
+		return joinBundle{
+			Version:            joinBundleVersion,
+			Name:               "micro02",
+			ClusterAddress:     "10.0.0.1",
+			ClusterCertificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+			Issued:             now,
+			Expires:            now.Add(time.Hour),
+			Config: types.ServicesPut{
+				Tokens: []types.ServiceToken{{Service: types.MicroCloud, JoinToken: s.newCloudToken(tokenCert)}},
+			},
+		}
+	}
+
+	cases := []struct {
+		desc      string
+		bundle    joinBundle
+		signer    *ecdsa.PrivateKey
+		now       time.Time
+		expectErr bool
+	}{
+		{
+			desc:   "Valid bundle",
+			bundle: newBundle(cert, cert),
+			signer: key,
+			now:    now,
+		},
+		{
+			desc:      "Signed by a different key",
+			bundle:    newBundle(cert, cert),
+			signer:    otherKey,
+			now:       now,
+			expectErr: true,
+		},
+		{
+			desc:      "Signed by a different cluster",
+			bundle:    newBundle(otherCert, cert),
+			signer:    otherKey,
+			now:       now,
+			expectErr: true,
+		},
+		{
+			desc:      "Consistent bundle of a different cluster",
+			bundle:    newBundle(otherCert, otherCert),
+			signer:    otherKey,
+			now:       now,
+			expectErr: true,
+		},
+		{
+			desc:      "Expired bundle",
+			bundle:    newBundle(cert, cert),
+			signer:    key,
+			now:       now.Add(2 * time.Hour),
+			expectErr: true,
+		},
+	}
+
+	for i, c := range cases {
+		s.T().Logf("%d: %s", i, c.desc)
+
+		buf := &bytes.Buffer{}
+		err := writeJoinBundle(buf, c.bundle, c.signer)
+		s.Require().NoError(err)
+
+		bundle, err := parseJoinBundle(buf, shared.CertFingerprint(cert), c.now)
+		if c.expectErr {
+			s.Error(err)
+			continue
+		}
+
+		s.NoError(err)
+		s.Equal(c.bundle.Name, bundle.Name)
+		s.Equal(c.bundle.Config, bundle.Config)
+	}
+}
+
+func (s *bundleSuite) Test_parseJoinBundleModified() {
+	key, cert := s.newClusterCert()
+
+	bundle := joinBundle{
+		Version:            joinBundleVersion,
+		Name:               "micro02",
+		ClusterCertificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+		Expires:            time.Now().Add(time.Hour),
+		Config: types.ServicesPut{
+			Tokens: []types.ServiceToken{{Service: types.MicroCloud, JoinToken: s.newCloudToken(cert)}},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	err := writeJoinBundle(buf, bundle, key)
+	s.Require().NoError(err)
+
+	// Change the name of the system allowed to join without updating the signature.
+	modified := bytes.Replace(buf.Bytes(), []byte(`"micro02"`), []byte(`"micro03"`), 1)
+	s.NotEqual(buf.Bytes(), modified)
+
+	_, err = parseJoinBundle(bytes.NewReader(modified), shared.CertFingerprint(cert), time.Now())
+	s.Error(err)
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	cli "github.com/canonical/lxd/shared/cmd"
+	"github.com/spf13/cobra"
+
+	"github.com/canonical/microcloud/microcloud/api/types"
+	"github.com/canonical/microcloud/microcloud/cmd/tui"
+	"github.com/canonical/microcloud/microcloud/multicast"
+	"github.com/canonical/microcloud/microcloud/service"
+)
+
+// DefaultDiscoverTimeout is the default time spent probing each interface.
+const DefaultDiscoverTimeout time.Duration = 5 * time.Second
+
+type cmdDiscover struct {
+	common *CmdControl
+
+	flagInterface      string
+	flagDiscovery      string
+	flagMulticastGroup string
+	flagMulticastPort  int64
+	flagRealm          string
+	flagTimeout        int
+	flagFormat         string
+}
+
+// discoveredSystem is a system which answered the probes of the discover command.
+type discoveredSystem struct {
+	Interface  string `json:"interface" yaml:"interface"`
+	Name       string `json:"name" yaml:"name"`
+	Address    string `json:"address" yaml:"address"`
+	Versions   string `json:"versions" yaml:"versions"`
+	Compatible bool   `json:"compatible" yaml:"compatible"`
+	RTT        string `json:"rtt" yaml:"rtt"`
+}
+
+// discoverResult is the result of probing the network with the discover command.
+type discoverResult struct {
+	Systems          []discoveredSystem `json:"systems" yaml:"systems"`
+	SilentInterfaces []string           `json:"silent_interfaces" yaml:"silent_interfaces"`
+}
+
+// command returns the subcommand for discovering systems on the network.
+func (c *cmdDiscover) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "discover",
+		Short: "Probe the network for systems running a MicroCloud session",
+		Long: `Probe the network for systems running a MicroCloud session
+
+Sends discovery probes on the given or on every network interface and lists each system that answers.
+Interfaces on which no system answered are reported too.`,
+		RunE: c.run,
+	}
+
+	cmd.Flags().StringVar(&c.flagInterface, "interface", "", "Network interface to probe (all interfaces by default)")
+	cmd.Flags().StringVar(&c.flagDiscovery, "discovery", string(types.DiscoveryMulticast), "Mechanism used to find other systems (multicast|mdns)")
+	cmd.Flags().StringVar(&c.flagMulticastGroup, "multicast-group", "", "Multicast group to probe (default group if empty)"+"``")
+	cmd.Flags().Int64Var(&c.flagMulticastPort, "multicast-port", service.CloudMulticastPort, "Multicast port to probe"+"``")
+	cmd.Flags().StringVar(&c.flagRealm, "realm", "", "Deployment realm of the systems to find"+"``")
+	cmd.Flags().IntVarP(&c.flagTimeout, "timeout", "t", int(DefaultDiscoverTimeout.Seconds()), "Number of seconds to probe each interface"+"``")
+	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", tui.TableFormatTable, "Format (csv|json|table|yaml|compact)")
+
+	return cmd
+}
+
+// run runs the subcommand for discovering systems on the network.
+func (c *cmdDiscover) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return cmd.Help()
+	}
+
+	mode, err := parseDiscoveryMode(c.flagDiscovery)
+	if err != nil {
+		return err
+	}
+
+	if mode == types.DiscoveryStatic {
+		return fmt.Errorf("Cannot probe the network using %q discovery", mode)
+	}
+
+	if c.flagTimeout <= 0 {
+		return errors.New("Timeout must be greater than zero")
+	}
+
+	config := multicast.GroupConfig{Port: c.flagMulticastPort}
+	if c.flagMulticastGroup != "" {
+		config.Group = net.ParseIP(c.flagMulticastGroup)
+		if config.Group == nil || !config.Group.IsMulticast() {
+			return fmt.Errorf("Invalid multicast group %q", c.flagMulticastGroup)
+		}
+	}
+
+	query := multicast.Query{Versions: multicast.SupportedVersions, Realm: c.flagRealm}
+
+	ifaces, err := c.interfaces()
+	if err != nil {
+		return err
+	}
+
+	timeout := time.Duration(c.flagTimeout) * time.Second
+	result := discoverResult{
+		Systems:          []discoveredSystem{},
+		SilentInterfaces: []string{},
+	}
+
+	systems := make([][]discoveredSystem, len(ifaces))
+	errs := make([]error, len(ifaces))
+
+	// Probe all interfaces at the same time so that the total duration doesn't depend on their number.
+	var wg sync.WaitGroup
+	for i, iface := range ifaces {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			systems[i], errs[i] = probeInterface(context.Background(), iface, mode, config, query, timeout)
+		}()
+	}
+
+	wg.Wait()
+
+	for i, iface := range ifaces {
+		if errs[i] != nil {
+			return fmt.Errorf("Failed to probe interface %q: %w", iface, errs[i])
+		}
+
+		if len(systems[i]) == 0 {
+			result.SilentInterfaces = append(result.SilentInterfaces, iface)
+			continue
+		}
+
+		result.Systems = append(result.Systems, systems[i]...)
+	}
+
+	data := make([][]string, 0, len(result.Systems))
+	for _, system := range result.Systems {
+		compatible := "NO"
+		if system.Compatible {
+			compatible = "YES"
+		}
+
+		data = append(data, []string{system.Interface, system.Name, system.Address, system.Versions, compatible, system.RTT})
+	}
+
+	header := []string{"INTERFACE", "NAME", "ADDRESS", "VERSIONS", "COMPATIBLE", "RTT"}
+	sort.Sort(cli.SortColumnsNaturally(data))
+
+	table, err := tui.FormatData(c.flagFormat, header, data, result)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(table)
+
+	// The silent interfaces are already part of the structured formats.
+	if c.flagFormat == tui.TableFormatTable || c.flagFormat == tui.TableFormatCompact {
+		for _, iface := range result.SilentInterfaces {
+			tui.PrintWarning(fmt.Sprintf("No system answered on interface %q", iface))
+		}
+	}
+
+	return nil
+}
+
+// interfaces returns the names of the interfaces to probe.
+// Only interfaces with a global unicast address are considered.
+func (c *cmdDiscover) interfaces() ([]string, error) {
+	networks, err := multicast.GetNetworkInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	ifaces := make([]string, 0, len(networks))
+	for _, network := range networks {
+		if !slices.Contains(ifaces, network.Interface.Name) {
+			ifaces = append(ifaces, network.Interface.Name)
+		}
+	}
+
+	if c.flagInterface != "" {
+		if !slices.Contains(ifaces, c.flagInterface) {
+			return nil, fmt.Errorf("Interface %q doesn't have any global unicast address", c.flagInterface)
+		}
+
+		return []string{c.flagInterface}, nil
+	}
+
+	if len(ifaces) == 0 {
+		return nil, errors.New("Found no interfaces with a global unicast address")
+	}
+
+	return ifaces, nil
+}
+
+// probeInterface probes the given interface for the given duration and returns every system that answered the query.
+// The group configuration is only used for multicast discovery.
+func probeInterface(ctx context.Context, iface string, mode types.DiscoveryMode, config multicast.GroupConfig, query multicast.Query, timeout time.Duration) ([]discoveredSystem, error) {
+	var backend multicast.Backend
+	switch mode {
+	case types.DiscoveryMDNS:
+		backend = multicast.NewMDNSBackend(iface)
+	default:
+		backend = multicast.NewGroupBackend(iface, config)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// The beacons are enough to diagnose the network as the full info can only be fetched using a session's passphrase.
+	events, err := multicast.NewDiscoveryWithBackend(backend, nil, nil).Probe(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	systems := []discoveredSystem{}
+	for event := range events {
+		if event.Err != nil {
+			return nil, event.Err
+		}
+
+		_, err := query.Versions.Negotiate(event.Beacon.Versions())
+
+		systems = append(systems, discoveredSystem{
+			Interface:  iface,
+			Name:       event.Beacon.Name,
+			Address:    event.Beacon.Address,
+			Versions:   event.Beacon.Versions().String(),
+			Compatible: err == nil,
+			RTT:        event.RTT.Round(time.Microsecond).String(),
+		})
+	}
+
+	return systems, nil
+}
@@ -2,15 +2,17 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/spf13/cobra"
 
-	"github.com/canonical/microcloud/microcloud/api"
 	"github.com/canonical/microcloud/microcloud/api/types"
 	cloudClient "github.com/canonical/microcloud/microcloud/client"
+	"github.com/canonical/microcloud/microcloud/cmd/tui"
 	"github.com/canonical/microcloud/microcloud/service"
 )
 
@@ -20,6 +22,10 @@ type cmdJoin struct {
 	flagLookupTimeout    int64
 	flagSessionTimeout   int64
 	flagInitiatorAddress string
+	flagDiscovery        string
+	flagURI              string
+	flagBundle           string
+	flagFingerprint      string
 }
 
 // command returns the subcommand for joining a MicroCloud.
@@ -33,6 +39,10 @@ func (c *cmdJoin) command() *cobra.Command {
 	cmd.Flags().Int64Var(&c.flagLookupTimeout, "lookup-timeout", 0, "Amount of seconds to wait when finding systems on the network. Defaults: 60s")
 	cmd.Flags().Int64Var(&c.flagSessionTimeout, "session-timeout", 0, "Amount of seconds to wait for the trust establishment session. Defaults: 10m")
 	cmd.Flags().StringVar(&c.flagInitiatorAddress, "initiator-address", "", "Address of the trust establishment session's initiator")
+	cmd.Flags().StringVar(&c.flagDiscovery, "discovery", string(types.DiscoveryMulticast), "Mechanism used to find the initiator (multicast|mdns|static)")
+	cmd.Flags().StringVar(&c.flagURI, "uri", "", "Join URI printed by the initiator containing its address, passphrase and fingerprint"+"``")
+	cmd.Flags().StringVar(&c.flagBundle, "bundle", "", "Offline join bundle written by \"microcloud add --bundle\""+"``")
+	cmd.Flags().StringVar(&c.flagFingerprint, "fingerprint", "", "Fingerprint of the cluster certificate printed by \"microcloud add --bundle\", required with --bundle"+"``")
 
 	return cmd
 }
@@ -43,14 +53,44 @@ func (c *cmdJoin) run(cmd *cobra.Command, args []string) error {
 		return cmd.Help()
 	}
 
+	discovery, err := parseDiscoveryMode(c.flagDiscovery)
+	if err != nil {
+		return err
+	}
+
+	if c.flagBundle != "" && (c.flagURI != "" || c.flagInitiatorAddress != "") {
+		return errors.New("Cannot join using an offline join bundle and a trust establishment session")
+	}
+
+	// The bundle is self-signed, so it can only be trusted if the fingerprint of its cluster certificate is known beforehand.
+	if (c.flagBundle == "") != (c.flagFingerprint == "") {
+		return errors.New("Offline join bundles require both --bundle and --fingerprint")
+	}
+
+	// The join URI contains the initiator's address so there isn't any lookup.
+	var uri *joinURI
+	if c.flagURI != "" {
+		if c.flagInitiatorAddress != "" {
+			return errors.New("Cannot provide both the initiator's address and a join URI")
+		}
+
+		uri, err = parseJoinURI(c.flagURI)
+		if err != nil {
+			return err
+		}
+
+		c.flagInitiatorAddress = uri.address
+	}
+
 	fmt.Println("Waiting for services to start ...")
-	err := checkInitialized(c.common.FlagMicroCloudDir, false, false)
+	err = checkInitialized(c.common.FlagMicroCloudDir, false, false)
 	if err != nil {
 		return err
 	}
 
 	cfg := initConfig{
 		bootstrap: false,
+		discovery: discovery,
 		common:    c.common,
 		asker:     c.common.asker,
 		systems:   map[string]InitSystem{},
@@ -73,20 +113,21 @@ func (c *cmdJoin) run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("Failed to retrieve system hostname: %w", err)
 	}
 
+	// Offline join bundles replace the trust establishment session.
+	if c.flagBundle != "" {
+		return c.joinBundle(&cfg)
+	}
+
 	err = cfg.askAddress(c.flagInitiatorAddress)
 	if err != nil {
 		return err
 	}
 
-	installedServices := []types.ServiceType{types.MicroCloud, types.LXD}
-	optionalServices := map[types.ServiceType]string{
-		types.MicroCeph: api.MicroCephDir,
-		types.MicroOVN:  api.MicroOVNDir,
-	}
+	installedServices := service.RequiredServices()
 
 	// Enable auto setup to skip service related questions.
 	cfg.autoSetup = true
-	installedServices, err = cfg.askMissingServices(installedServices, optionalServices)
+	installedServices, err = cfg.askMissingServices(installedServices)
 	if err != nil {
 		return err
 	}
@@ -108,12 +149,79 @@ func (c *cmdJoin) run(cmd *cobra.Command, args []string) error {
 		services[s.Type()] = version
 	}
 
-	passphrase, err := cfg.askPassphrase()
-	if err != nil {
-		return err
+	// Without a join URI, the passphrase is asked for once the initiator is found, so its wordlist can be used.
+	var passphrase string
+	if uri != nil {
+		passphrase = uri.passphrase
+		cfg.initiatorFingerprint = uri.fingerprint
 	}
 
 	return cfg.runSession(context.Background(), s, types.SessionJoining, cfg.sessionTimeout, func(gw *cloudClient.WebsocketGateway) error {
 		return cfg.joiningSession(gw, s, services, c.flagInitiatorAddress, passphrase)
 	})
 }
+
+// joinBundle joins the cluster using the configuration of the offline join bundle given by --bundle.
+// The bundle has to be issued by the cluster whose certificate matches the fingerprint given by --fingerprint.
+func (c *cmdJoin) joinBundle(cfg *initConfig) error {
+	fingerprint, err := parseFingerprint(c.flagFingerprint)
+	if err != nil {
+		return err
+	}
+
+	bundle, err := readJoinBundle(c.flagBundle, fingerprint, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if bundle.Name != cfg.name {
+		return fmt.Errorf("Join bundle was issued for system %q instead of %q", bundle.Name, cfg.name)
+	}
+
+	// Use the address chosen when issuing the bundle, if any. It has to be within one of the local subnets.
+	cfg.address = bundle.Config.Address
+
+	err = cfg.askAddress(bundle.ClusterAddress)
+	if err != nil {
+		return err
+	}
+
+	cfg.autoSetup = true
+	installedServices, err := cfg.askMissingServices(service.RequiredServices())
+	if err != nil {
+		return err
+	}
+
+	cfg.autoSetup = false
+
+	// Each service of the cluster has to be installed to join it.
+	for _, token := range bundle.Config.Tokens {
+		if !slices.Contains(installedServices, token.Service) {
+			return fmt.Errorf("Join bundle contains a token for %s which isn't installed", token.Service)
+		}
+	}
+
+	s, err := service.NewHandler(cfg.name, cfg.address, c.common.FlagMicroCloudDir, installedServices...)
+	if err != nil {
+		return err
+	}
+
+	bundle.Config.Address = cfg.address
+
+	fmt.Println("Awaiting cluster formation ...")
+
+	cloud := s.Services[types.MicroCloud].(*service.CloudService)
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	bar := tui.NewProgressBar(fmt.Sprintf("Joining %s", cfg.name))
+	err = cloud.RequestJoin(ctx, cfg.name, nil, bundle.Config, operationProgress(bar))
+	bar.Done()
+	if err != nil {
+		return fmt.Errorf("System %q failed to join the cluster: %w", cfg.name, err)
+	}
+
+	fmt.Println(tui.SummarizeResult("Peer %s has joined the cluster", cfg.name))
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// joinURIScheme is the scheme of the URI which contains the details required to join a trust establishment session.
+const joinURIScheme = "microcloud"
+
+// joinURI contains the details required to join the trust establishment session of an initiator.
+type joinURI struct {
+	// address is the address of the initiator.
+	address string
+
+	// passphrase is the passphrase of the initiator's session.
+	passphrase string
+
+	// fingerprint is the full SHA-256 fingerprint of the initiator's certificate.
+	fingerprint string
+}
+
+// String returns the URI in the form microcloud://<address>?fingerprint=<fingerprint>&passphrase=<passphrase>.
+func (u joinURI) String() string {
+	query := url.Values{}
+	query.Set("fingerprint", u.fingerprint)
+	query.Set("passphrase", u.passphrase)
+
+	// IPv6 addresses have to be enclosed in brackets.
+	host := u.address
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+
+	uri := url.URL{
+		Scheme:   joinURIScheme,
+		Host:     host,
+		RawQuery: query.Encode(),
+	}
+
+	return uri.String()
+}
+
+// parseJoinURI parses and validates the given join URI.
+func parseJoinURI(uri string) (*joinURI, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse join URI: %w", err)
+	}
+
+	if parsed.Scheme != joinURIScheme {
+		return nil, fmt.Errorf("Invalid join URI scheme %q (must be %q)", parsed.Scheme, joinURIScheme)
+	}
+
+	if parsed.Port() != "" || net.ParseIP(parsed.Hostname()) == nil {
+		return nil, fmt.Errorf("Invalid initiator address %q in join URI", parsed.Host)
+	}
+
+	query := parsed.Query()
+	passphrase := strings.Join(strings.Fields(query.Get("passphrase")), " ")
+	if passphrase == "" {
+		return nil, errors.New("Missing passphrase in join URI")
+	}
+
+	// The full fingerprint is required as the initiator's certificate is verified against it before sending the passphrase.
+	fingerprint, err := parseFingerprint(query.Get("fingerprint"))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid initiator fingerprint in join URI: %w", err)
+	}
+
+	return &joinURI{
+		address:     net.ParseIP(parsed.Hostname()).String(),
+		passphrase:  passphrase,
+		fingerprint: fingerprint,
+	}, nil
+}
+
+// parseFingerprint validates the given full SHA-256 certificate fingerprint and returns it in lower case.
+func parseFingerprint(fingerprint string) (string, error) {
+	normalized := strings.ToLower(fingerprint)
+	_, err := hex.DecodeString(normalized)
+	if err != nil || len(normalized) != sha256.Size*2 {
+		return "", fmt.Errorf("Fingerprint %q isn't a full SHA-256 fingerprint", fingerprint)
+	}
+
+	return normalized, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type joinURISuite struct {
+	suite.Suite
+}
+
+func TestJoinURISuite(t *testing.T) {
+	suite.Run(t, new(joinURISuite))
+}
+
+func (s *joinURISuite) Test_parseJoinURI() {
+	fingerprint := strings.Repeat("0123456789abcdef", 4)
+
+	cases := []struct {
+		desc      string
+		uri       string
+		expectURI *joinURI
+		expectErr bool
+	}{
+		{
+			desc:      "Valid IPv4 URI",
+			uri:       joinURI{address: "10.0.0.1", passphrase: "a b c d", fingerprint: fingerprint}.String(),
+			expectURI: &joinURI{address: "10.0.0.1", passphrase: "a b c d", fingerprint: fingerprint},
+		},
+		{
+			desc:      "Valid IPv6 URI",
+			uri:       joinURI{address: "fd42::1", passphrase: "a b c d", fingerprint: fingerprint}.String(),
+			expectURI: &joinURI{address: "fd42::1", passphrase: "a b c d", fingerprint: fingerprint},
+		},
+		{
+			desc:      "Invalid scheme",
+			uri:       "https://10.0.0.1?fingerprint=0123456789ab&passphrase=a+b+c+d",
+			expectErr: true,
+		},
+		{
+			desc:      "Invalid address",
+			uri:       "microcloud://system01?fingerprint=0123456789ab&passphrase=a+b+c+d",
+			expectErr: true,
+		},
+		{
+			desc:      "Missing passphrase",
+			uri:       "microcloud://10.0.0.1?fingerprint=0123456789ab",
+			expectErr: true,
+		},
+		{
+			desc:      "Short fingerprint",
+			uri:       "microcloud://10.0.0.1?fingerprint=0123456789ab&passphrase=a+b+c+d",
+			expectErr: true,
+		},
+		{
+			desc:      "Invalid fingerprint",
+			uri:       "microcloud://10.0.0.1?fingerprint=" + strings.Repeat("x", 64) + "&passphrase=a+b+c+d",
+			expectErr: true,
+		},
+	}
+
+	for i, c := range cases {
+		s.T().Logf("%d: %s", i, c.desc)
+
+		uri, err := parseJoinURI(c.uri)
+		if c.expectErr {
+			s.Error(err)
+			continue
+		}
+
+		s.NoError(err)
+		s.Equal(c.expectURI, uri)
+	}
+}
@@ -91,6 +91,12 @@ func main() {
 	var cmdStatus = cmdStatus{common: &commonCmd}
 	app.AddCommand(cmdStatus.command())
 
+	var cmdDiscover = cmdDiscover{common: &commonCmd}
+	app.AddCommand(cmdDiscover.command())
+
+	var cmdSession = cmdSession{common: &commonCmd}
+	app.AddCommand(cmdSession.command())
+
 	var cmdPeers = cmdClusterMembers{common: &commonCmd}
 	app.AddCommand(cmdPeers.command())
 
@@ -3,9 +3,11 @@ package main
 import (
 	"context"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"net"
 	"os"
+	"os/signal"
 	"slices"
 	"strings"
 	"sync"
@@ -20,7 +22,6 @@ import (
 	cephTypes "github.com/canonical/microceph/microceph/api/types"
 	"github.com/spf13/cobra"
 
-	"github.com/canonical/microcloud/microcloud/api"
 	"github.com/canonical/microcloud/microcloud/api/types"
 	cloudClient "github.com/canonical/microcloud/microcloud/client"
 	"github.com/canonical/microcloud/microcloud/cmd/tui"
@@ -110,6 +111,46 @@ type initConfig struct {
 	// lookupIface is the interface used for multicast lookup.
 	lookupIface *net.Interface
 
+	// discovery is the mechanism used to find other systems during the trust establishment session.
+	discovery types.DiscoveryMode
+
+	// seedAddresses are the addresses of systems probed when using static discovery.
+	seedAddresses []string
+
+	// initiatorFingerprint is the full fingerprint of the initiator's certificate given in the join URI.
+	// If set, the joiner verifies the initiator's certificate before sending its intent to join.
+	initiatorFingerprint string
+
+	// allowedFingerprints are the certificate fingerprints of the systems which are confirmed automatically.
+	// If set, any other system is rejected.
+	allowedFingerprints []string
+
+	// passphraseWords is the number of words in the passphrase generated by the initiator.
+	// If zero, the default number of words is used.
+	passphraseWords uint8
+
+	// wordlist is the name of the wordlist used for the passphrase. It selects the built-in wordlist if empty.
+	wordlist string
+
+	// resume resumes the trust establishment session interrupted by a restart of the daemon.
+	resume bool
+
+	// discardInterrupted discards the trust establishment session interrupted by a restart of the daemon.
+	// Without it, a new session cannot be started while the interrupted one is still active.
+	discardInterrupted bool
+
+	// discoveryInterfaces are additional interfaces on which the initiator responds to discovery.
+	discoveryInterfaces []string
+
+	// multicastGroup, multicastPort and multicastTTL override the daemon's multicast group configuration.
+	// They have to match on the initiator and the joiners.
+	multicastGroup string
+	multicastPort  int64
+	multicastTTL   int
+
+	// realm overrides the daemon's deployment realm which separates deployments sharing the same network.
+	realm string
+
 	// lookupSubnet is the subnet in which other peers are being expected.
 	// It represents the internal network used for MicroCloud.
 	lookupSubnet *net.IPNet
@@ -124,7 +165,16 @@ type initConfig struct {
 type cmdInit struct {
 	common *CmdControl
 
-	flagSessionTimeout int64
+	flagSessionTimeout      int64
+	flagDiscovery           string
+	flagSeedAddresses       []string
+	flagDiscoveryInterfaces []string
+	flagAllowFingerprints   []string
+	flagAllowCertificates   string
+	flagPassphraseWords     uint8
+	flagWordlist            string
+	flagResume              bool
+	flagDiscardInterrupted  bool
 }
 
 // command returns the subcommand for initializing a MicroCloud.
@@ -137,6 +187,15 @@ func (c *cmdInit) command() *cobra.Command {
 	}
 
 	cmd.Flags().Int64Var(&c.flagSessionTimeout, "session-timeout", 0, "Amount of seconds to wait for the trust establishment session. Defaults: 60m")
+	cmd.Flags().StringVar(&c.flagDiscovery, "discovery", string(types.DiscoveryMulticast), "Mechanism used to find other systems (multicast|mdns|static)")
+	cmd.Flags().StringSliceVar(&c.flagSeedAddresses, "seed-address", nil, "Address of a system to probe using static discovery (implies --discovery=static)")
+	cmd.Flags().StringSliceVar(&c.flagDiscoveryInterfaces, "discovery-interface", nil, "Additional interface on which other systems can find this system (\"all\" for every interface)")
+	cmd.Flags().StringSliceVar(&c.flagAllowFingerprints, "allow-fingerprint", nil, "Certificate fingerprint of a system to confirm automatically (rejects all other systems)")
+	cmd.Flags().StringVar(&c.flagAllowCertificates, "allow-certificates", "", "Directory of PEM certificates of the systems to confirm automatically (rejects all other systems)"+"``")
+	cmd.Flags().Uint8Var(&c.flagPassphraseWords, "passphrase-words", service.PassphraseWordCount, "Number of words in the session passphrase (4 to 8)"+"``")
+	cmd.Flags().StringVar(&c.flagWordlist, "wordlist", service.DefaultWordlist, "Name of the wordlist used for the session passphrase"+"``")
+	cmd.Flags().BoolVar(&c.flagResume, "resume", false, "Resume the trust establishment session interrupted by a restart using its passphrase")
+	cmd.Flags().BoolVar(&c.flagDiscardInterrupted, "discard-interrupted", false, "Discard the trust establishment session interrupted by a restart and start a new one")
 
 	return cmd
 }
@@ -147,6 +206,10 @@ func (c *cmdInit) run(cmd *cobra.Command, args []string) error {
 		return cmd.Help()
 	}
 
+	if c.flagResume && c.flagDiscardInterrupted {
+		return errors.New("The interrupted session cannot be both resumed and discarded")
+	}
+
 	cfg := initConfig{
 		bootstrap: true,
 		setupMany: true,
@@ -156,11 +219,36 @@ func (c *cmdInit) run(cmd *cobra.Command, args []string) error {
 		state:     map[string]service.SystemInformation{},
 	}
 
+	cfg.resume = c.flagResume
+	cfg.discardInterrupted = c.flagDiscardInterrupted
 	cfg.sessionTimeout = DefaultSessionTimeout
 	if c.flagSessionTimeout > 0 {
 		cfg.sessionTimeout = time.Duration(c.flagSessionTimeout) * time.Second
 	}
 
+	var err error
+	cfg.discovery, err = parseDiscoveryMode(c.flagDiscovery)
+	if err != nil {
+		return err
+	}
+
+	err = cfg.setSeedAddresses(c.flagSeedAddresses, cmd.Flags().Changed("discovery"))
+	if err != nil {
+		return err
+	}
+
+	cfg.discoveryInterfaces = c.flagDiscoveryInterfaces
+
+	err = cfg.setAllowedFingerprints(c.flagAllowFingerprints, c.flagAllowCertificates)
+	if err != nil {
+		return err
+	}
+
+	err = cfg.setPassphraseStrength(c.flagPassphraseWords, c.flagWordlist)
+	if err != nil {
+		return err
+	}
+
 	return cfg.runInteractive(cmd, args)
 }
 
@@ -194,13 +282,9 @@ func (c *initConfig) runInteractive(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	installedServices := []types.ServiceType{types.MicroCloud, types.LXD}
-	optionalServices := map[types.ServiceType]string{
-		types.MicroCeph: api.MicroCephDir,
-		types.MicroOVN:  api.MicroOVNDir,
-	}
+	installedServices := service.RequiredServices()
 
-	installedServices, err = c.askMissingServices(installedServices, optionalServices)
+	installedServices, err = c.askMissingServices(installedServices)
 	if err != nil {
 		return err
 	}
@@ -382,7 +466,12 @@ microCloudPeerIfaceFound:
 // If the request was successful, it additionally waits until the cluster appears in the database.
 func waitForJoin(sh *service.Handler, clusterSizes map[types.ServiceType]int, peer string, cert *x509.Certificate, cfg types.ServicesPut) error {
 	cloud := sh.Services[types.MicroCloud].(*service.CloudService)
-	err := cloud.RequestJoin(context.Background(), peer, cert, cfg)
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	bar := tui.NewProgressBar(fmt.Sprintf("Joining %s", peer))
+	err := cloud.RequestJoin(ctx, peer, cert, cfg, operationProgress(bar))
+	bar.Done()
 	if err != nil {
 		return fmt.Errorf("System %q failed to join the cluster: %w", peer, err)
 	}
@@ -419,6 +508,45 @@ func waitForJoin(sh *service.Handler, clusterSizes map[types.ServiceType]int, pe
 	return nil
 }
 
+// interruptContext returns a context which gets cancelled with cloudClient.ErrInterrupted once the user interrupts
+// the command, so that the operations watched using the context get cancelled too.
+// A second interrupt terminates the command right away.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		defer signal.Stop(interrupts)
+
+		select {
+		case <-interrupts:
+			cancel(cloudClient.ErrInterrupted)
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() { cancel(context.Canceled) }
+}
+
+// operationProgress returns a function which renders the progress of an operation on the given progress bar.
+// The services which the operation is currently working on are shown next to the bar.
+func operationProgress(bar *tui.ProgressBar) func(op types.Operation) {
+	return func(op types.Operation) {
+		running := make([]string, 0, len(op.Services))
+		for serviceType, stage := range op.Services {
+			if stage == types.OperationStageRunning {
+				running = append(running, string(serviceType))
+			}
+		}
+
+		slices.Sort(running)
+
+		done, total := op.Progress()
+		bar.Update(done, total, strings.Join(running, ", "))
+	}
+}
+
 func (c *initConfig) addPeers(sh *service.Handler) (revert.Hook, error) {
 	reverter := revert.New()
 	defer reverter.Fail()
@@ -482,7 +610,7 @@ func (c *initConfig) addPeers(sh *service.Handler) (revert.Hook, error) {
 	// Concurrently issue a token for each joiner.
 	for peer := range c.systems {
 		mut := sync.Mutex{}
-		err := sh.RunConcurrent("", "", func(s service.Service) error {
+		err := sh.RunConcurrent(service.RunParallel, func(s service.Service) error {
 			// Skip MicroCloud as the cluster is already formed.
 			if s.Type() == types.MicroCloud {
 				return nil
@@ -733,7 +861,7 @@ func (c *initConfig) setupCluster(s *service.Handler) error {
 
 	fmt.Println("Initializing new services ...")
 	mu := sync.Mutex{}
-	err = s.RunConcurrent(types.MicroCloud, types.LXD, func(s service.Service) error {
+	err = s.RunConcurrent(service.RunDependenciesFirst, func(s service.Service) error {
 		// If there's already an initialized system for this service, we don't need to bootstrap it.
 		if initializedServices[s.Type()] != "" {
 			return nil
@@ -31,16 +31,25 @@ import (
 
 // Preseed represents the structure of the supported preseed yaml.
 type Preseed struct {
-	LookupSubnet      string        `yaml:"lookup_subnet"`
-	LookupTimeout     int64         `yaml:"lookup_timeout"`
-	SessionPassphrase string        `yaml:"session_passphrase"`
-	SessionTimeout    int64         `yaml:"session_timeout"`
-	Initiator         string        `yaml:"initiator"`
-	InitiatorAddress  string        `yaml:"initiator_address"`
-	Systems           []System      `yaml:"systems"`
-	OVN               InitNetwork   `yaml:"ovn"`
-	Ceph              CephOptions   `yaml:"ceph"`
-	Storage           StorageFilter `yaml:"storage"`
+	LookupSubnet        string        `yaml:"lookup_subnet"`
+	LookupTimeout       int64         `yaml:"lookup_timeout"`
+	SessionPassphrase   string        `yaml:"session_passphrase"`
+	SessionTimeout      int64         `yaml:"session_timeout"`
+	Initiator           string        `yaml:"initiator"`
+	InitiatorAddress    string        `yaml:"initiator_address"`
+	Discovery           string        `yaml:"discovery"`
+	SeedAddresses       []string      `yaml:"seed_addresses"`
+	DiscoveryIfaces     []string      `yaml:"discovery_interfaces"`
+	AllowedFingerprints []string      `yaml:"allowed_fingerprints"`
+	AllowedCertificates string        `yaml:"allowed_certificates"`
+	MulticastGroup      string        `yaml:"multicast_group"`
+	MulticastPort       int64         `yaml:"multicast_port"`
+	MulticastTTL        int           `yaml:"multicast_ttl"`
+	Realm               string        `yaml:"realm"`
+	Systems             []System      `yaml:"systems"`
+	OVN                 InitNetwork   `yaml:"ovn"`
+	Ceph                CephOptions   `yaml:"ceph"`
+	Storage             StorageFilter `yaml:"storage"`
 }
 
 // System represents the structure of the systems we expect to find in the preseed yaml.
@@ -221,14 +230,32 @@ func (c *initConfig) RunPreseed(cmd *cobra.Command) error {
 		c.sessionTimeout = time.Duration(config.SessionTimeout) * time.Second
 	}
 
-	// Build the service handler.
-	installedServices := []types.ServiceType{types.MicroCloud, types.LXD}
-	optionalServices := map[types.ServiceType]string{
-		types.MicroCeph: api.MicroCephDir,
-		types.MicroOVN:  api.MicroOVNDir,
+	c.discovery = types.DiscoveryMulticast
+	if config.Discovery != "" {
+		c.discovery = types.DiscoveryMode(config.Discovery)
 	}
 
-	installedServices, err = c.askMissingServices(installedServices, optionalServices)
+	err = c.setSeedAddresses(config.SeedAddresses, config.Discovery != "")
+	if err != nil {
+		return err
+	}
+
+	c.discoveryInterfaces = config.DiscoveryIfaces
+
+	c.multicastGroup = config.MulticastGroup
+	c.multicastPort = config.MulticastPort
+	c.multicastTTL = config.MulticastTTL
+	c.realm = config.Realm
+
+	err = c.setAllowedFingerprints(config.AllowedFingerprints, config.AllowedCertificates)
+	if err != nil {
+		return err
+	}
+
+	// Build the service handler.
+	installedServices := service.RequiredServices()
+
+	installedServices, err = c.askMissingServices(installedServices)
 	if err != nil {
 		return err
 	}
@@ -358,6 +385,46 @@ func (p *Preseed) validate(name string, bootstrap bool) error {
 		return errors.New("Missing session passphrase")
 	}
 
+	if p.Discovery != "" {
+		mode, err := parseDiscoveryMode(p.Discovery)
+		if err != nil {
+			return err
+		}
+
+		if mode == types.DiscoveryStatic && p.InitiatorAddress == "" && len(p.SeedAddresses) == 0 {
+			return fmt.Errorf("Missing the initiator's address or seed addresses when using %q discovery", mode)
+		}
+
+		if mode != types.DiscoveryStatic && len(p.SeedAddresses) > 0 {
+			return fmt.Errorf("Seed addresses can only be used with %q discovery", types.DiscoveryStatic)
+		}
+
+		if mode == types.DiscoveryStatic && len(p.DiscoveryIfaces) > 0 {
+			return fmt.Errorf("Discovery interfaces cannot be used with %q discovery", types.DiscoveryStatic)
+		}
+	}
+
+	for _, address := range p.SeedAddresses {
+		if net.ParseIP(address) == nil {
+			return fmt.Errorf("Invalid seed address %q", address)
+		}
+	}
+
+	if p.MulticastGroup != "" {
+		group := net.ParseIP(p.MulticastGroup)
+		if group == nil || !group.IsMulticast() {
+			return fmt.Errorf("Invalid multicast group %q", p.MulticastGroup)
+		}
+	}
+
+	if p.MulticastPort < 0 || p.MulticastPort > 65535 {
+		return fmt.Errorf("Invalid multicast port %d", p.MulticastPort)
+	}
+
+	if p.MulticastTTL < 0 || p.MulticastTTL > 255 {
+		return fmt.Errorf("Invalid multicast TTL %d", p.MulticastTTL)
+	}
+
 	systemNames := make([]string, 0, len(p.Systems))
 	for _, system := range p.Systems {
 		if system.Name == "" {
@@ -280,6 +280,55 @@ func (s *preseedSuite) Test_preseedValidateInvalid() {
 			addErr: true,
 			err:    errors.New("Cannot specify IPv4 range without IPv4 gateway"),
 		},
+		{
+			desc: "Invalid discovery mode",
+			preseed: Preseed{
+				SessionPassphrase: "foo",
+				Initiator:         "n1",
+				LookupSubnet:      "10.0.1.0/24",
+				Discovery:         "invalid",
+				Systems:           []System{{Name: "n1"}, {Name: "n2"}},
+			},
+			addErr: true,
+			err:    errors.New(`Invalid discovery mode "invalid" (must be one of multicast, mdns or static)`),
+		},
+		{
+			desc: "Static discovery without initiator address",
+			preseed: Preseed{
+				SessionPassphrase: "foo",
+				Initiator:         "n1",
+				LookupSubnet:      "10.0.1.0/24",
+				Discovery:         "static",
+				Systems:           []System{{Name: "n1"}, {Name: "n2"}},
+			},
+			addErr: true,
+			err:    errors.New(`Missing the initiator's address or seed addresses when using "static" discovery`),
+		},
+		{
+			desc: "Seed addresses with multicast discovery",
+			preseed: Preseed{
+				SessionPassphrase: "foo",
+				Initiator:         "n1",
+				LookupSubnet:      "10.0.1.0/24",
+				Discovery:         "multicast",
+				SeedAddresses:     []string{"10.0.1.2"},
+				Systems:           []System{{Name: "n1"}, {Name: "n2"}},
+			},
+			addErr: true,
+			err:    errors.New(`Seed addresses can only be used with "static" discovery`),
+		},
+		{
+			desc: "Invalid multicast group",
+			preseed: Preseed{
+				SessionPassphrase: "foo",
+				Initiator:         "n1",
+				LookupSubnet:      "10.0.1.0/24",
+				MulticastGroup:    "10.0.1.2",
+				Systems:           []System{{Name: "n1"}, {Name: "n2"}},
+			},
+			addErr: true,
+			err:    errors.New(`Invalid multicast group "10.0.1.2"`),
+		},
 		{
 			desc: "Invalid OVN IPv4 Ranges",
 			preseed: Preseed{
@@ -8,6 +8,7 @@ import (
 	"github.com/spf13/cobra"
 
 	cloudClient "github.com/canonical/microcloud/microcloud/client"
+	"github.com/canonical/microcloud/microcloud/cmd/tui"
 )
 
 type cmdRemove struct {
@@ -52,5 +53,15 @@ func (c *cmdRemove) run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	return cloudClient.DeleteClusterMember(context.Background(), client, args[0], c.flagForce)
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	bar := tui.NewProgressBar(fmt.Sprintf("Removing %s", args[0]))
+	err = cloudClient.DeleteClusterMember(ctx, client, args[0], c.flagForce, operationProgress(bar))
+	bar.Done()
+	if err != nil {
+		return err
+	}
+
+	return nil
 }
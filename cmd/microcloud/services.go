@@ -15,7 +15,6 @@ import (
 	"github.com/canonical/microcluster/v3/microcluster"
 	"github.com/spf13/cobra"
 
-	"github.com/canonical/microcloud/microcloud/api"
 	"github.com/canonical/microcloud/microcloud/api/types"
 	"github.com/canonical/microcloud/microcloud/cmd/tui"
 	"github.com/canonical/microcloud/microcloud/multicast"
@@ -85,11 +84,7 @@ func (c *cmdServiceList) run(cmd *cobra.Command, args []string) error {
 		return errors.New("MicroCloud is uninitialized, run 'microcloud init' first")
 	}
 
-	services := []types.ServiceType{types.MicroCloud, types.LXD}
-	optionalServices := map[types.ServiceType]string{
-		types.MicroCeph: api.MicroCephDir,
-		types.MicroOVN:  api.MicroOVNDir,
-	}
+	services := service.RequiredServices()
 
 	cfg := initConfig{
 		autoSetup: true,
@@ -103,7 +98,7 @@ func (c *cmdServiceList) run(cmd *cobra.Command, args []string) error {
 	cfg.name = status.Name
 	cfg.address = status.Address.Addr().String()
 
-	services, err = cfg.askMissingServices(services, optionalServices)
+	services, err = cfg.askMissingServices(services)
 	if err != nil {
 		return err
 	}
@@ -117,7 +112,7 @@ func (c *cmdServiceList) run(cmd *cobra.Command, args []string) error {
 	mu := sync.Mutex{}
 	header := []string{"NAME", "ADDRESS", "ROLE", "STATUS"}
 	allClusters := map[types.ServiceType][][]string{}
-	err = s.RunConcurrent("", "", func(s service.Service) error {
+	err = s.RunConcurrent(service.RunParallel, func(s service.Service) error {
 		var err error
 		var data [][]string
 		var m *microcluster.MicroCluster
@@ -129,12 +124,11 @@ func (c *cmdServiceList) run(cmd *cobra.Command, args []string) error {
 				return err
 			}
 
-		case types.MicroCeph:
-			m = s.(*service.CephService).Microcluster()
-		case types.MicroOVN:
-			m = s.(*service.OVNService).Microcluster()
-		case types.MicroCloud:
-			m = s.(*service.CloudService).Microcluster()
+		default:
+			microclusterService, ok := s.(service.MicroclusterService)
+			if ok {
+				m = microclusterService.Microcluster()
+			}
 		}
 
 		if m != nil {
@@ -253,14 +247,10 @@ func (c *cmdServiceAdd) run(cmd *cobra.Command, args []string) error {
 	}
 
 	cfg.autoSetup = false
-	installedServices := []types.ServiceType{types.MicroCloud, types.LXD}
-	optionalServices := map[types.ServiceType]string{
-		types.MicroCeph: api.MicroCephDir,
-		types.MicroOVN:  api.MicroOVNDir,
-	}
+	installedServices := service.RequiredServices()
 
 	// Set the auto flag to true so that we automatically omit any services that aren't installed.
-	installedServices, err = cfg.askMissingServices(installedServices, optionalServices)
+	installedServices, err = cfg.askMissingServices(installedServices)
 	if err != nil {
 		return err
 	}
@@ -2,13 +2,23 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/termios"
+	"github.com/canonical/microcluster/v3/microcluster"
+	"golang.org/x/sys/unix"
 
 	"github.com/canonical/microcloud/microcloud/api/types"
 	cloudClient "github.com/canonical/microcloud/microcloud/client"
@@ -17,6 +27,120 @@ import (
 	"github.com/canonical/microcloud/microcloud/service"
 )
 
+// parseDiscoveryMode validates the given discovery mode.
+func parseDiscoveryMode(mode string) (types.DiscoveryMode, error) {
+	if !slices.Contains(types.DiscoveryModes, types.DiscoveryMode(mode)) {
+		return "", fmt.Errorf("Invalid discovery mode %q (must be one of multicast, mdns or static)", mode)
+	}
+
+	return types.DiscoveryMode(mode), nil
+}
+
+// setSeedAddresses validates the given seed addresses and configures them to be probed using static discovery.
+// Seed addresses imply static discovery unless another discovery mode was explicitly requested.
+func (c *initConfig) setSeedAddresses(addresses []string, discoveryRequested bool) error {
+	if len(addresses) == 0 {
+		return nil
+	}
+
+	if discoveryRequested && c.discovery != types.DiscoveryStatic {
+		return fmt.Errorf("Seed addresses can only be used with %q discovery", types.DiscoveryStatic)
+	}
+
+	for _, address := range addresses {
+		if net.ParseIP(address) == nil {
+			return fmt.Errorf("Invalid seed address %q", address)
+		}
+	}
+
+	c.discovery = types.DiscoveryStatic
+	c.seedAddresses = addresses
+
+	return nil
+}
+
+// setAllowedFingerprints validates the given certificate fingerprints and the certificates in the given directory
+// and configures them to be the only systems which are confirmed automatically during the trust establishment session.
+// Fingerprints can be given with or without colons.
+func (c *initConfig) setAllowedFingerprints(fingerprints []string, certificatesDir string) error {
+	allowed := make([]string, 0, len(fingerprints))
+	for _, fingerprint := range fingerprints {
+		fingerprint = strings.ToLower(strings.ReplaceAll(fingerprint, ":", ""))
+
+		_, err := hex.DecodeString(fingerprint)
+		if err != nil || len(fingerprint) != sha256.Size*2 {
+			return fmt.Errorf("Invalid certificate fingerprint %q", fingerprint)
+		}
+
+		allowed = append(allowed, fingerprint)
+	}
+
+	if certificatesDir != "" {
+		entries, err := os.ReadDir(certificatesDir)
+		if err != nil {
+			return fmt.Errorf("Failed to read certificates directory: %w", err)
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || !slices.Contains([]string{".crt", ".pem"}, filepath.Ext(entry.Name())) {
+				continue
+			}
+
+			content, err := os.ReadFile(filepath.Join(certificatesDir, entry.Name()))
+			if err != nil {
+				return fmt.Errorf("Failed to read certificate %q: %w", entry.Name(), err)
+			}
+
+			fingerprint, err := shared.CertFingerprintStr(string(content))
+			if err != nil {
+				return fmt.Errorf("Failed to get fingerprint of certificate %q: %w", entry.Name(), err)
+			}
+
+			allowed = append(allowed, fingerprint)
+		}
+
+		if len(allowed) == len(fingerprints) {
+			return fmt.Errorf("No certificates found in %q", certificatesDir)
+		}
+	}
+
+	slices.Sort(allowed)
+	c.allowedFingerprints = slices.Compact(allowed)
+
+	return nil
+}
+
+// setPassphraseStrength validates the number of words and the wordlist of the passphrase generated by the initiator.
+func (c *initConfig) setPassphraseStrength(words uint8, wordlist string) error {
+	err := service.ValidatePassphraseWordCount(words)
+	if err != nil {
+		return err
+	}
+
+	c.passphraseWords = words
+	c.wordlist = wordlist
+
+	// Check the wordlist before starting the session as it's loaded from the same state directory by the daemon.
+	_, err = c.loadWordlist(wordlist)
+
+	return err
+}
+
+// loadWordlist returns the words of the wordlist with the given name.
+// Wordlists other than the built-in one are read from MicroCloud's state directory.
+func (c *initConfig) loadWordlist(name string) ([]string, error) {
+	if name == "" || name == service.DefaultWordlist {
+		return service.Wordlist, nil
+	}
+
+	app, err := microcluster.App(microcluster.Args{StateDir: c.common.FlagMicroCloudDir})
+	if err != nil {
+		return nil, err
+	}
+
+	return service.LoadWordlist(app.FileSystem.StateDir(), name)
+}
+
 // SessionFunc represents a function executed throughout the lifetime of a session.
 type SessionFunc func(gw *cloudClient.WebsocketGateway) error
 
@@ -33,11 +157,32 @@ func (c *initConfig) runSession(ctx context.Context, s *service.Handler, role ty
 }
 
 func (c *initConfig) initiatingSession(gw *cloudClient.WebsocketGateway, sh *service.Handler, services map[types.ServiceType]string, passphrase string, expectedSystems []string) error {
+	// Resuming the interrupted session requires its passphrase as the daemon only stored its hash.
+	if c.resume && passphrase == "" {
+		var err error
+		passphrase, err = c.askPassphrase("Specify the passphrase of the interrupted session", c.wordlist, 0)
+		if err != nil {
+			return err
+		}
+	}
+
 	session := types.Session{
-		Address:    c.address,
-		Interface:  c.lookupIface.Name,
-		Services:   services,
-		Passphrase: passphrase,
+		Address:             c.address,
+		Interface:           c.lookupIface.Name,
+		Discovery:           c.discovery,
+		SeedAddresses:       c.seedAddresses,
+		AllowedFingerprints: c.allowedFingerprints,
+		DiscoveryInterfaces: c.discoveryInterfaces,
+		MulticastGroup:      c.multicastGroup,
+		MulticastPort:       c.multicastPort,
+		MulticastTTL:        c.multicastTTL,
+		Realm:               c.realm,
+		Services:            services,
+		Passphrase:          passphrase,
+		PassphraseWords:     c.passphraseWords,
+		Wordlist:            c.wordlist,
+		Resume:              c.resume,
+		DiscardInterrupted:  c.discardInterrupted,
 	}
 
 	err := gw.Write(session)
@@ -93,6 +238,27 @@ Verify the fingerprint %s is displayed on joining systems.
 		passArg := tui.Fmt{Arg: session.Passphrase, Color: tui.Green, Bold: true}
 		fingerprintArg := tui.Fmt{Arg: fingerprint, Color: tui.Green, Bold: true}
 		fmt.Print(tui.Printf(tui.Fmt{Arg: template}, cmdArg, passArg, fingerprintArg))
+
+		// The join URI allows joining without typing the passphrase and skips the lookup of the initiator.
+		uri := joinURI{
+			address:     c.address,
+			passphrase:  session.Passphrase,
+			fingerprint: cert.Fingerprint(),
+		}
+
+		// Quote the URI as its query contains characters interpreted by the shell.
+		uriArg := tui.Fmt{Arg: "microcloud join --uri '" + uri.String() + "'", Color: tui.Green, Bold: true}
+		fmt.Print(tui.Printf(tui.Fmt{Arg: "\nAlternatively, use the following command which contains all of the above:\n\n %s\n"}, uriArg))
+
+		// The QR code is only a convenience, so don't fail if it cannot be rendered.
+		if termios.IsTerminal(unix.Stdout) {
+			qrCode, err := tui.QRCode(uri.String())
+			if err != nil {
+				tui.PrintWarning(fmt.Sprintf("Failed to render join URI as QR code: %v", err))
+			} else {
+				fmt.Printf("\n%s\n\n", qrCode)
+			}
+		}
 	}
 
 	confirmedIntents, err := c.askJoinIntents(gw, expectedSystems)
@@ -100,7 +266,7 @@ Verify the fingerprint %s is displayed on joining systems.
 		return err
 	}
 
-	if c.autoSetup {
+	if c.autoSetup || len(c.allowedFingerprints) > 0 {
 		for _, info := range confirmedIntents {
 			if info.Name == c.name {
 				continue
@@ -167,12 +333,18 @@ Verify the fingerprint %s is displayed on joining systems.
 
 func (c *initConfig) joiningSession(gw *cloudClient.WebsocketGateway, sh *service.Handler, services map[types.ServiceType]string, initiatorAddress string, passphrase string) error {
 	session := types.Session{
-		Passphrase:       passphrase,
-		Address:          sh.Address(),
-		InitiatorAddress: initiatorAddress,
-		Interface:        c.lookupIface.Name,
-		Services:         services,
-		LookupTimeout:    c.lookupTimeout,
+		Passphrase:           passphrase,
+		Address:              sh.Address(),
+		InitiatorAddress:     initiatorAddress,
+		InitiatorFingerprint: c.initiatorFingerprint,
+		Interface:            c.lookupIface.Name,
+		Discovery:            c.discovery,
+		MulticastGroup:       c.multicastGroup,
+		MulticastPort:        c.multicastPort,
+		MulticastTTL:         c.multicastTTL,
+		Realm:                c.realm,
+		Services:             services,
+		LookupTimeout:        c.lookupTimeout,
 	}
 
 	err := gw.Write(session)
@@ -184,10 +356,28 @@ func (c *initConfig) joiningSession(gw *cloudClient.WebsocketGateway, sh *servic
 		fmt.Println("Searching for an eligible system ...")
 	}
 
+	// Without a passphrase, the daemon first finds the initiator and asks for the passphrase using its passphrase settings.
+	if passphrase == "" {
+		err = c.sendPassphrase(gw)
+		if err != nil {
+			return err
+		}
+	}
+
 	// The server confirms the target regardless whether or not one was provided.
-	err = gw.ReceiveWithContext(gw.Context(), &session)
-	if err != nil {
-		return fmt.Errorf("Failed to find an eligible system: %w", err)
+	// Before that, the server reports any incompatible systems found during lookup.
+	for {
+		session = types.Session{}
+		err = gw.ReceiveWithContext(gw.Context(), &session)
+		if err != nil {
+			return fmt.Errorf("Failed to find an eligible system: %w", err)
+		}
+
+		if session.IncompatibleSystem == nil {
+			break
+		}
+
+		tui.PrintWarning(incompatibleSystemWarning(*session.IncompatibleSystem))
 	}
 
 	if !c.autoSetup {
@@ -223,3 +413,31 @@ func (c *initConfig) joiningSession(gw *cloudClient.WebsocketGateway, sh *servic
 
 	return c.askJoinConfirmation(gw, services)
 }
+
+// sendPassphrase receives the passphrase settings of the initiator found by the daemon, and replies with the passphrase
+// entered using these settings.
+func (c *initConfig) sendPassphrase(gw *cloudClient.WebsocketGateway) error {
+	settings := types.Session{}
+	err := gw.ReceiveWithContext(gw.Context(), &settings)
+	if err != nil {
+		return fmt.Errorf("Failed to find an eligible system: %w", err)
+	}
+
+	passphrase, err := c.askPassphrase("Specify the passphrase for joining the system", settings.Wordlist, settings.PassphraseWords)
+	if err != nil {
+		return err
+	}
+
+	err = gw.Write(types.Session{Passphrase: passphrase})
+	if err != nil {
+		return fmt.Errorf("Failed to send passphrase: %w", err)
+	}
+
+	return nil
+}
+
+// incompatibleSystemWarning returns the warning shown for a system found during the session which doesn't support any of our versions.
+func incompatibleSystemWarning(system types.SessionSystem) string {
+	versions := multicast.NewVersionRange(system.MinVersion, system.Version)
+	return fmt.Sprintf("Skipping system %q at %q as it supports incompatible versions %q (supported versions are %q)", system.Name, system.Address, versions.String(), multicast.SupportedVersions.String())
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/canonical/microcluster/v3/microcluster"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/canonical/microcloud/microcloud/api/types"
+	cloudClient "github.com/canonical/microcloud/microcloud/client"
+	"github.com/canonical/microcloud/microcloud/cmd/tui"
+)
+
+type cmdSession struct {
+	common *CmdControl
+}
+
+// command returns the subcommand to inspect trust establishment sessions.
+func (c *cmdSession) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "session",
+		Short: "Inspect the active trust establishment session",
+		RunE:  c.run,
+	}
+
+	var cmdShow = cmdSessionShow{common: c.common}
+	cmd.AddCommand(cmdShow.command())
+
+	var cmdLog = cmdSessionLog{common: c.common}
+	cmd.AddCommand(cmdLog.command())
+
+	var cmdWatch = cmdSessionWatch{common: c.common}
+	cmd.AddCommand(cmdWatch.command())
+
+	return cmd
+}
+
+// run runs the subcommand to inspect trust establishment sessions.
+func (c *cmdSession) run(cmd *cobra.Command, args []string) error {
+	return cmd.Help()
+}
+
+type cmdSessionShow struct {
+	common *CmdControl
+}
+
+// command returns the subcommand to show the active trust establishment session.
+func (c *cmdSessionShow) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show the state of the active trust establishment session",
+		RunE:  c.run,
+	}
+
+	return cmd
+}
+
+// run runs the subcommand to show the active trust establishment session.
+func (c *cmdSessionShow) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return cmd.Help()
+	}
+
+	m, err := microcluster.App(microcluster.Args{StateDir: c.common.FlagMicroCloudDir})
+	if err != nil {
+		return err
+	}
+
+	err = m.Ready(context.Background())
+	if err != nil {
+		return fmt.Errorf("Failed to wait for MicroCloud to get ready: %w", err)
+	}
+
+	client, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	status, err := cloudClient.GetSession(context.Background(), client)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal session: %w", err)
+	}
+
+	fmt.Print(string(data))
+
+	return nil
+}
+
+type cmdSessionLog struct {
+	common *CmdControl
+
+	flagEvent  string
+	flagFormat string
+}
+
+// command returns the subcommand to list the events of all trust establishment sessions.
+func (c *cmdSessionLog) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "log",
+		Short: "List the recorded events of all trust establishment sessions",
+		Long: `List the recorded events of all trust establishment sessions
+
+Events include session starts and stops, join intents, failed HMAC attempts and accepted systems.`,
+		RunE: c.run,
+	}
+
+	cmd.Flags().StringVar(&c.flagEvent, "event", "", "Only list events of the given kind"+"``")
+	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", tui.TableFormatTable, "Format (csv|json|table|yaml|compact)")
+
+	return cmd
+}
+
+// run runs the subcommand to list the events of all trust establishment sessions.
+func (c *cmdSessionLog) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return cmd.Help()
+	}
+
+	m, err := microcluster.App(microcluster.Args{StateDir: c.common.FlagMicroCloudDir})
+	if err != nil {
+		return err
+	}
+
+	err = m.Ready(context.Background())
+	if err != nil {
+		return fmt.Errorf("Failed to wait for MicroCloud to get ready: %w", err)
+	}
+
+	client, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	entries, err := cloudClient.GetSessionLog(context.Background(), client, types.SessionEvent(c.flagEvent))
+	if err != nil {
+		return err
+	}
+
+	// Keep the order of the log instead of sorting the rows.
+	data := make([][]string, 0, len(entries))
+	for _, entry := range entries {
+		fingerprint := entry.Fingerprint
+		if len(fingerprint) > 12 {
+			fingerprint = fingerprint[:12]
+		}
+
+		data = append(data, []string{entry.Time.Local().Format(time.DateTime), string(entry.Role), string(entry.Event), entry.Name, entry.Address, fingerprint, entry.Version, entry.Message})
+	}
+
+	header := []string{"TIME", "ROLE", "EVENT", "NAME", "ADDRESS", "FINGERPRINT", "VERSION", "MESSAGE"}
+	table, err := tui.FormatData(c.flagFormat, header, data, entries)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(table)
+
+	return nil
+}
+
+type cmdSessionWatch struct {
+	common *CmdControl
+}
+
+// command returns the subcommand to watch the messages of trust establishment sessions.
+func (c *cmdSessionWatch) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Watch the messages of trust establishment sessions",
+		Long: `Watch the messages of trust establishment sessions
+
+Prints each message exchanged in the active and any later session as a line of JSON, including join intents,
+confirmed systems, accepted joins and errors. The session passphrase is never shown.`,
+		RunE: c.run,
+	}
+
+	return cmd
+}
+
+// run runs the subcommand to watch the messages of trust establishment sessions.
+func (c *cmdSessionWatch) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return cmd.Help()
+	}
+
+	m, err := microcluster.App(microcluster.Args{StateDir: c.common.FlagMicroCloudDir})
+	if err != nil {
+		return err
+	}
+
+	err = m.Ready(context.Background())
+	if err != nil {
+		return fmt.Errorf("Failed to wait for MicroCloud to get ready: %w", err)
+	}
+
+	client, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	conn, err := cloudClient.WatchSessions(context.Background(), client)
+	if err != nil {
+		return err
+	}
+
+	gw := cloudClient.NewWebsocketGateway(context.Background(), conn)
+	for {
+		select {
+		case data, ok := <-gw.Receive():
+			if !ok {
+				return context.Cause(gw.Context())
+			}
+
+			fmt.Println(string(bytes.TrimSpace(data)))
+		case <-gw.Context().Done():
+			return context.Cause(gw.Context())
+		}
+	}
+}
@@ -12,7 +12,6 @@ import (
 	microTypes "github.com/canonical/microcluster/v3/microcluster/types"
 	"github.com/spf13/cobra"
 
-	"github.com/canonical/microcloud/microcloud/api"
 	"github.com/canonical/microcloud/microcloud/api/types"
 	"github.com/canonical/microcloud/microcloud/client"
 	"github.com/canonical/microcloud/microcloud/cmd/tui"
@@ -31,19 +30,20 @@ type Warnings []Warning
 // Status returns the overall status of the warning list.
 // If there are any Error level warnings, the status will be error.
 // Otherwise, if there are any Warn level warnings, the status will be warn.
-// Finally, the status will be Success, implying no warnings.
+// Finally, the status will be Success, implying no warnings other than informational Success level ones.
 func (w Warnings) Status() StatusLevel {
-	if len(w) == 0 {
-		return Success
-	}
-
+	status := Success
 	for _, warning := range w {
 		if warning.Level == Error {
 			return Error
 		}
+
+		if warning.Level == Warn {
+			status = Warn
+		}
 	}
 
-	return Warn
+	return status
 }
 
 // StatusLevel represents the severity level of warnings.
@@ -140,13 +140,9 @@ func (c *cmdStatus) run(cmd *cobra.Command, args []string) error {
 	cfg.name = status.Name
 	cfg.address = status.Address.Addr().String()
 
-	services := []types.ServiceType{types.MicroCloud, types.LXD}
-	optionalServices := map[types.ServiceType]string{
-		types.MicroCeph: api.MicroCephDir,
-		types.MicroOVN:  api.MicroOVNDir,
-	}
+	services := service.RequiredServices()
 
-	services, err = cfg.askMissingServices(services, optionalServices)
+	services, err = cfg.askMissingServices(services)
 	if err != nil {
 		return err
 	}
@@ -244,72 +240,88 @@ func compileWarnings(name string, statuses []types.Status) Warnings {
 	// Systems that are offline on at least one service.
 	offlineSystems := map[string][]string{}
 
+	// Systems that are either offline or upgrading on each service. Their health reports only repeat their member status.
+	unavailableSystems := map[types.ServiceType]map[string]bool{}
+
 	osdsConfigured := false
 	clusterSize := 0
 	osdCount := 0
 
+	registrations := service.RegisteredServices()
+	allServices := make([]types.ServiceType, 0, len(registrations))
+	for _, registration := range registrations {
+		allServices = append(allServices, registration.Type)
+	}
+
 	for _, s := range statuses {
 		if s.Name == name {
 			clusterSize = len(s.Clusters[types.MicroCloud])
-			for service, clusterMembers := range s.Clusters {
+			for svc, clusterMembers := range s.Clusters {
 				for _, member := range clusterMembers {
+					if member.Status != microTypes.MemberOnline {
+						if unavailableSystems[svc] == nil {
+							unavailableSystems[svc] = map[string]bool{}
+						}
+
+						unavailableSystems[svc][member.Name] = true
+					}
+
 					if member.Status == microTypes.MemberNeedsUpgrade || member.Status == microTypes.MemberUpgrading {
-						upgradingServices[service] = true
+						upgradingServices[svc] = true
 					} else if member.Status != microTypes.MemberOnline {
 						if offlineSystems[member.Name] == nil {
 							offlineSystems[member.Name] = []string{}
 						}
 
-						offlineSystems[member.Name] = append(offlineSystems[member.Name], string(service))
+						offlineSystems[member.Name] = append(offlineSystems[member.Name], string(svc))
 					}
 				}
 			}
 		}
 
 		osdCount = osdCount + len(s.OSDs)
-		allServices := []types.ServiceType{types.LXD, types.MicroCeph, types.MicroOVN, types.MicroCloud}
 		cloudMembers := make(map[string]bool, len(s.Clusters[types.MicroCloud]))
 		for _, member := range s.Clusters[types.MicroCloud] {
 			cloudMembers[member.Name] = true
 		}
 
-		for _, service := range allServices {
-			members, ok := s.Clusters[service]
+		for _, svc := range allServices {
+			members, ok := s.Clusters[svc]
 			if !ok || len(members) == 0 {
-				if uninstalledServices[service] == nil {
-					uninstalledServices[service] = []string{}
+				if uninstalledServices[svc] == nil {
+					uninstalledServices[svc] = []string{}
 				}
 
-				uninstalledServices[service] = append(uninstalledServices[service], s.Name)
+				uninstalledServices[svc] = append(uninstalledServices[svc], s.Name)
 			}
 
-			if service == types.MicroCloud || s.Name != name {
+			if svc == types.MicroCloud || s.Name != name {
 				continue
 			}
 
-			for _, member := range s.Clusters[service] {
+			for _, member := range s.Clusters[svc] {
 				if !cloudMembers[member.Name] {
-					if unmanagedSystems[service] == nil {
-						unmanagedSystems[service] = map[string]bool{}
+					if unmanagedSystems[svc] == nil {
+						unmanagedSystems[svc] = map[string]bool{}
 					}
 
-					unmanagedSystems[service][member.Name] = true
+					unmanagedSystems[svc][member.Name] = true
 				}
 			}
 
-			if len(s.Clusters[service]) > 0 {
-				clusterMap := make(map[string]bool, len(s.Clusters[service]))
-				for _, member := range s.Clusters[service] {
+			if len(s.Clusters[svc]) > 0 {
+				clusterMap := make(map[string]bool, len(s.Clusters[svc]))
+				for _, member := range s.Clusters[svc] {
 					clusterMap[member.Name] = true
 				}
 
 				for name := range cloudMembers {
 					if !clusterMap[name] {
-						if orphanedSystems[service] == nil {
-							orphanedSystems[service] = map[string]bool{}
+						if orphanedSystems[svc] == nil {
+							orphanedSystems[svc] = map[string]bool{}
 						}
 
-						orphanedSystems[service][name] = true
+						orphanedSystems[svc][name] = true
 					}
 				}
 			}
@@ -349,7 +361,7 @@ func compileWarnings(name string, statuses []types.Status) Warnings {
 		warnings = append(warnings, Warning{Level: Error, Message: msg})
 	}
 
-	for service, systems := range orphanedSystems {
+	for svc, systems := range orphanedSystems {
 		list := make([]string, 0, len(systems))
 		for name := range systems {
 			list = append(list, name)
@@ -357,7 +369,7 @@ func compileWarnings(name string, statuses []types.Status) Warnings {
 
 		tmpl := tui.Fmt{Arg: "MicroCloud members not found in %s: %s"}
 		msg := tui.Printf(tmpl,
-			tui.Fmt{Color: tui.Bright, Arg: service, Bold: true},
+			tui.Fmt{Color: tui.Bright, Arg: svc, Bold: true},
 			tui.Fmt{Color: tui.Bright, Bold: true, Arg: strings.Join(list, ", ")})
 		warnings = append(warnings, Warning{Level: Error, Message: msg})
 	}
@@ -372,25 +384,25 @@ func compileWarnings(name string, statuses []types.Status) Warnings {
 		warnings = append(warnings, Warning{Level: Error, Message: msg})
 	}
 
-	for service := range upgradingServices {
+	for svc := range upgradingServices {
 		tmpl := tui.Fmt{Arg: "%s upgrade in progress"}
-		msg := tui.Printf(tmpl, tui.Fmt{Color: tui.Bright, Bold: true, Arg: service})
+		msg := tui.Printf(tmpl, tui.Fmt{Color: tui.Bright, Bold: true, Arg: svc})
 		warnings = append(warnings, Warning{Level: Warn, Message: msg})
 	}
 
-	for service, names := range uninstalledServices {
-		if service == types.LXD || service == types.MicroCloud {
+	for svc, names := range uninstalledServices {
+		if svc == types.LXD || svc == types.MicroCloud {
 			continue
 		}
 
 		tmpl := tui.Fmt{Arg: "%s is not found on %s"}
 		msg := tui.Printf(tmpl,
-			tui.Fmt{Color: tui.Bright, Bold: true, Arg: service},
+			tui.Fmt{Color: tui.Bright, Bold: true, Arg: svc},
 			tui.Fmt{Color: tui.Bright, Bold: true, Arg: strings.Join(names, ", ")})
 		warnings = append(warnings, Warning{Level: Warn, Message: msg})
 	}
 
-	for service, systems := range unmanagedSystems {
+	for svc, systems := range unmanagedSystems {
 		list := make([]string, 0, len(systems))
 		for name := range systems {
 			list = append(list, name)
@@ -398,11 +410,80 @@ func compileWarnings(name string, statuses []types.Status) Warnings {
 
 		tmpl := tui.Fmt{Arg: "Found %s systems not managed by MicroCloud: %s"}
 		msg := tui.Printf(tmpl,
-			tui.Fmt{Color: tui.Bright, Bold: true, Arg: service},
+			tui.Fmt{Color: tui.Bright, Bold: true, Arg: svc},
 			tui.Fmt{Color: tui.Bright, Bold: true, Arg: strings.Join(list, ",")})
 		warnings = append(warnings, Warning{Level: Warn, Message: msg})
 	}
 
+	// Conditions of the whole cluster are reported by every member, so group identical messages of each service.
+	type healthMessage struct {
+		service types.ServiceType
+		message string
+	}
+
+	healthMessages := []healthMessage{}
+	healthLevels := map[healthMessage]StatusLevel{}
+	healthSystems := map[healthMessage][]string{}
+	healthReporters := map[types.ServiceType]int{}
+	for _, s := range statuses {
+		for svc, report := range s.Health {
+			if unavailableSystems[svc][s.Name] {
+				continue
+			}
+
+			healthReporters[svc]++
+			level := Warn
+			if report.Status == types.HealthUnhealthy {
+				level = Error
+			}
+
+			for _, message := range report.Messages {
+				key := healthMessage{service: svc, message: message}
+				_, ok := healthLevels[key]
+				if !ok {
+					healthMessages = append(healthMessages, key)
+				}
+
+				if level == Error || !ok {
+					healthLevels[key] = level
+				}
+
+				healthSystems[key] = append(healthSystems[key], s.Name)
+			}
+
+			// Checks which cannot be done are only listed for information.
+			for _, message := range report.Unsupported {
+				key := healthMessage{service: svc, message: message}
+				_, ok := healthLevels[key]
+				if !ok {
+					healthMessages = append(healthMessages, key)
+					healthLevels[key] = Success
+				}
+
+				healthSystems[key] = append(healthSystems[key], s.Name)
+			}
+		}
+	}
+
+	for _, key := range healthMessages {
+		var msg string
+		systems := healthSystems[key]
+		if len(systems) > 1 && len(systems) == healthReporters[key.service] {
+			tmpl := tui.Fmt{Arg: "%s: %s"}
+			msg = tui.Printf(tmpl,
+				tui.Fmt{Color: tui.Bright, Bold: true, Arg: key.service},
+				tui.Fmt{Arg: key.message})
+		} else {
+			tmpl := tui.Fmt{Arg: "%s on %s: %s"}
+			msg = tui.Printf(tmpl,
+				tui.Fmt{Color: tui.Bright, Bold: true, Arg: key.service},
+				tui.Fmt{Color: tui.Bright, Bold: true, Arg: strings.Join(systems, ", ")},
+				tui.Fmt{Arg: key.message})
+		}
+
+		warnings = append(warnings, Warning{Level: healthLevels[key], Message: msg})
+	}
+
 	return warnings
 }
 
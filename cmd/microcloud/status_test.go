@@ -467,6 +467,111 @@ func (s *statusSuite) Test_statusWarnings() {
 			},
 			expectedWarnings: []Warning{},
 		},
+		{
+			desc: "3 node MicroCloud with unhealthy services",
+			statuses: []types.Status{
+				{
+					Name:    "micro01",
+					Address: "10.0.0.100",
+					Clusters: map[types.ServiceType][]microTypes.ClusterMember{
+						types.MicroCloud: {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberOnline)},
+						types.MicroOVN:   {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberOnline)},
+						types.MicroCeph:  {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberOnline)},
+						types.LXD:        {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberOnline)},
+					},
+					OSDs: cephTypes.Disks{{OSD: 0}},
+					Health: map[types.ServiceType]types.HealthReport{
+						types.LXD:      {Status: types.HealthDegraded, Messages: []string{"Offline cluster member: Member is offline"}},
+						types.MicroOVN: {Status: types.HealthOK, Messages: []string{}},
+					},
+				},
+				{
+					Name:    "micro02",
+					Address: "10.0.0.102",
+					Clusters: map[types.ServiceType][]microTypes.ClusterMember{
+						types.MicroCloud: {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberOnline)},
+						types.MicroOVN:   {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberOnline)},
+						types.MicroCeph:  {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberOnline)},
+						types.LXD:        {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberOnline)},
+					},
+					OSDs: cephTypes.Disks{{OSD: 1}},
+					Health: map[types.ServiceType]types.HealthReport{
+						types.MicroCeph: {Status: types.HealthUnhealthy, Messages: []string{"Database member is OFFLINE"}},
+					},
+				},
+				{
+					Name:    "micro03",
+					Address: "10.0.0.102",
+					Clusters: map[types.ServiceType][]microTypes.ClusterMember{
+						types.MicroCloud: {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberOnline)},
+						types.MicroOVN:   {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberOnline)},
+						types.MicroCeph:  {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberOnline)},
+						types.LXD:        {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberOnline)},
+					},
+					OSDs: cephTypes.Disks{{OSD: 2}},
+				},
+			},
+			expectedWarnings: []Warning{
+				{Level: Warn, Message: "LXD on micro01: Offline cluster member: Member is offline"},
+				{Level: Error, Message: "MicroCeph on micro02: Database member is OFFLINE"},
+			},
+		},
+		{
+			desc: "3 node MicroCloud with cluster-wide health issues and an offline member",
+			statuses: []types.Status{
+				{
+					Name:    "micro01",
+					Address: "10.0.0.100",
+					Clusters: map[types.ServiceType][]microTypes.ClusterMember{
+						types.MicroCloud: {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberOnline)},
+						types.MicroOVN:   {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberOnline)},
+						types.MicroCeph:  {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberUnreachable)},
+						types.LXD:        {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberOnline)},
+					},
+					OSDs: cephTypes.Disks{{OSD: 0}},
+					Health: map[types.ServiceType]types.HealthReport{
+						types.MicroCeph: {Status: types.HealthDegraded, Messages: []string{"Ceph monitors run on 2 members, 3 are required for fault tolerance"}, Unsupported: []string{"Ceph health checks and the state of each OSD aren't exposed by the MicroCeph API"}},
+					},
+				},
+				{
+					Name:    "micro02",
+					Address: "10.0.0.101",
+					Clusters: map[types.ServiceType][]microTypes.ClusterMember{
+						types.MicroCloud: {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberOnline)},
+						types.MicroOVN:   {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberOnline)},
+						types.MicroCeph:  {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberUnreachable)},
+						types.LXD:        {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberOnline)},
+					},
+					OSDs: cephTypes.Disks{{OSD: 1}},
+					Health: map[types.ServiceType]types.HealthReport{
+						types.MicroCeph: {Status: types.HealthDegraded, Messages: []string{"Ceph monitors run on 2 members, 3 are required for fault tolerance"}, Unsupported: []string{"Ceph health checks and the state of each OSD aren't exposed by the MicroCeph API"}},
+						types.MicroOVN:  {Status: types.HealthDegraded, Messages: []string{"OVN databases run on 2 members, 3 are required for fault tolerance"}, Unsupported: []string{"The raft cluster status of the OVN databases isn't exposed by the MicroOVN API"}},
+					},
+				},
+				{
+					Name:    "micro03",
+					Address: "10.0.0.102",
+					Clusters: map[types.ServiceType][]microTypes.ClusterMember{
+						types.MicroCloud: {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberOnline)},
+						types.MicroOVN:   {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberOnline)},
+						types.MicroCeph:  {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberUnreachable)},
+						types.LXD:        {genMember("micro01", microTypes.MemberOnline), genMember("micro02", microTypes.MemberOnline), genMember("micro03", microTypes.MemberOnline)},
+					},
+					OSDs: cephTypes.Disks{{OSD: 2}},
+					Health: map[types.ServiceType]types.HealthReport{
+						types.MicroCeph: {Status: types.HealthUnhealthy, Messages: []string{"Database member is OFFLINE"}},
+						types.MicroOVN:  {Status: types.HealthOK, Messages: []string{}},
+					},
+				},
+			},
+			expectedWarnings: []Warning{
+				{Level: Error, Message: "MicroCeph is not available on micro03"},
+				{Level: Warn, Message: "MicroCeph: Ceph monitors run on 2 members, 3 are required for fault tolerance"},
+				{Level: Success, Message: "MicroCeph: Ceph health checks and the state of each OSD aren't exposed by the MicroCeph API"},
+				{Level: Warn, Message: "MicroOVN on micro02: OVN databases run on 2 members, 3 are required for fault tolerance"},
+				{Level: Success, Message: "MicroOVN on micro02: The raft cluster status of the OVN databases isn't exposed by the MicroOVN API"},
+			},
+		},
 	}
 
 	for i, c := range cases {
@@ -490,3 +595,37 @@ func (s *statusSuite) Test_statusWarnings() {
 		}
 	}
 }
+
+func (s *statusSuite) Test_warningsStatus() {
+	cases := []struct {
+		desc           string
+		warnings       Warnings
+		expectedStatus StatusLevel
+	}{
+		{
+			desc:           "No warnings",
+			warnings:       Warnings{},
+			expectedStatus: Success,
+		},
+		{
+			desc:           "Only informational warnings",
+			warnings:       Warnings{{Level: Success, Message: "foo"}},
+			expectedStatus: Success,
+		},
+		{
+			desc:           "Informational and medium severity warnings",
+			warnings:       Warnings{{Level: Success, Message: "foo"}, {Level: Warn, Message: "bar"}},
+			expectedStatus: Warn,
+		},
+		{
+			desc:           "Medium and critical severity warnings",
+			warnings:       Warnings{{Level: Warn, Message: "foo"}, {Level: Error, Message: "bar"}},
+			expectedStatus: Error,
+		},
+	}
+
+	for i, c := range cases {
+		s.T().Log(i, c.desc)
+		s.Equal(c.expectedStatus, c.warnings.Status())
+	}
+}
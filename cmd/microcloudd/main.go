@@ -4,6 +4,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"strconv"
@@ -52,6 +53,12 @@ type cmdDaemon struct {
 
 	flagMicroCloudDir     string
 	flagHeartbeatInterval time.Duration
+
+	flagMulticastGroup string
+	flagMulticastPort  int64
+	flagMulticastTTL   int
+	flagRealm          string
+	flagVersionSkew    []string
 }
 
 // command returns the main microcloudd command.
@@ -83,17 +90,13 @@ func (c *cmdDaemon) run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("Failed to retrieve system hostname: %w", err)
 	}
 
-	services := []types.ServiceType{types.MicroCloud, types.LXD}
-	optionalServices := map[types.ServiceType]string{
-		types.MicroCeph: api.MicroCephDir,
-		types.MicroOVN:  api.MicroOVNDir,
-	}
-
-	for serviceType, stateDir := range optionalServices {
-		if service.Exists(serviceType, stateDir) {
-			services = append(services, serviceType)
+	services := service.RequiredServices()
+	optionalServices := service.OptionalServices()
+	for _, registration := range optionalServices {
+		if registration.Installed() {
+			services = append(services, registration.Type)
 		} else {
-			logger.Infof("Skipping %s service, could not detect state directory", serviceType)
+			logger.Infof("Skipping %s service, could not detect state directory", registration.Type)
 		}
 	}
 
@@ -102,11 +105,38 @@ func (c *cmdDaemon) run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if c.flagMulticastGroup != "" {
+		group := net.ParseIP(c.flagMulticastGroup)
+		if group == nil || !group.IsMulticast() {
+			return fmt.Errorf("Invalid multicast group %q", c.flagMulticastGroup)
+		}
+
+		s.DiscoveryGroup.Group = group
+	}
+
+	if c.flagMulticastPort <= 0 || c.flagMulticastPort > 65535 {
+		return fmt.Errorf("Invalid multicast port %d", c.flagMulticastPort)
+	}
+
+	if c.flagMulticastTTL < 0 || c.flagMulticastTTL > 255 {
+		return fmt.Errorf("Invalid multicast TTL %d", c.flagMulticastTTL)
+	}
+
+	s.DiscoveryGroup.Port = c.flagMulticastPort
+	s.DiscoveryGroup.TTL = c.flagMulticastTTL
+	s.DiscoveryRealm = c.flagRealm
+
+	s.VersionPolicy, err = service.ParseVersionPolicy(c.flagVersionSkew)
+	if err != nil {
+		return err
+	}
+
 	// Periodically check if new services have been installed.
 	go func() {
 		for {
-			for serviceName, stateDir := range optionalServices {
-				if service.Exists(serviceName, stateDir) {
+			for _, registration := range optionalServices {
+				serviceName := registration.Type
+				if registration.Installed() {
 					if s.Services[serviceName] != nil {
 						continue
 					}
@@ -132,7 +162,13 @@ func (c *cmdDaemon) run(cmd *cobra.Command, args []string) error {
 		api.ServicesCmd(s),
 		api.ServiceTokensCmd(s),
 		api.ServicesClusterCmd(s),
+		api.OperationCmd(s),
+		api.OperationWaitCmd(s),
+		api.SessionCmd(s),
+		api.SessionLogCmd(s),
+		api.SessionEventsCmd(s),
 		api.SessionJoinCmd(s),
+		api.SessionDiscoveryCmd(s),
 		api.SessionInitiatingCmd(s),
 		api.SessionJoiningCmd(s),
 		api.SessionStopCmd(s),
@@ -172,6 +208,12 @@ func (c *cmdDaemon) run(cmd *cobra.Command, args []string) error {
 		PreInitListenAddress: "[::]:" + strconv.FormatInt(service.CloudPort, 10),
 		Hooks: &microTypes.Hooks{
 			PostBootstrap: func(ctx context.Context, state microTypes.State, initConfig map[string]string) error {
+				// Write the events of the session which was running before the database got available.
+				err := s.SessionLog.Flush(ctx, state)
+				if err != nil {
+					logger.Warn("Failed to write session log", logger.Ctx{"err": err})
+				}
+
 				return setHandlerAddress(state.Address().Host)
 			},
 			PostJoin: func(ctx context.Context, state microTypes.State, cfg map[string]string) error {
@@ -184,6 +226,12 @@ func (c *cmdDaemon) run(cmd *cobra.Command, args []string) error {
 				case <-ctx.Done():
 				}
 
+				// Write the events of the session which was running before the database got available.
+				err := s.SessionLog.Flush(ctx, state)
+				if err != nil {
+					logger.Warn("Failed to write session log", logger.Ctx{"err": err})
+				}
+
 				return setHandlerAddress(state.Address().Host)
 			},
 			OnStart: func(ctx context.Context, state microTypes.State) error {
@@ -192,8 +240,14 @@ func (c *cmdDaemon) run(cmd *cobra.Command, args []string) error {
 				ReconcileClusterManagerTunnel(backgroundTasksCtx, backgroundTasks, s, state)
 				SendClusterManagerStatusMessageTask(backgroundTasksCtx, backgroundTasks, s, state)
 
+				// Keep the trust establishment session which got interrupted by the restart so that it can be resumed.
+				err := api.RestoreSession(state, s)
+				if err != nil {
+					logger.Warn("Failed to restore interrupted session", logger.Ctx{"err": err})
+				}
+
 				// If we are already initialized, there's nothing to do.
-				err := state.Database().IsOpen(ctx)
+				err = state.Database().IsOpen(ctx)
 				// If we encounter a non-503 error, that means the database failed for some reason.
 				if err != nil && !lxdAPI.StatusErrorCheck(err, http.StatusServiceUnavailable) {
 					return nil
@@ -306,6 +360,11 @@ func main() {
 
 	app.PersistentFlags().StringVar(&daemonCmd.flagMicroCloudDir, "state-dir", "", "Path to store state information for MicroCloud"+"``")
 	app.PersistentFlags().DurationVar(&daemonCmd.flagHeartbeatInterval, "heartbeat", time.Second*10, "Time between attempted heartbeats")
+	app.PersistentFlags().StringVar(&daemonCmd.flagMulticastGroup, "multicast-group", "", "Default multicast group used for discovery"+"``")
+	app.PersistentFlags().Int64Var(&daemonCmd.flagMulticastPort, "multicast-port", service.CloudMulticastPort, "Default multicast port used for discovery"+"``")
+	app.PersistentFlags().IntVar(&daemonCmd.flagMulticastTTL, "multicast-ttl", 0, "Default TTL of multicast discovery queries (system default if 0)"+"``")
+	app.PersistentFlags().StringVar(&daemonCmd.flagRealm, "realm", "", "Default deployment realm used to separate discovery between deployments"+"``")
+	app.PersistentFlags().StringSliceVar(&daemonCmd.flagVersionSkew, "version-skew", nil, "Version skew allowed for joining systems (none|patch|minor), either for all services or per service as <service>=<skew>")
 
 	app.SetVersionTemplate("{{.Version}}\n")
 
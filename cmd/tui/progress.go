@@ -0,0 +1,73 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/canonical/lxd/shared/termios"
+)
+
+// progressBarWidth is the number of characters used to draw the bar of a progress bar.
+const progressBarWidth = 20
+
+// ProgressBar renders the progress of a long-running task on a single line of the terminal.
+type ProgressBar struct {
+	out     io.Writer
+	title   string
+	enabled bool
+	last    string
+}
+
+// NewProgressBar returns a progress bar with the given title.
+// The progress bar is only rendered if stdout is a terminal.
+func NewProgressBar(title string) *ProgressBar {
+	return &ProgressBar{
+		out:     os.Stdout,
+		title:   title,
+		enabled: termios.IsTerminal(int(os.Stdout.Fd())),
+	}
+}
+
+// Update redraws the progress bar with the given number of finished steps out of the total steps.
+// The status is shown next to the bar.
+func (p *ProgressBar) Update(done int, total int, status string) {
+	if !p.enabled {
+		return
+	}
+
+	line := p.render(done, total, status)
+	if line == p.last {
+		return
+	}
+
+	p.last = line
+	fmt.Fprint(p.out, "\r\033[K"+line)
+}
+
+// Done removes the progress bar from the terminal.
+func (p *ProgressBar) Done() {
+	if !p.enabled || p.last == "" {
+		return
+	}
+
+	p.last = ""
+	fmt.Fprint(p.out, "\r\033[K")
+}
+
+// render returns the line representing the given progress.
+func (p *ProgressBar) render(done int, total int, status string) string {
+	filled := 0
+	if total > 0 {
+		filled = min(done, total) * progressBarWidth / total
+	}
+
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	line := fmt.Sprintf("%s [%s] %d/%d", p.title, bar, done, total)
+	if status != "" {
+		line += " " + status
+	}
+
+	return line
+}
@@ -0,0 +1,565 @@
+package tui
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// qrQuietZone is the number of light modules surrounding the QR code.
+const qrQuietZone = 4
+
+// qrVersion describes the error correction block structure of a QR code version using error correction level M.
+type qrVersion struct {
+	// ecCodewords is the number of error correction codewords of each block.
+	ecCodewords int
+
+	// blocks contains the number of data codewords of each block.
+	blocks []int
+
+	// alignment contains the row and column coordinates of the alignment patterns.
+	alignment []int
+}
+
+// qrVersions contains the supported QR code versions 1 to 15 using error correction level M.
+var qrVersions = []qrVersion{
+	{ecCodewords: 10, blocks: []int{16}},
+	{ecCodewords: 16, blocks: []int{28}, alignment: []int{6, 18}},
+	{ecCodewords: 26, blocks: []int{44}, alignment: []int{6, 22}},
+	{ecCodewords: 18, blocks: []int{32, 32}, alignment: []int{6, 26}},
+	{ecCodewords: 24, blocks: []int{43, 43}, alignment: []int{6, 30}},
+	{ecCodewords: 16, blocks: []int{27, 27, 27, 27}, alignment: []int{6, 34}},
+	{ecCodewords: 18, blocks: []int{31, 31, 31, 31}, alignment: []int{6, 22, 38}},
+	{ecCodewords: 22, blocks: []int{38, 38, 39, 39}, alignment: []int{6, 24, 42}},
+	{ecCodewords: 22, blocks: []int{36, 36, 36, 37, 37}, alignment: []int{6, 26, 46}},
+	{ecCodewords: 26, blocks: []int{43, 43, 43, 43, 44}, alignment: []int{6, 28, 50}},
+	{ecCodewords: 30, blocks: []int{50, 51, 51, 51, 51}, alignment: []int{6, 30, 54}},
+	{ecCodewords: 22, blocks: []int{36, 36, 36, 36, 36, 36, 37, 37}, alignment: []int{6, 32, 58}},
+	{ecCodewords: 22, blocks: []int{37, 37, 37, 37, 37, 37, 37, 37, 38}, alignment: []int{6, 34, 62}},
+	{ecCodewords: 24, blocks: []int{40, 40, 40, 40, 41, 41, 41, 41, 41}, alignment: []int{6, 26, 46, 66}},
+	{ecCodewords: 24, blocks: []int{41, 41, 41, 41, 41, 42, 42, 42, 42, 42}, alignment: []int{6, 26, 48, 70}},
+}
+
+// dataCodewords returns the total number of data codewords of the version.
+func (v qrVersion) dataCodewords() int {
+	total := 0
+	for _, block := range v.blocks {
+		total += block
+	}
+
+	return total
+}
+
+// QRCode renders the given content as a QR code which can be printed to a terminal.
+// Each line of text combines two rows of modules using half block characters.
+// The colors are set explicitly so the code can be scanned regardless of the terminal's color scheme.
+func QRCode(content string) (string, error) {
+	modules, err := encodeQRCode([]byte(content))
+	if err != nil {
+		return "", err
+	}
+
+	size := len(modules)
+	dark := func(row int, col int) bool {
+		row -= qrQuietZone
+		col -= qrQuietZone
+		if row < 0 || col < 0 || row >= size || col >= size {
+			return false
+		}
+
+		return modules[row][col]
+	}
+
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color(black)).Background(lipgloss.Color(brightWhite))
+
+	lines := make([]string, 0, (size+2*qrQuietZone+1)/2)
+	for row := 0; row < size+2*qrQuietZone; row += 2 {
+		var line strings.Builder
+		for col := range size + 2*qrQuietZone {
+			top := dark(row, col)
+			bottom := dark(row+1, col)
+			switch {
+			case top && bottom:
+				line.WriteString("█")
+			case top:
+				line.WriteString("▀")
+			case bottom:
+				line.WriteString("▄")
+			default:
+				line.WriteString(" ")
+			}
+		}
+
+		lines = append(lines, style.Render(line.String()))
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// encodeQRCode encodes the given data in byte mode using error correction level M.
+// It picks the smallest supported version which fits the data and returns the modules of the QR code.
+// A module is true if it's dark.
+func encodeQRCode(data []byte) ([][]bool, error) {
+	versionNumber := 0
+	for i, version := range qrVersions {
+		countBits := 8
+		if i+1 >= 10 {
+			countBits = 16
+		}
+
+		if 4+countBits+len(data)*8 <= version.dataCodewords()*8 {
+			versionNumber = i + 1
+			break
+		}
+	}
+
+	if versionNumber == 0 {
+		return nil, errors.New("Content is too long to be encoded as QR code")
+	}
+
+	version := qrVersions[versionNumber-1]
+	codewords := qrCodewords(versionNumber, version, data)
+
+	// Use the mask which results in the lowest penalty.
+	var best [][]bool
+	bestPenalty := -1
+	for mask := range 8 {
+		modules := qrModules(versionNumber, version, codewords, mask)
+		penalty := qrPenalty(modules)
+		if bestPenalty < 0 || penalty < bestPenalty {
+			best = modules
+			bestPenalty = penalty
+		}
+	}
+
+	return best, nil
+}
+
+// qrModules returns the modules of the given version containing the given codewords masked using the given mask pattern.
+func qrModules(versionNumber int, version qrVersion, codewords []byte, mask int) [][]bool {
+	modules, function := qrFunctionPatterns(versionNumber, version)
+	qrPlaceCodewords(modules, function, codewords)
+	qrApplyMask(modules, function, mask)
+	qrDrawFormat(modules, mask)
+
+	return modules
+}
+
+// qrCodewords returns the final sequence of interleaved data and error correction codewords.
+func qrCodewords(versionNumber int, version qrVersion, data []byte) []byte {
+	capacity := version.dataCodewords() * 8
+
+	bits := make([]bool, 0, capacity)
+	appendBits := func(value int, length int) {
+		for i := length - 1; i >= 0; i-- {
+			bits = append(bits, (value>>i)&1 == 1)
+		}
+	}
+
+	// Byte mode indicator followed by the character count.
+	appendBits(0b0100, 4)
+	if versionNumber >= 10 {
+		appendBits(len(data), 16)
+	} else {
+		appendBits(len(data), 8)
+	}
+
+	for _, b := range data {
+		appendBits(int(b), 8)
+	}
+
+	// Terminator and padding to the next full byte.
+	appendBits(0, min(4, capacity-len(bits)))
+	appendBits(0, (8-len(bits)%8)%8)
+
+	dataCodewords := make([]byte, 0, version.dataCodewords())
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for j := range 8 {
+			if bits[i+j] {
+				b |= 1 << (7 - j)
+			}
+		}
+
+		dataCodewords = append(dataCodewords, b)
+	}
+
+	for i := 0; len(dataCodewords) < version.dataCodewords(); i++ {
+		if i%2 == 0 {
+			dataCodewords = append(dataCodewords, 0xEC)
+		} else {
+			dataCodewords = append(dataCodewords, 0x11)
+		}
+	}
+
+	// Split the data into blocks and compute the error correction codewords of each block.
+	dataBlocks := make([][]byte, 0, len(version.blocks))
+	ecBlocks := make([][]byte, 0, len(version.blocks))
+	offset := 0
+	maxBlock := 0
+	for _, length := range version.blocks {
+		block := dataCodewords[offset : offset+length]
+		dataBlocks = append(dataBlocks, block)
+		ecBlocks = append(ecBlocks, qrReedSolomon(block, version.ecCodewords))
+		offset += length
+		maxBlock = max(maxBlock, length)
+	}
+
+	// Interleave the codewords of all blocks.
+	result := make([]byte, 0, len(dataCodewords)+len(version.blocks)*version.ecCodewords)
+	for i := range maxBlock {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+
+	for i := range version.ecCodewords {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+
+	return result
+}
+
+// qrGaloisMultiply multiplies the given elements of GF(256) using the QR code's primitive polynomial.
+func qrGaloisMultiply(a byte, b byte) byte {
+	var result byte
+	for i := 7; i >= 0; i-- {
+		carry := result&0x80 != 0
+		result <<= 1
+		if carry {
+			result ^= 0x1D
+		}
+
+		if (b>>i)&1 == 1 {
+			result ^= a
+		}
+	}
+
+	return result
+}
+
+// qrReedSolomon returns the given number of Reed-Solomon error correction codewords for the given data.
+func qrReedSolomon(data []byte, length int) []byte {
+	// Compute the generator polynomial (x - 2^0) * (x - 2^1) * ... * (x - 2^(length-1)).
+	// The coefficients are stored from the highest to the lowest power, omitting the leading 1.
+	generator := make([]byte, length)
+	generator[length-1] = 1
+	var root byte = 1
+	for range length {
+		for j := range length {
+			generator[j] = qrGaloisMultiply(generator[j], root)
+			if j+1 < length {
+				generator[j] ^= generator[j+1]
+			}
+		}
+
+		root = qrGaloisMultiply(root, 0x02)
+	}
+
+	// Compute the remainder of the polynomial division.
+	result := make([]byte, length)
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[length-1] = 0
+		for i := range length {
+			result[i] ^= qrGaloisMultiply(generator[i], factor)
+		}
+	}
+
+	return result
+}
+
+// qrFunctionPatterns returns the modules of the given version with all function patterns drawn.
+// The second return value marks the modules which are part of a function pattern or reserved for the format and version information.
+func qrFunctionPatterns(versionNumber int, version qrVersion) ([][]bool, [][]bool) {
+	size := versionNumber*4 + 17
+
+	modules := make([][]bool, size)
+	function := make([][]bool, size)
+	for i := range size {
+		modules[i] = make([]bool, size)
+		function[i] = make([]bool, size)
+	}
+
+	set := func(row int, col int, dark bool) {
+		modules[row][col] = dark
+		function[row][col] = true
+	}
+
+	// Timing patterns.
+	for i := range size {
+		set(6, i, i%2 == 0)
+		set(i, 6, i%2 == 0)
+	}
+
+	// Finder patterns including their separators.
+	for _, corner := range [][2]int{{3, 3}, {3, size - 4}, {size - 4, 3}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				row := corner[0] + dy
+				col := corner[1] + dx
+				if row < 0 || col < 0 || row >= size || col >= size {
+					continue
+				}
+
+				distance := max(abs(dx), abs(dy))
+				set(row, col, distance != 2 && distance != 4)
+			}
+		}
+	}
+
+	// Alignment patterns, except the ones overlapping the finder patterns.
+	last := len(version.alignment) - 1
+	for i, row := range version.alignment {
+		for j, col := range version.alignment {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					set(row+dy, col+dx, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// Reserve the format information areas, they are drawn once the mask is known.
+	for i := range 9 {
+		function[8][i] = true
+		function[i][8] = true
+	}
+
+	for i := range 8 {
+		function[8][size-1-i] = true
+		function[size-1-i][8] = true
+	}
+
+	// The dark module.
+	set(size-8, 8, true)
+
+	// Version information.
+	if versionNumber >= 7 {
+		remainder := versionNumber
+		for range 12 {
+			remainder = (remainder << 1) ^ ((remainder >> 11) * 0x1F25)
+		}
+
+		bits := versionNumber<<12 | remainder
+		for i := range 18 {
+			dark := (bits>>i)&1 == 1
+			a := size - 11 + i%3
+			b := i / 3
+			set(b, a, dark)
+			set(a, b, dark)
+		}
+	}
+
+	return modules, function
+}
+
+// qrPlaceCodewords places the bits of the given codewords in the zigzag order on all modules which aren't function modules.
+func qrPlaceCodewords(modules [][]bool, function [][]bool, codewords []byte) {
+	size := len(modules)
+	i := 0
+	for right := size - 1; right >= 1; right -= 2 {
+		// Skip the vertical timing pattern.
+		if right == 6 {
+			right = 5
+		}
+
+		upward := (right+1)&2 == 0
+		for vert := range size {
+			row := vert
+			if upward {
+				row = size - 1 - vert
+			}
+
+			for j := range 2 {
+				col := right - j
+				if function[row][col] || i >= len(codewords)*8 {
+					continue
+				}
+
+				modules[row][col] = (codewords[i/8]>>(7-i%8))&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// qrApplyMask inverts all modules which aren't function modules according to the given mask pattern.
+func qrApplyMask(modules [][]bool, function [][]bool, mask int) {
+	for row := range modules {
+		for col := range modules[row] {
+			if function[row][col] {
+				continue
+			}
+
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (row+col)%2 == 0
+			case 1:
+				invert = row%2 == 0
+			case 2:
+				invert = col%3 == 0
+			case 3:
+				invert = (row+col)%3 == 0
+			case 4:
+				invert = (row/2+col/3)%2 == 0
+			case 5:
+				invert = row*col%2+row*col%3 == 0
+			case 6:
+				invert = (row*col%2+row*col%3)%2 == 0
+			case 7:
+				invert = ((row+col)%2+row*col%3)%2 == 0
+			}
+
+			modules[row][col] = modules[row][col] != invert
+		}
+	}
+}
+
+// qrFormatBits returns the format information for error correction level M and the given mask pattern.
+func qrFormatBits(mask int) int {
+	// Error correction level M is indicated by 0b00.
+	data := mask
+	remainder := data
+	for range 10 {
+		remainder = (remainder << 1) ^ ((remainder >> 9) * 0x537)
+	}
+
+	return (data<<10 | remainder) ^ 0x5412
+}
+
+// qrDrawFormat draws both copies of the format information for the given mask pattern.
+func qrDrawFormat(modules [][]bool, mask int) {
+	size := len(modules)
+	bits := qrFormatBits(mask)
+	bit := func(i int) bool {
+		return (bits>>i)&1 == 1
+	}
+
+	// First copy around the top left finder pattern.
+	for i := range 6 {
+		modules[i][8] = bit(i)
+	}
+
+	modules[7][8] = bit(6)
+	modules[8][8] = bit(7)
+	modules[8][7] = bit(8)
+	for i := 9; i < 15; i++ {
+		modules[8][14-i] = bit(i)
+	}
+
+	// Second copy split between the top right and bottom left finder patterns.
+	for i := range 8 {
+		modules[8][size-1-i] = bit(i)
+	}
+
+	for i := 8; i < 15; i++ {
+		modules[size-15+i][8] = bit(i)
+	}
+}
+
+// qrPenalty returns the penalty score of the given modules used to select the best mask pattern.
+func qrPenalty(modules [][]bool) int {
+	size := len(modules)
+	penalty := 0
+
+	at := func(row int, col int, vertical bool) bool {
+		if vertical {
+			return modules[col][row]
+		}
+
+		return modules[row][col]
+	}
+
+	finderLike := []bool{true, false, true, true, true, false, true, false, false, false, false}
+
+	for _, vertical := range []bool{false, true} {
+		for row := range size {
+			// Runs of five or more modules of the same color.
+			run := 1
+			for col := 1; col < size; col++ {
+				if at(row, col, vertical) == at(row, col-1, vertical) {
+					run++
+					continue
+				}
+
+				if run >= 5 {
+					penalty += run - 2
+				}
+
+				run = 1
+			}
+
+			if run >= 5 {
+				penalty += run - 2
+			}
+
+			// Patterns looking like the finder patterns.
+			for col := 0; col+len(finderLike) <= size; col++ {
+				forward := true
+				backward := true
+				for k, dark := range finderLike {
+					if at(row, col+k, vertical) != dark {
+						forward = false
+					}
+
+					if at(row, col+len(finderLike)-1-k, vertical) != dark {
+						backward = false
+					}
+				}
+
+				if forward {
+					penalty += 40
+				}
+
+				if backward {
+					penalty += 40
+				}
+			}
+		}
+	}
+
+	// Blocks of two by two modules of the same color.
+	dark := 0
+	for row := range size {
+		for col := range size {
+			if modules[row][col] {
+				dark++
+			}
+
+			if row+1 < size && col+1 < size {
+				color := modules[row][col]
+				if modules[row][col+1] == color && modules[row+1][col] == color && modules[row+1][col+1] == color {
+					penalty += 3
+				}
+			}
+		}
+	}
+
+	// Deviation of the proportion of dark modules from one half.
+	total := size * size
+	deviation := abs(dark*20-total*10) / total
+	penalty += deviation * 10
+
+	return penalty
+}
+
+// abs returns the absolute value of the given integer.
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+
+	return i
+}
@@ -0,0 +1,189 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type qrCodeSuite struct {
+	suite.Suite
+}
+
+func TestQRCodeSuite(t *testing.T) {
+	suite.Run(t, new(qrCodeSuite))
+}
+
+func (s *qrCodeSuite) Test_qrReedSolomon() {
+	// Data and error correction codewords of "HELLO WORLD" encoded as version 1-M.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	expected := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+	s.Equal(expected, qrReedSolomon(data, len(expected)))
+}
+
+func (s *qrCodeSuite) Test_qrFormatBits() {
+	expected := []int{0b101010000010010, 0b101000100100101, 0b101111001111100, 0b101101101001011, 0b100010111111001, 0b100000011001110, 0b100111110010111, 0b100101010100000}
+	for mask, bits := range expected {
+		s.Equal(bits, qrFormatBits(mask))
+	}
+}
+
+func (s *qrCodeSuite) Test_encodeQRCode() {
+	cases := []struct {
+		desc       string
+		length     int
+		expectSize int
+		expectErr  bool
+	}{
+		{
+			desc:       "Smallest version",
+			length:     14,
+			expectSize: 21,
+		},
+		{
+			desc:       "Version with version information",
+			length:     120,
+			expectSize: 45,
+		},
+		{
+			desc:       "Largest version",
+			length:     412,
+			expectSize: 77,
+		},
+		{
+			desc:      "Content too long",
+			length:    413,
+			expectErr: true,
+		},
+	}
+
+	for i, c := range cases {
+		s.T().Logf("%d: %s", i, c.desc)
+
+		modules, err := encodeQRCode([]byte(strings.Repeat("a", c.length)))
+		if c.expectErr {
+			s.Error(err)
+			continue
+		}
+
+		s.NoError(err)
+		s.Len(modules, c.expectSize)
+
+		// The dark module is always set.
+		s.True(modules[c.expectSize-8][8])
+	}
+}
+
+func (s *qrCodeSuite) Test_encodeQRCodeGolden() {
+	// The expected matrices match the output of a reference encoder using the same version and mask pattern.
+	cases := []struct {
+		desc         string
+		content      string
+		expectMatrix []string
+	}{
+		{
+			desc:    "Version 1",
+			content: "microcloud",
+			expectMatrix: []string{
+				"#######.####..#######",
+				"#.....#.##....#.....#",
+				"#.###.#.###...#.###.#",
+				"#.###.#...#.#.#.###.#",
+				"#.###.#.#.#.#.#.###.#",
+				"#.....#...###.#.....#",
+				"#######.#.#.#.#######",
+				".........####........",
+				"#..######...##..#.###",
+				"######.####.##.......",
+				"..#.#.#..#...#....###",
+				"#.##.#...###.#..#.##.",
+				"...#..#..#.......#...",
+				"........##.##.#.#.##.",
+				"#######.#.#.#...##...",
+				"#.....#.##.#####.##..",
+				"#.###.#.#.###..#.....",
+				"#.###.#.#.####...##..",
+				"#.###.#..#...##.##.##",
+				"#.....#...#..########",
+				"#######.#..#.#.......",
+			},
+		},
+		{
+			desc:    "Version 7 with version information",
+			content: "microcloud://10.0.0.1?fingerprint=" + strings.Repeat("0123456789abcdef", 4) + "&passphrase=a+b+c+d",
+			expectMatrix: []string{
+				"#######.....###..#.......#....##.#..#.#######",
+				"#.....#...#.###..##..#.##.#..#.....#..#.....#",
+				"#.###.#.#.###..###..##.##.#.#.#.##.#..#.###.#",
+				"#.###.#.#.#....#..###.#..###.###...##.#.###.#",
+				"#.###.#.#..#.##.##.#######.####.#.###.#.###.#",
+				"#.....#.#.#.......#.#...#.#...........#.....#",
+				"#######.#.#.#.#.#.#.#.#.#.#.#.#.#.#.#.#######",
+				"........#..###..###.#...#####..##...#........",
+				"#.#####..#######..#######.##.##....#..#####..",
+				"...#...#.....######..#.....#.##.....##..##..#",
+				"..#.####...##.###.#...######....####..#.#..#.",
+				"#####..###.#..#...####.#...##.#.#...#..#####.",
+				".##.#.##.##########.#.#....#.###...#.#...#.##",
+				".#..#...#####..###.##..#.....###.#..#.....#.#",
+				".#..#.#..#..##.#...###..####.#..#.#.###..#.#.",
+				"##...#..####..#.###..#...#.##.###..##...####.",
+				"##..#######.##..###.##..#.#...#..#.#...#.....",
+				"..####.##.##.#.....#..##...#..####.##..##.#.#",
+				"###...##.##.###....####.###....##.##.##....#.",
+				"#..#.#......##..#.#....#...##.####.#.#..###.#",
+				".########.##.#.##.#######.#....#..#.#####....",
+				".##.#...#.#.#.####.##...#....##....##...#...#",
+				"#..##.#.#.###.#.##.##.#.#.#.....#.###.#.####.",
+				"..#.#...###....##...#...#...#.###...#...####.",
+				".##########..#####.#######.#.##..#..#####...#",
+				".##....##.####.#..#####.#..#######..#.##.##.#",
+				".#...##..#.#..##..#.#....##.#..#..#....#..##.",
+				"##.##......##..#.#..#..##..##.###..##.##..##.",
+				"##..###..##...#.#...##...#...#...##.#..###.#.",
+				".##.#...#....###.##...###....##..#.####..##.#",
+				"..#..##.######...#..#....##.#...#.#..#.##.##.",
+				"#.#.....##.....####..#..#..###..#..##.##.###.",
+				"#.#.#.#######.##..#.##.#...............##...#",
+				"...###.###..########..#.#...#.####.##.#...#.#",
+				"....#.###....#.#..#.#.##.###.#.##.#.#..#.#.#.",
+				".####....##...#####.###.#.###.#.##.#.##..###.",
+				"#..##.#.#..#....#...#######..#.#..#.#####....",
+				"........##..##..#####...##.#.##.....#...#.#.#",
+				"#######.....#..##..##.#.#.#.......#.#.#.##.#.",
+				"#.....#.#..#..#..##.#...#####.#######...###.#",
+				"#.###.#.#....#.#....########.....#########...",
+				"#.###.#.#..#..##.#..#.####.#.##.##.#...###.##",
+				"#.###.#.#...#.###.##.#..#.##...#.#####...###.",
+				"#.....#..#...#.#....#..#....#####.........#..",
+				"#######.#......#..##..#.#.##.##...#####..#.#.",
+			},
+		},
+	}
+
+	for i, c := range cases {
+		s.T().Logf("%d: %s", i, c.desc)
+
+		modules, err := encodeQRCode([]byte(c.content))
+		s.NoError(err)
+
+		// Dark modules are represented by "#" and light modules by ".".
+		matrix := make([]string, 0, len(modules))
+		for _, row := range modules {
+			var line strings.Builder
+			for _, dark := range row {
+				if dark {
+					line.WriteString("#")
+				} else {
+					line.WriteString(".")
+				}
+			}
+
+			matrix = append(matrix, line.String())
+		}
+
+		s.Equal(c.expectMatrix, matrix)
+	}
+}
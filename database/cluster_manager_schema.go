@@ -12,6 +12,7 @@ import (
 // Each entry will increase the database schema version by one, and will be applied after internal schema updates.
 var SchemaExtensions = []db.Update{
 	clusterManagerTables,
+	sessionLogTable,
 }
 
 func clusterManagerTables(ctx context.Context, tx *sql.Tx) error {
@@ -48,3 +49,23 @@ CREATE TABLE cluster_manager_config (
 
 	return err
 }
+
+func sessionLogTable(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE session_log (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+    time         DATETIME NOT NULL,
+    role         TEXT NOT NULL,
+    event        TEXT NOT NULL,
+    name         TEXT NOT NULL,
+    address      TEXT NOT NULL,
+    fingerprint  TEXT NOT NULL,
+    version      TEXT NOT NULL,
+    message      TEXT NOT NULL
+);
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
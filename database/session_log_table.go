@@ -0,0 +1,39 @@
+package database
+
+import (
+	"time"
+)
+
+//go:generate -command mapper lxd-generate db mapper -t session_log.mapper.go
+//go:generate mapper reset
+//
+//go:generate mapper stmt -d github.com/canonical/microcluster/v3/cluster -e SessionLogEntry objects table=session_log
+//go:generate mapper stmt -d github.com/canonical/microcluster/v3/cluster -e SessionLogEntry objects-by-ID table=session_log
+//go:generate mapper stmt -d github.com/canonical/microcluster/v3/cluster -e SessionLogEntry objects-by-Event table=session_log
+//go:generate mapper stmt -d github.com/canonical/microcluster/v3/cluster -e SessionLogEntry id table=session_log
+//go:generate mapper stmt -d github.com/canonical/microcluster/v3/cluster -e SessionLogEntry create table=session_log
+//
+//go:generate mapper method -i -d github.com/canonical/microcluster/v3/cluster -e SessionLogEntry GetMany table=session_log
+//go:generate mapper method -i -d github.com/canonical/microcluster/v3/cluster -e SessionLogEntry GetOne table=session_log
+//go:generate mapper method -i -d github.com/canonical/microcluster/v3/cluster -e SessionLogEntry ID table=session_log
+//go:generate mapper method -i -d github.com/canonical/microcluster/v3/cluster -e SessionLogEntry Exists table=session_log
+//go:generate mapper method -i -d github.com/canonical/microcluster/v3/cluster -e SessionLogEntry Create table=session_log
+
+// SessionLogEntry is used to record an event of a trust establishment session.
+type SessionLogEntry struct {
+	ID          int64 `db:"primary=yes"`
+	Time        time.Time
+	Role        string
+	Event       string
+	Name        string
+	Address     string
+	Fingerprint string
+	Version     string
+	Message     string
+}
+
+// SessionLogEntryFilter is used to filter session log queries.
+type SessionLogEntryFilter struct {
+	ID    *int64
+	Event *string
+}
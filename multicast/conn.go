@@ -0,0 +1,327 @@
+package multicast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+	"golang.org/x/sys/unix"
+)
+
+// controlMessage is the address family independent subset of the control message of a received datagram.
+// Its fields are empty if the datagram didn't carry a control message.
+type controlMessage struct {
+	// Dst is the destination address of the datagram.
+	Dst net.IP
+
+	// IfIndex is the index of the interface on which the datagram was received.
+	IfIndex int
+}
+
+// packetConn is the address family independent subset of the IPv4 and IPv6 packet connections used for discovery.
+type packetConn interface {
+	JoinGroup(iface *net.Interface, group net.Addr) error
+	SetMulticastInterface(iface *net.Interface) error
+	SetMulticastTTL(ttl int) error
+	ReadFrom(b []byte) (n int, cm controlMessage, src net.Addr, err error)
+	WriteTo(b []byte, dst net.Addr) (int, error)
+	Close() error
+}
+
+// ipv4Conn wraps an IPv4 packet connection.
+type ipv4Conn struct {
+	*ipv4.PacketConn
+}
+
+// ReadFrom reads a datagram and returns the destination address and interface from the control message if available.
+func (c ipv4Conn) ReadFrom(b []byte) (int, controlMessage, net.Addr, error) {
+	n, cm, src, err := c.PacketConn.ReadFrom(b)
+	if cm == nil {
+		return n, controlMessage{}, src, err
+	}
+
+	return n, controlMessage{Dst: cm.Dst, IfIndex: cm.IfIndex}, src, err
+}
+
+// WriteTo writes a datagram to the given destination without any control message.
+func (c ipv4Conn) WriteTo(b []byte, dst net.Addr) (int, error) {
+	return c.PacketConn.WriteTo(b, nil, dst)
+}
+
+// ipv6Conn wraps an IPv6 packet connection.
+type ipv6Conn struct {
+	*ipv6.PacketConn
+}
+
+// ReadFrom reads a datagram and returns the destination address and interface from the control message if available.
+func (c ipv6Conn) ReadFrom(b []byte) (int, controlMessage, net.Addr, error) {
+	n, cm, src, err := c.PacketConn.ReadFrom(b)
+	if cm == nil {
+		return n, controlMessage{}, src, err
+	}
+
+	return n, controlMessage{Dst: cm.Dst, IfIndex: cm.IfIndex}, src, err
+}
+
+// WriteTo writes a datagram to the given destination without any control message.
+func (c ipv6Conn) WriteTo(b []byte, dst net.Addr) (int, error) {
+	return c.PacketConn.WriteTo(b, nil, dst)
+}
+
+// SetMulticastTTL sets the hop limit of outgoing multicast datagrams.
+func (c ipv6Conn) SetMulticastTTL(ttl int) error {
+	return c.PacketConn.SetMulticastHopLimit(ttl)
+}
+
+// networksForAddrs returns the networks used for discovery based on the given interface addresses.
+// Both IPv4 and IPv6 are used if the interface has addresses of both families, so that peers of either family can be found.
+func networksForAddrs(addrs []net.Addr) ([]string, error) {
+	hasIPv4 := false
+	hasIPv6 := false
+	for _, addr := range addrs {
+		ip, _, err := net.ParseCIDR(addr.String())
+		if err != nil {
+			continue
+		}
+
+		if ip.To4() != nil {
+			hasIPv4 = true
+		} else {
+			hasIPv6 = true
+		}
+	}
+
+	networks := make([]string, 0, 2)
+	if hasIPv4 {
+		networks = append(networks, "udp4")
+	}
+
+	if hasIPv6 {
+		networks = append(networks, "udp6")
+	}
+
+	if len(networks) == 0 {
+		return nil, errors.New("No IP addresses found")
+	}
+
+	return networks, nil
+}
+
+// groupConn is a packet connection together with the multicast group of its address family.
+type groupConn struct {
+	conn  packetConn
+	group net.IP
+}
+
+// listen opens a packet connection for each address family of the addresses on the given interface.
+// The given setup function is called on each connection, e.g. to join its group.
+// A family which cannot be used is skipped as long as any other family can be used, as IPv6 might be disabled on the
+// interface even though it has a link-local address.
+// If reuse is set, the port can be shared with other processes, e.g. an already running mDNS responder.
+func listen(iface *net.Interface, port int64, groupV4 net.IP, groupV6 net.IP, reuse bool, setup func(c groupConn) error) ([]groupConn, error) {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get addresses of interface %q: %w", iface.Name, err)
+	}
+
+	networks, err := networksForAddrs(addrs)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to select address family for interface %q: %w", iface.Name, err)
+	}
+
+	conns := make([]groupConn, 0, len(networks))
+	errs := make([]error, 0, len(networks))
+	for _, network := range networks {
+		conn, group, err := listenNetwork(network, port, groupV4, groupV6, reuse)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		c := groupConn{conn: conn, group: group}
+		err = setup(c)
+		if err != nil {
+			_ = conn.Close()
+			errs = append(errs, err)
+			continue
+		}
+
+		conns = append(conns, c)
+	}
+
+	if len(conns) == 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	for _, err := range errs {
+		logger.Warn("Skipping address family for discovery", logger.Ctx{"interface": iface.Name, "err": err})
+	}
+
+	return conns, nil
+}
+
+// listenNetwork opens a packet connection of the given network and returns it together with the multicast group
+// of the respective family.
+// The destination address and interface control flags get set on the connection so that the group and interface
+// of received datagrams can be checked.
+func listenNetwork(network string, port int64, groupV4 net.IP, groupV6 net.IP, reuse bool) (packetConn, net.IP, error) {
+	lc := net.ListenConfig{}
+	if reuse {
+		lc.Control = reuseAddrControl
+	}
+
+	// The PacketConn gets closed when calling Close on the derived IPv4 or IPv6 PacketConn.
+	conn, err := lc.ListenPacket(context.Background(), network, fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to listen on %d: %w", port, err)
+	}
+
+	if network == "udp6" {
+		p := ipv6.NewPacketConn(conn)
+		err = p.SetControlMessage(ipv6.FlagDst|ipv6.FlagInterface, true)
+		if err != nil {
+			_ = p.Close()
+			return nil, nil, fmt.Errorf("Failed to set IPv6 control flags for destination address and interface: %w", err)
+		}
+
+		return ipv6Conn{PacketConn: p}, groupV6, nil
+	}
+
+	p := ipv4.NewPacketConn(conn)
+	err = p.SetControlMessage(ipv4.FlagDst|ipv4.FlagInterface, true)
+	if err != nil {
+		_ = p.Close()
+		return nil, nil, fmt.Errorf("Failed to set IPv4 control flags for destination address and interface: %w", err)
+	}
+
+	return ipv4Conn{PacketConn: p}, groupV4, nil
+}
+
+// closeConns closes all of the given connections.
+// Connections which are already closed are ignored.
+func closeConns(conns []groupConn) error {
+	for _, c := range conns {
+		err := c.conn.Close()
+		if err != nil && !errors.Is(err, net.ErrClosed) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// browseConns repeatedly sends the given query to the multicast group of each of the given connections
+// and reports every beacon parsed from the received responses.
+// The connections get closed once the context is cancelled, or once reading from any of them fails.
+// In the latter case, a final event containing the error is sent before closing the channel.
+func browseConns(ctx context.Context, conns []groupConn, port int, query []byte, bufSize int, parse func(b []byte) ([]Beacon, error)) <-chan BeaconEvent {
+	ctx, cancel := context.WithCancel(ctx)
+	events := make(chan BeaconEvent)
+
+	wg := sync.WaitGroup{}
+	for _, c := range conns {
+		// Time of the most recently sent query used to measure the round-trip time of the responses.
+		var sent atomic.Int64
+
+		go func() {
+			dst := &net.UDPAddr{IP: c.group, Port: port}
+
+			// Only log when sending starts failing as the group of one family might not be routable on the interface.
+			failing := false
+			for {
+				select {
+				case <-ctx.Done():
+					// Close the network endpoint if the lookup context got cancelled.
+					_ = c.conn.Close()
+					return
+				default:
+					sent.Store(time.Now().UnixNano())
+					_, err := c.conn.WriteTo(query, dst)
+					if err != nil && !failing {
+						logger.Error("Failed to send discovery query", logger.Ctx{"group": c.group.String(), "err": err})
+					}
+
+					failing = err != nil
+					time.Sleep(time.Second)
+				}
+			}
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				buf := make([]byte, bufSize)
+
+				// Block until the read succeeds or the connection is closed.
+				// The latter happens in case the context gets cancelled.
+				n, _, _, err := c.conn.ReadFrom(buf)
+				if err != nil {
+					// The connection got closed due to a cancelled context.
+					if errors.Is(err, net.ErrClosed) && ctx.Err() != nil {
+						return
+					}
+
+					select {
+					case events <- BeaconEvent{Err: fmt.Errorf("Failed to read from multicast network endpoint: %w", err)}:
+					case <-ctx.Done():
+					}
+
+					// Stop browsing using the other connections too.
+					cancel()
+					return
+				}
+
+				rtt := time.Since(time.Unix(0, sent.Load()))
+				beacons, err := parse(buf[:n])
+				if err != nil {
+					logger.Warn("Failed to parse received discovery response", logger.Ctx{"group": c.group.String(), "err": err})
+					continue
+				}
+
+				for _, beacon := range beacons {
+					select {
+					case events <- BeaconEvent{Beacon: beacon, RTT: rtt}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		cancel()
+		close(events)
+	}()
+
+	return events
+}
+
+// reuseAddrControl allows binding to an address and port which is already in use by another socket.
+func reuseAddrControl(network string, address string, c syscall.RawConn) error {
+	var sockErr error
+	err := c.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEADDR, 1)
+		if sockErr != nil {
+			return
+		}
+
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+	})
+	if err != nil {
+		return err
+	}
+
+	return sockErr
+}
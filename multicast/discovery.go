@@ -2,248 +2,667 @@ package multicast
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/rand"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/logger"
-	"golang.org/x/net/ipv4"
+	"github.com/canonical/lxd/shared/trust"
 
 	"github.com/canonical/microcloud/microcloud/api/types"
 )
 
 // ServerInfo is information about the server that is discovered using multicast.
+// It isn't part of the beacons announced on the network and is instead fetched from the server's API.
 type ServerInfo struct {
 	Version     string                       `json:"version"`
+	MinVersion  string                       `json:"min_version,omitempty"`
 	Name        string                       `json:"name,omitempty"`
+	Realm       string                       `json:"realm,omitempty"`
 	Address     string                       `json:"address,omitempty"`
 	Services    map[types.ServiceType]string `json:"services,omitempty"`
-	Certificate *x509.Certificate            `json:"certificates,omitempty"`
+	Fingerprint string                       `json:"fingerprint,omitempty"`
+	Signature   string                       `json:"signature,omitempty"`
+
+	// PassphraseWords and Wordlist describe the passphrase of the server's session.
+	// They allow joiners to validate and complete the words while the passphrase is entered.
+	PassphraseWords uint8  `json:"passphrase_words,omitempty"`
+	Wordlist        string `json:"wordlist,omitempty"`
+
+	// Certificate is the certificate presented by the server when fetching its info.
+	// It's not part of the signed info but has to match the signed fingerprint.
+	Certificate *x509.Certificate `json:"-"`
+}
+
+// Beacon is the small announcement sent by a server during discovery.
+// It contains only what's required to fetch the server's full info from its API.
+// The version is the highest supported version which allows older systems to still parse the beacon.
+// The name is only informational as it isn't authenticated, the signed name is part of the full info.
+// The passphrase settings are announced too as joiners need them before the passphrase is known.
+type Beacon struct {
+	Version         string `json:"version"`
+	MinVersion      string `json:"min_version,omitempty"`
+	Name            string `json:"name,omitempty"`
+	Realm           string `json:"realm,omitempty"`
+	Address         string `json:"address,omitempty"`
+	Port            int64  `json:"port,omitempty"`
+	Nonce           string `json:"nonce,omitempty"`
+	PassphraseWords uint8  `json:"passphrase_words,omitempty"`
+	Wordlist        string `json:"wordlist,omitempty"`
+}
+
+// Versions returns the range of versions supported by the system which sent the beacon.
+func (b Beacon) Versions() VersionRange {
+	return NewVersionRange(b.MinVersion, b.Version)
 }
 
-// Discovery represents the information used for discovering peers using multicast.
+// BeaconEvent is a beacon received by a backend during browsing.
+// RTT is the time between sending the most recent query and receiving the beacon.
+// It's zero if the backend doesn't send queries.
+type BeaconEvent struct {
+	Beacon Beacon
+	RTT    time.Duration
+	Err    error
+}
+
+// Fetcher fetches the full info of the server announced by the given beacon.
+// The given authorization has to be presented to the server when requesting the info.
+// The fetcher is expected to set the info's certificate to the one presented by the server.
+type Fetcher func(ctx context.Context, beacon Beacon, authorization string) (*ServerInfo, error)
+
+// Versions returns the range of versions supported by the system.
+func (i ServerInfo) Versions() VersionRange {
+	return NewVersionRange(i.MinVersion, i.Version)
+}
+
+// Query describes the peers a lookup is interested in.
+// Peers are only considered if they announce the same realm.
+// The name and address of the system looking up peers are sent along with the query if the backend supports it.
+// They are only informational as they aren't authenticated.
+type Query struct {
+	Versions VersionRange
+	Realm    string
+	Name     string
+	Address  string
+
+	// Networks restricts the addresses from which the full info of peers is fetched during lookup.
+	// Any address is allowed if empty.
+	Networks []*net.IPNet
+}
+
+// allows returns whether the full info of a peer can be fetched from the given address.
+func (q Query) allows(address string) bool {
+	if len(q.Networks) == 0 {
+		return true
+	}
+
+	ip := net.ParseIP(address)
+	for _, network := range q.Networks {
+		if ip != nil && network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LookupEvent is a peer reported during lookup.
+// Version is the highest version supported by both the peer and the lookup.
+// Err is set if the peer cannot be used, e.g. because of a VersionMismatchError.
+type LookupEvent struct {
+	Info    ServerInfo
+	Version string
+	Err     error
+}
+
+// VersionMismatchError indicates that a peer found during lookup doesn't support any version supported by the lookup.
+type VersionMismatchError struct {
+	Name      string
+	Versions  VersionRange
+	Supported VersionRange
+}
+
+// Error returns the error message of the version mismatch.
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("System %q supports versions %q which are incompatible with the supported versions %q", e.Name, e.Versions.String(), e.Supported.String())
+}
+
+// Backend is a mechanism used by Discovery to announce the local system and to find other systems on the network.
+type Backend interface {
+	// Respond announces the given beacon until the context is cancelled or the responder is stopped.
+	Respond(ctx context.Context, beacon Beacon) error
+
+	// StopResponder stops announcing the beacon.
+	StopResponder() error
+
+	// Browse queries the network for other systems and reports every received beacon.
+	// Backends which send the query to the other systems allow them to only respond if they match the query.
+	// The channel gets closed once the context is cancelled.
+	// If browsing fails, a final event containing the error is sent before closing the channel.
+	Browse(ctx context.Context, query Query) (<-chan BeaconEvent, error)
+}
+
+// QueryReporter is implemented by backends which receive the beacon of the systems looking up peers.
+type QueryReporter interface {
+	// ReportQueries sets a function which gets called with the beacon of each query received by the responder.
+	ReportQueries(f func(query Beacon))
+}
+
+// maxConcurrentFetches is the number of peers whose info is fetched at the same time during lookup.
+const maxConcurrentFetches = 4
+
+// maxSeenBeacons is the number of beacons remembered during lookup to fetch each peer only once.
+const maxSeenBeacons = 256
+
+// fetchRetryInterval is the minimum time between fetching the info of a peer again after it failed.
+const fetchRetryInterval = 5 * time.Second
+
+// Discovery represents the information used for discovering peers.
 type Discovery struct {
-	iface           string
-	port            int64
-	group           net.IP
-	responderConn   *ipv4.PacketConn
-	responderCancel context.CancelFunc
+	backend Backend
+	signer  trust.HMACFormatter
+	fetch   Fetcher
+	onQuery func(event LookupEvent)
+
+	lock   sync.RWMutex
+	info   *ServerInfo
+	beacon *Beacon
+	nonce  string
 }
 
 // NewDiscovery returns a new instance of Discovery which allows to lookup peers
 // and to respond on multicast queries.
-func NewDiscovery(iface string, port int64) *Discovery {
+// If signer is set, the info is signed and lookups only accept info with a valid signature.
+// The fetcher is used to fetch the full info of peers announced during lookup.
+func NewDiscovery(iface string, port int64, signer trust.HMACFormatter, fetch Fetcher) *Discovery {
+	return NewDiscoveryWithBackend(NewGroupBackend(iface, GroupConfig{Port: port}), signer, fetch)
+}
+
+// NewDiscoveryWithBackend returns a new instance of Discovery which uses the given backend
+// to lookup peers and to respond on queries.
+// If signer is set, the info is signed and lookups only accept info with a valid signature.
+// The fetcher is used to fetch the full info of peers announced during lookup.
+func NewDiscoveryWithBackend(backend Backend, signer trust.HMACFormatter, fetch Fetcher) *Discovery {
 	return &Discovery{
-		iface: iface,
-		port:  port,
-		// This uses an address of the organization-local scope which isn't reserved for any public protocol.
-		// See https://www.iana.org/assignments/multicast-addresses/multicast-addresses.xhtml#multicast-addresses-12.
-		group: net.IPv4(239, 100, 100, 100),
+		backend: backend,
+		signer:  signer,
+		fetch:   fetch,
 	}
 }
 
-// Respond starts a new server that listens for datagrams on the configured multicast group
-// and sends the given info in response until the context is cancelled.
-func (d *Discovery) Respond(ctx context.Context, info ServerInfo) error {
-	iface, err := net.InterfaceByName(d.iface)
+// Respond announces a beacon for the given info using the backend until the context is cancelled.
+// The port is the port of the API serving the full info, see Info.
+func (d *Discovery) Respond(ctx context.Context, info ServerInfo, port int64) error {
+	if d.signer != nil {
+		var err error
+		info.Signature, err = d.signature(info)
+		if err != nil {
+			return fmt.Errorf("Failed to sign server info: %w", err)
+		}
+	}
+
+	// The nonce identifies this responder and prevents fetching the info of a previous one.
+	nonceBytes := make([]byte, 16)
+	_, err := rand.Read(nonceBytes)
 	if err != nil {
-		return fmt.Errorf("Failed to resolve server interface %q: %w", d.iface, err)
+		return fmt.Errorf("Failed to generate nonce: %w", err)
+	}
+
+	nonce := hex.EncodeToString(nonceBytes)
+
+	beacon := Beacon{
+		Version:         info.Version,
+		MinVersion:      info.MinVersion,
+		Name:            info.Name,
+		Realm:           info.Realm,
+		Address:         info.Address,
+		Port:            port,
+		Nonce:           nonce,
+		PassphraseWords: info.PassphraseWords,
+		Wordlist:        info.Wordlist,
+	}
+
+	d.lock.Lock()
+	d.info = &info
+	d.beacon = &beacon
+	d.nonce = nonce
+	d.lock.Unlock()
+
+	reporter, ok := d.backend.(QueryReporter)
+	if ok && d.onQuery != nil {
+		reporter.ReportQueries(func(query Beacon) {
+			d.onQuery(queryEvent(beacon, query))
+		})
 	}
 
-	// The PacketConn gets closed when calling Close on the derived IPv4 PacketConn.
-	receiver, err := net.ListenPacket("udp4", fmt.Sprintf(":%d", d.port))
+	return d.backend.Respond(ctx, beacon)
+}
+
+// ReportQueries sets a function which gets called for each system looking up peers while responding.
+// The event contains the system's versions as well as its name and address as sent along with its query.
+// Its error is set if the system doesn't support any of the responder's versions.
+// Queries are only reported by backends implementing QueryReporter.
+func (d *Discovery) ReportQueries(f func(event LookupEvent)) {
+	d.onQuery = f
+}
+
+// queryEvent returns the event reporting the system which sent the given query to the responder announcing the given beacon.
+func queryEvent(beacon Beacon, query Beacon) LookupEvent {
+	event := LookupEvent{
+		Info: ServerInfo{
+			Version:    query.Version,
+			MinVersion: query.MinVersion,
+			Name:       query.Name,
+			Address:    query.Address,
+		},
+	}
+
+	var err error
+	event.Version, err = beacon.Versions().Negotiate(query.Versions())
 	if err != nil {
-		return fmt.Errorf("Failed to listen on %d: %w", d.port, err)
+		event.Err = &VersionMismatchError{Name: query.Name, Versions: query.Versions(), Supported: beacon.Versions()}
+	}
+
+	return event
+}
+
+// StopResponder stops the responder of the backend.
+func (d *Discovery) StopResponder() error {
+	return d.backend.StopResponder()
+}
+
+// Beacon returns the beacon announced by the responder.
+// It allows backends without multicast to fetch the beacon from the responder's API.
+func (d *Discovery) Beacon() (*Beacon, error) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	if d.beacon == nil {
+		return nil, errors.New("No active responder")
+	}
+
+	beacon := *d.beacon
+
+	return &beacon, nil
+}
+
+// Info returns the full info announced by the responder for the given nonce.
+// If the info is signed, the authorization derived from the nonce has to be valid too.
+func (d *Discovery) Info(nonce string, authorization string) (*ServerInfo, error) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	if d.info == nil {
+		return nil, errors.New("No active responder")
+	}
+
+	if nonce != d.nonce {
+		return nil, errors.New("Invalid nonce")
 	}
 
-	ctx, d.responderCancel = context.WithCancel(ctx)
-	d.responderConn = ipv4.NewPacketConn(receiver)
-	err = d.responderConn.JoinGroup(iface, &net.UDPAddr{IP: d.group})
+	if d.signer != nil {
+		expected, err := d.authorization(nonce)
+		if err != nil {
+			return nil, err
+		}
+
+		if !hmac.Equal([]byte(expected), []byte(authorization)) {
+			return nil, errors.New("Invalid authorization")
+		}
+	}
+
+	info := *d.info
+
+	return &info, nil
+}
+
+// Lookup finds a listening peer matching the given query and returns its info.
+// Peers without any version in common are skipped.
+func (d *Discovery) Lookup(ctx context.Context, query Query) (*ServerInfo, error) {
+	// Stop the lookup as soon as the first eligible peer is found.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events, err := d.LookupAll(ctx, query)
 	if err != nil {
-		return fmt.Errorf("Failed to join multicast group %q: %w", d.group.String(), err)
+		return nil, err
+	}
+
+	for event := range events {
+		if event.Err != nil {
+			var mismatchErr *VersionMismatchError
+			if errors.As(event.Err, &mismatchErr) {
+				logger.Warn("Skipping system with mismatched version", logger.Ctx{"err": event.Err})
+				continue
+			}
+
+			return nil, event.Err
+		}
+
+		return &event.Info, nil
 	}
 
-	err = d.responderConn.SetControlMessage(ipv4.FlagDst, true)
+	return nil, fmt.Errorf("Failed to look up peers: %w", context.Cause(ctx))
+}
+
+// Probe finds all listening peers of the query's realm and networks and reports the beacon of each of them once on the returned channel.
+// Unlike LookupAll, the full info of the peers isn't fetched which allows probing the network without
+// knowing the passphrase of any session.
+// The channel gets closed once the context is cancelled or the backend fails to browse the network.
+func (d *Discovery) Probe(ctx context.Context, query Query) (<-chan BeaconEvent, error) {
+	beacons, err := d.backend.Browse(ctx, query)
 	if err != nil {
-		return fmt.Errorf("Failed to set IPv4 control flag for destination address: %w", err)
+		return nil, err
 	}
 
-	// Close the network endpoint if the outer context got cancelled.
-	// This allows existing the endpoint's blocking read using ReadFrom.
+	events := make(chan BeaconEvent)
+
 	go func() {
-		<-ctx.Done()
-		err := d.responderConn.Close()
-		if err != nil {
-			logger.Error("Failed to close network endpoint after context got cancelled", logger.Ctx{"err": err})
+		defer close(events)
+
+		seen := make(map[string]bool)
+		for event := range beacons {
+			if event.Err == nil {
+				key := beaconKey(event.Beacon)
+				if seen[key] || event.Beacon.Realm != query.Realm || !query.allows(event.Beacon.Address) {
+					continue
+				}
+
+				seen[key] = true
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
 		}
 	}()
 
-	// Respond on received multicast datagrams.
-	// The routine exits if the connection gets closed.
+	return events, nil
+}
+
+// LookupAll finds all listening peers of the query's realm and reports each of them once on the returned channel.
+// The full info of each peer is fetched from the API announced in its beacon.
+// The info of several peers is fetched concurrently so that peers which don't respond don't stall the lookup.
+// If fetching fails, the peer is fetched again once it's announced again after fetchRetryInterval.
+// Each peer is reported together with the highest version supported by both sides.
+// A peer without any version in common with the query is reported with a VersionMismatchError.
+// The channel gets closed once the context is cancelled or the backend fails to browse the network.
+func (d *Discovery) LookupAll(ctx context.Context, query Query) (<-chan LookupEvent, error) {
+	if d.fetch == nil {
+		return nil, errors.New("Cannot lookup peers without a fetcher")
+	}
+
+	beacons, err := d.backend.Browse(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan LookupEvent)
+
 	go func() {
-		for {
-			// See the comment on the sender (lookup) for the reasoning about using 500.
-			b := make([]byte, 500)
-			n, cm, src, err := d.responderConn.ReadFrom(b)
-			if err != nil {
-				// Ignore "use of closed network connection" errors as this happens normally
-				// if the outer context gets cancelled in the connection closer go routine.
-				if !errors.Is(err, net.ErrClosed) {
-					logger.Error("Failed to read from network endpoint", logger.Ctx{"err": err})
+		wg := sync.WaitGroup{}
+		defer func() {
+			wg.Wait()
+			close(events)
+		}()
+
+		workers := make(chan struct{}, maxConcurrentFetches)
+		tracker := newBeaconTracker(maxSeenBeacons)
+		for beaconEvent := range beacons {
+			if beaconEvent.Err != nil {
+				select {
+				case events <- LookupEvent{Err: beaconEvent.Err}:
+				case <-ctx.Done():
+					return
 				}
 
-				return
+				continue
 			}
 
-			receivedInfo := ServerInfo{}
+			beacon := beaconEvent.Beacon
 
-			// Reslice the byte slice with the actual amount of bytes read from the datagram.
-			err = json.Unmarshal(b[:n], &receivedInfo)
-			if err != nil {
-				logger.Error("Failed to parse received multicast server info", logger.Ctx{"err": err})
+			// Not every backend allows peers to filter the queries by realm.
+			if beacon.Realm != query.Realm {
 				continue
 			}
 
-			// Don't respond on this request as the peer is using a different version.
-			if receivedInfo.Version != info.Version {
-				logger.Warnf("Don't respond to multicast server info from %q as its using version %q", src.String(), receivedInfo.Version)
+			// Fetching the info reveals an HMAC derived from the passphrase which allows guessing it offline.
+			// So don't let anyone sending beacons direct the request to an address outside of the chosen networks.
+			if !query.allows(beacon.Address) {
+				logger.Debug("Dropping discovered system outside of the lookup networks", logger.Ctx{"address": beacon.Address})
 				continue
 			}
 
-			if cm.Dst.IsMulticast() {
-				if cm.Dst.Equal(d.group) {
-					bytes, err := json.Marshal(info)
-					if err != nil {
-						logger.Error("Failed to marshal server info", logger.Ctx{"err": err})
-						continue
-					}
-
-					// Send a unicast message back to the source.
-					_, err = d.responderConn.WriteTo(bytes, nil, src)
-					if err != nil {
-						logger.Error("Failed to send reply", logger.Ctx{"dest": src.String(), "err": err})
-						continue
-					}
-				} else {
-					logger.Warnf("Received multicast message from non recognized group %q", cm.Dst.String())
-				}
+			// Fetch each peer only once as the backends query the network repeatedly.
+			// A new nonce is used whenever a peer starts responding again.
+			key := beaconKey(beacon)
+			if !tracker.start(key) {
+				continue
 			}
+
+			// Skip the beacon if all workers are busy, the backend reports it again after its next query.
+			select {
+			case workers <- struct{}{}:
+			default:
+				tracker.finish(key, false)
+				continue
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-workers }()
+
+				event, err := d.lookupEvent(ctx, query, beacon)
+				tracker.finish(key, err == nil)
+				if err != nil {
+					logger.Debug("Dropping discovered system", logger.Ctx{"address": beacon.Address, "err": err})
+					return
+				}
+
+				select {
+				case events <- *event:
+				case <-ctx.Done():
+				}
+			}()
 		}
 	}()
 
-	return nil
+	return events, nil
 }
 
-// StopResponder stops the responder server and cancels it's inner context.
-func (d *Discovery) StopResponder() error {
-	// Check if this instance of discovery has an active responder server connection.
-	if d.responderConn != nil {
-		err := d.responderConn.Close()
-		// Ignore errors if the connection is already closed.
-		// This can happen if the responders context already got cancelled
-		// which also triggers a close of the connection.
-		if err != nil && !errors.Is(err, net.ErrClosed) {
-			return fmt.Errorf("Failed to stop responder: %w", err)
-		}
-
-		// Cancel the inner context too and release all routines of the responder.
-		if d.responderCancel != nil {
-			d.responderCancel()
-		}
+// LookupBeacon fetches the full info of the peer announced by the given beacon, e.g. a beacon reported by Probe.
+// This allows resolving a peer found earlier without looking it up again.
+// The peer is reported together with the highest version supported by both sides.
+// A peer without any version in common with the query is reported with a VersionMismatchError.
+func (d *Discovery) LookupBeacon(ctx context.Context, query Query, beacon Beacon) (*LookupEvent, error) {
+	if d.fetch == nil {
+		return nil, errors.New("Cannot lookup peers without a fetcher")
 	}
 
-	return nil
+	return d.lookupEvent(ctx, query, beacon)
 }
 
-// Lookup finds a listening peer matching the given version and returns its info.
-func (d *Discovery) Lookup(ctx context.Context, version string) (*ServerInfo, error) {
-	// Use a random port for sending the multicast message.
-	// The PacketConn gets closed when calling Close on the derived IPv4 PacketConn.
-	sender, err := net.ListenPacket("udp4", ":0")
+// lookupEvent fetches the full info of the peer announced by the given beacon and returns the event reporting it.
+// An error is returned if the peer cannot be authenticated or doesn't belong to the query's realm.
+func (d *Discovery) lookupEvent(ctx context.Context, query Query, beacon Beacon) (*LookupEvent, error) {
+	// Silently drop peers which cannot be authenticated as anyone on the network can send beacons.
+	info, err := d.fetchInfo(ctx, beacon)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to listen: %w", err)
+		return nil, err
 	}
 
-	iface, err := net.InterfaceByName(d.iface)
-	if err != nil {
-		return nil, fmt.Errorf("Failed to resolve lookup interface %q: %w", d.iface, err)
+	// The realm of the beacon isn't authenticated, so check the realm of the signed info too.
+	if info.Realm != query.Realm {
+		return nil, fmt.Errorf("System belongs to a different realm %q", info.Realm)
 	}
 
-	senderP := ipv4.NewPacketConn(sender)
-	err = senderP.SetMulticastInterface(iface)
+	event := LookupEvent{Info: *info}
+	event.Version, err = query.Versions.Negotiate(info.Versions())
 	if err != nil {
-		return nil, fmt.Errorf("Failed to set multicast interface %q: %w", iface.Name, err)
+		event.Err = &VersionMismatchError{Name: info.Name, Versions: info.Versions(), Supported: query.Versions}
 	}
 
-	lookupInfo := ServerInfo{
-		Version: version,
+	return &event, nil
+}
+
+// beaconTracker tracks the beacons handled during lookup.
+// Its size is limited as anyone on the network can send beacons.
+type beaconTracker struct {
+	lock    sync.Mutex
+	limit   int
+	seen    map[string]bool
+	order   []string
+	pending map[string]bool
+	failed  map[string]time.Time
+}
+
+// newBeaconTracker returns a new beaconTracker which remembers up to limit beacons.
+func newBeaconTracker(limit int) *beaconTracker {
+	return &beaconTracker{
+		limit:   limit,
+		seen:    make(map[string]bool, limit),
+		order:   make([]string, 0, limit),
+		pending: make(map[string]bool),
+		failed:  make(map[string]time.Time),
 	}
+}
 
-	lookupInfoBytes, err := json.Marshal(lookupInfo)
-	if err != nil {
-		return nil, fmt.Errorf("Failed to marshal lookup info: %w", err)
+// start returns whether the peer of the beacon with the given key should be fetched.
+// This is not the case if it was already fetched, is currently fetched or failed recently.
+func (t *beaconTracker) start(key string) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.seen[key] || t.pending[key] {
+		return false
 	}
 
-	go func() {
-		dst := &net.UDPAddr{IP: d.group, Port: int(d.port)}
+	failedAt, ok := t.failed[key]
+	if ok && time.Since(failedAt) < fetchRetryInterval {
+		return false
+	}
 
-		for {
-			select {
-			case <-ctx.Done():
-				// Close the network endpoint if the lookup context got cancelled.
-				senderP.Close()
-				return
-			default:
-				// Repeatedly send multicast message with our lookup info containing only our protocol version.
-				// The response contains the name, address and version which we use to validate if we want to join this peer.
-				_, err = senderP.WriteTo(lookupInfoBytes, nil, dst)
-				if err != nil {
-					logger.Error("Failed to send multicast message", logger.Ctx{"err": err})
-				}
+	t.pending[key] = true
 
-				time.Sleep(time.Second)
-			}
+	return true
+}
+
+// finish marks the fetch of the peer of the beacon with the given key as done.
+// Only successfully fetched peers are remembered as seen, evicting the oldest one if the limit is reached.
+func (t *beaconTracker) finish(key string, success bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.pending, key)
+
+	if !success {
+		// Forget about all failures rather than growing beyond the limit.
+		if len(t.failed) >= t.limit {
+			clear(t.failed)
 		}
-	}()
 
-	// 500 bytes should always make it through the network regardless of the MTU setting
-	// as Internet Protocol requires hosts to be able to process datagrams of at least 576 bytes.
-	// Subtracting the maximum IP header of size 60 bytes and the UDP header of size 8 bytes we are
-	// left with 508 bytes for the actual payload.
-	// We expect a response that contains the name, address and version.
-	// As the name correlates to the peers hostname, 255 may be occupied by it which leaves another
-	// 245 bytes for the address (IPv4 or IPv6) and used multicast discovery version (including some JSON formatting).
-	b := make([]byte, 500)
-
-	// Block until the read succeeds or the connection is closed.
-	// The latter happens in case the context gets cancelled.
-	n, _, _, err := senderP.ReadFrom(b)
+		t.failed[key] = time.Now()
+		return
+	}
+
+	delete(t.failed, key)
+
+	if len(t.order) >= t.limit {
+		delete(t.seen, t.order[0])
+		t.order = t.order[1:]
+	}
+
+	t.seen[key] = true
+	t.order = append(t.order, key)
+}
+
+// beaconKey returns the key identifying the responder which sent the given beacon.
+func beaconKey(beacon Beacon) string {
+	return beacon.Address + "/" + strconv.FormatInt(beacon.Port, 10) + "/" + beacon.Nonce
+}
+
+// fetchInfo fetches and verifies the full info of the peer announced by the given beacon.
+func (d *Discovery) fetchInfo(ctx context.Context, beacon Beacon) (*ServerInfo, error) {
+	var authorization string
+	if d.signer != nil {
+		var err error
+		authorization, err = d.authorization(beacon.Nonce)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	info, err := d.fetch(fetchCtx, beacon, authorization)
 	if err != nil {
-		// In case the connection got closed due to a cancelled context,
-		// try to return the cause from the context instead.
-		ctxErr := context.Cause(ctx)
-		if errors.Is(err, net.ErrClosed) && ctxErr != nil {
-			err = ctxErr
+		return nil, fmt.Errorf("Failed to fetch server info: %w", err)
+	}
+
+	if d.signer != nil {
+		err := d.verify(*info)
+		if err != nil {
+			return nil, err
 		}
+	}
+
+	if info.Certificate != nil && shared.CertFingerprint(info.Certificate) != info.Fingerprint {
+		return nil, errors.New("Certificate doesn't match the fingerprint")
+	}
 
-		return nil, fmt.Errorf("Failed to read from multicast network endpoint: %w", err)
+	return info, nil
+}
+
+// authorization returns the HMAC of the given nonce used to authorize fetching the full info.
+func (d *Discovery) authorization(nonce string) (string, error) {
+	return Authorization(d.signer, nonce)
+}
+
+// Authorization returns the HMAC of the given beacon nonce using the given signer.
+// It proves the knowledge of the session's passphrase to the system which announced the beacon.
+func Authorization(signer trust.HMACFormatter, nonce string) (string, error) {
+	return trust.HMACAuthorizationHeader(signer, nonce)
+}
+
+// signature returns the HMAC of the given info without its signature.
+// The raw JSON payload gets signed so that both the responder and the lookup derive the HMAC from the same bytes.
+func (d *Discovery) signature(info ServerInfo) (string, error) {
+	info.Signature = ""
+	payload, err := json.Marshal(info)
+	if err != nil {
+		return "", fmt.Errorf("Failed to marshal server info: %w", err)
 	}
 
-	receivedInfo := ServerInfo{}
+	return trust.HMACAuthorizationHeader(d.signer, json.RawMessage(payload))
+}
 
-	// Reslice the byte slice with the actual amount of bytes read from the datagram.
-	err = json.Unmarshal(b[:n], &receivedInfo)
+// verify checks whether the signature of the given info is valid.
+func (d *Discovery) verify(info ServerInfo) error {
+	if info.Signature == "" {
+		return errors.New("Missing signature")
+	}
+
+	expected, err := d.signature(info)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to parse received multicast server info: %w", err)
+		return err
 	}
 
-	// Exit if peer has mismatched version.
-	if receivedInfo.Version != version {
-		return nil, fmt.Errorf("System %q (version %q) has a version mismatch: Expected %q", receivedInfo.Name, receivedInfo.Version, version)
+	if !hmac.Equal([]byte(expected), []byte(info.Signature)) {
+		return errors.New("Invalid signature")
 	}
 
-	return &receivedInfo, nil
+	return nil
 }
@@ -2,12 +2,18 @@ package multicast
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"net"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/canonical/lxd/shared/trust"
 	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/canonical/microcloud/microcloud/api/types"
 )
 
 type multicastSuite struct {
@@ -18,12 +24,21 @@ func TestMulticastSuite(t *testing.T) {
 	suite.Run(t, new(multicastSuite))
 }
 
+// fetchFrom returns a fetcher which gets the info directly from the given responder instead of its API.
+func fetchFrom(responder *Discovery) Fetcher {
+	return func(ctx context.Context, beacon Beacon, authorization string) (*ServerInfo, error) {
+		return responder.Info(beacon.Nonce, authorization)
+	}
+}
+
 func (m *multicastSuite) Test_Lookup() {
 	cases := []struct {
 		desc          string
 		lookupVersion string
+		lookupRealm   string
 		lookupIface   string
 		lookupPort    int64
+		lookupNetwork string
 		responseInfo  ServerInfo
 		lookupErr     error
 		lookupTimeout time.Duration
@@ -41,14 +56,16 @@ func (m *multicastSuite) Test_Lookup() {
 			},
 		},
 		{
-			desc:          "System with maximum allowed server name length, IPv6 address and high version number can be looked up",
+			desc:          "System with maximum allowed server name length, services, IPv6 address and high version number can be looked up",
 			lookupVersion: "142.0",
 			lookupIface:   "lo",
 			lookupPort:    9444,
 			responseInfo: ServerInfo{
-				Version: "142.0",
-				Name:    strings.Repeat("a", 255),
-				Address: "fd42:c4cc:2e1d:132d:a216:3eff:fecd:9d15",
+				Version:     "142.0",
+				Name:        strings.Repeat("a", 255),
+				Address:     "fd42:c4cc:2e1d:132d:a216:3eff:fecd:9d15",
+				Services:    map[types.ServiceType]string{types.MicroCloud: "2.1.0", types.LXD: "5.21.3", types.MicroCeph: "19.2.0", types.MicroOVN: "24.03.2"},
+				Fingerprint: strings.Repeat("f", 64),
 			},
 		},
 		{
@@ -70,7 +87,7 @@ func (m *multicastSuite) Test_Lookup() {
 			modifier: func(server *Discovery) {
 				_ = server.StopResponder()
 			},
-			lookupErr: errors.New("Failed to read from multicast network endpoint: Timeout exceeded"),
+			lookupErr: errors.New("Failed to look up peers: Timeout exceeded"),
 		},
 		{
 			desc:          "Cannot lookup system if the responder uses a different version",
@@ -83,7 +100,61 @@ func (m *multicastSuite) Test_Lookup() {
 				Address: "1.2.3.4",
 			},
 			lookupTimeout: 500 * time.Microsecond,
-			lookupErr:     errors.New("Failed to read from multicast network endpoint: Timeout exceeded"),
+			lookupErr:     errors.New("Failed to look up peers: Timeout exceeded"),
+		},
+		{
+			desc:          "System of the same realm can be looked up",
+			lookupVersion: "2.0",
+			lookupRealm:   "production",
+			lookupIface:   "lo",
+			lookupPort:    9444,
+			responseInfo: ServerInfo{
+				Version: "2.0",
+				Name:    "foo",
+				Realm:   "production",
+				Address: "1.2.3.4",
+			},
+		},
+		{
+			desc:          "Cannot lookup system if the responder uses a different realm",
+			lookupVersion: "2.0",
+			lookupRealm:   "staging",
+			lookupIface:   "lo",
+			lookupPort:    9444,
+			responseInfo: ServerInfo{
+				Version: "2.0",
+				Name:    "foo",
+				Realm:   "production",
+				Address: "1.2.3.4",
+			},
+			lookupTimeout: 500 * time.Microsecond,
+			lookupErr:     errors.New("Failed to look up peers: Timeout exceeded"),
+		},
+		{
+			desc:          "System within the lookup networks can be looked up",
+			lookupVersion: "2.0",
+			lookupIface:   "lo",
+			lookupPort:    9444,
+			lookupNetwork: "1.2.3.0/24",
+			responseInfo: ServerInfo{
+				Version: "2.0",
+				Name:    "foo",
+				Address: "1.2.3.4",
+			},
+		},
+		{
+			desc:          "Cannot lookup system outside of the lookup networks",
+			lookupVersion: "2.0",
+			lookupIface:   "lo",
+			lookupPort:    9444,
+			lookupNetwork: "10.0.0.0/8",
+			responseInfo: ServerInfo{
+				Version: "2.0",
+				Name:    "foo",
+				Address: "1.2.3.4",
+			},
+			lookupTimeout: 1500 * time.Millisecond,
+			lookupErr:     errors.New("Failed to look up peers: Timeout exceeded"),
 		},
 	}
 
@@ -91,16 +162,16 @@ func (m *multicastSuite) Test_Lookup() {
 		m.T().Log(c.desc)
 
 		// Use the loopback interface as it should always be there on any test system.
-		discovery := NewDiscovery("lo", 9444)
+		discovery := NewDiscovery("lo", 9444, nil, nil)
 
-		err := discovery.Respond(context.Background(), c.responseInfo)
+		err := discovery.Respond(context.Background(), c.responseInfo, 9443)
 		m.Require().NoError(err)
 
 		if c.modifier != nil {
 			c.modifier(discovery)
 		}
 
-		testDiscovery := NewDiscovery(c.lookupIface, c.lookupPort)
+		testDiscovery := NewDiscovery(c.lookupIface, c.lookupPort, nil, fetchFrom(discovery))
 
 		ctx := context.Background()
 		var cancel context.CancelFunc
@@ -108,7 +179,14 @@ func (m *multicastSuite) Test_Lookup() {
 			ctx, cancel = context.WithTimeoutCause(ctx, c.lookupTimeout, errors.New("Timeout exceeded"))
 		}
 
-		receivedInfo, err := testDiscovery.Lookup(ctx, c.lookupVersion)
+		query := Query{Versions: VersionRange{Min: c.lookupVersion, Max: c.lookupVersion}, Realm: c.lookupRealm}
+		if c.lookupNetwork != "" {
+			_, network, err := net.ParseCIDR(c.lookupNetwork)
+			m.Require().NoError(err)
+			query.Networks = []*net.IPNet{network}
+		}
+
+		receivedInfo, err := testDiscovery.Lookup(ctx, query)
 		if c.lookupErr == nil {
 			m.Require().NoError(err)
 			m.Require().Equal(&c.responseInfo, receivedInfo)
@@ -127,3 +205,499 @@ func (m *multicastSuite) Test_Lookup() {
 		m.Require().NoError(err)
 	}
 }
+
+func (m *multicastSuite) Test_networksForAddrs() {
+	cases := []struct {
+		desc      string
+		addrs     []net.Addr
+		networks  []string
+		expectErr bool
+	}{
+		{
+			desc:     "IPv4 is used on IPv4 only interfaces",
+			addrs:    []net.Addr{&net.IPNet{IP: net.ParseIP("10.0.0.1"), Mask: net.CIDRMask(24, 32)}},
+			networks: []string{"udp4"},
+		},
+		{
+			desc: "Both IPv4 and IPv6 are used on dual stack interfaces",
+			addrs: []net.Addr{
+				&net.IPNet{IP: net.ParseIP("fd42::1"), Mask: net.CIDRMask(64, 128)},
+				&net.IPNet{IP: net.ParseIP("10.0.0.1"), Mask: net.CIDRMask(24, 32)},
+			},
+			networks: []string{"udp4", "udp6"},
+		},
+		{
+			desc: "IPv6 is used on IPv6 only interfaces",
+			addrs: []net.Addr{
+				&net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)},
+				&net.IPNet{IP: net.ParseIP("fd42::1"), Mask: net.CIDRMask(64, 128)},
+			},
+			networks: []string{"udp6"},
+		},
+		{
+			desc:      "Interfaces without addresses cannot be used",
+			addrs:     []net.Addr{},
+			expectErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		m.T().Log(c.desc)
+
+		networks, err := networksForAddrs(c.addrs)
+		if c.expectErr {
+			m.Require().Error(err)
+		} else {
+			m.Require().NoError(err)
+			m.Require().Equal(c.networks, networks)
+		}
+	}
+}
+
+func (m *multicastSuite) Test_LookupAll() {
+	cases := []struct {
+		desc           string
+		lookupVersions VersionRange
+		responseInfo   ServerInfo
+		expectVersion  string
+		expectErr      error
+	}{
+		{
+			desc:           "System with matching version is reported",
+			lookupVersions: VersionRange{Min: "2.0", Max: "2.0"},
+			responseInfo: ServerInfo{
+				Version: "2.0",
+				Name:    "foo",
+				Address: "1.2.3.4",
+			},
+			expectVersion: "2.0",
+		},
+		{
+			desc:           "System supporting an older version is reported with the highest common version",
+			lookupVersions: VersionRange{Min: "2.0", Max: "2.2"},
+			responseInfo: ServerInfo{
+				Version:    "2.1",
+				MinVersion: "1.0",
+				Name:       "foo",
+				Address:    "1.2.3.4",
+			},
+			expectVersion: "2.1",
+		},
+		{
+			desc:           "System with mismatched version is reported with an error",
+			lookupVersions: VersionRange{Min: "3.0", Max: "3.1"},
+			responseInfo: ServerInfo{
+				Version: "2.0",
+				Name:    "foo",
+				Address: "1.2.3.4",
+			},
+			expectErr: &VersionMismatchError{Name: "foo", Versions: VersionRange{Min: "2.0", Max: "2.0"}, Supported: VersionRange{Min: "3.0", Max: "3.1"}},
+		},
+	}
+
+	for _, c := range cases {
+		m.T().Log(c.desc)
+
+		// Use the loopback interface as it should always be there on any test system.
+		discovery := NewDiscovery("lo", 9444, nil, nil)
+
+		// The responder reports the system looking it up regardless of the versions.
+		queries := make(chan LookupEvent, 10)
+		discovery.ReportQueries(func(event LookupEvent) {
+			select {
+			case queries <- event:
+			default:
+			}
+		})
+
+		err := discovery.Respond(context.Background(), c.responseInfo, 9443)
+		m.Require().NoError(err)
+
+		// Allow for multiple lookup messages to be sent to check that the system is reported only once.
+		ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+
+		events, err := NewDiscovery("lo", 9444, nil, fetchFrom(discovery)).LookupAll(ctx, Query{Versions: c.lookupVersions, Name: "bar", Address: "5.6.7.8"})
+		m.Require().NoError(err)
+
+		received := []LookupEvent{}
+		for event := range events {
+			received = append(received, event)
+		}
+
+		m.Require().Len(received, 1)
+		m.Require().Equal(c.responseInfo, received[0].Info)
+		m.Require().Equal(c.expectVersion, received[0].Version)
+		m.Require().Equal(c.expectErr, received[0].Err)
+
+		m.Require().NotEmpty(queries)
+		query := <-queries
+		m.Require().Equal("bar", query.Info.Name)
+		m.Require().Equal("5.6.7.8", query.Info.Address)
+		m.Require().Equal(c.expectVersion, query.Version)
+		m.Require().Equal(c.expectErr != nil, query.Err != nil)
+
+		cancel()
+
+		// Stop the responder.
+		err = discovery.StopResponder()
+		m.Require().NoError(err)
+	}
+}
+
+func (m *multicastSuite) Test_LookupSigned() {
+	newHMAC := func(passphrase string) trust.HMACFormatter {
+		h, err := trust.NewHMACArgon2([]byte(passphrase), []byte("salt"), trust.NewDefaultHMACConf("test"))
+		m.Require().NoError(err)
+
+		return h
+	}
+
+	cases := []struct {
+		desc            string
+		responderSigner trust.HMACFormatter
+		lookupSigner    trust.HMACFormatter
+		expectFound     bool
+	}{
+		{
+			desc:            "System with valid signature can be looked up",
+			responderSigner: newHMAC("foo"),
+			lookupSigner:    newHMAC("foo"),
+			expectFound:     true,
+		},
+		{
+			desc:            "System signed using a different passphrase is ignored",
+			responderSigner: newHMAC("bar"),
+			lookupSigner:    newHMAC("foo"),
+		},
+		{
+			desc:         "System without signature is ignored",
+			lookupSigner: newHMAC("foo"),
+		},
+	}
+
+	for _, c := range cases {
+		m.T().Log(c.desc)
+
+		responseInfo := ServerInfo{
+			Version: "2.0",
+			Name:    "foo",
+			Address: "1.2.3.4",
+		}
+
+		// Use the loopback interface as it should always be there on any test system.
+		discovery := NewDiscovery("lo", 9444, c.responderSigner, nil)
+
+		err := discovery.Respond(context.Background(), responseInfo, 9443)
+		m.Require().NoError(err)
+
+		ctx, cancel := context.WithTimeoutCause(context.Background(), 2*time.Second, errors.New("Timeout exceeded"))
+
+		receivedInfo, err := NewDiscovery("lo", 9444, c.lookupSigner, fetchFrom(discovery)).Lookup(ctx, Query{Versions: VersionRange{Min: "2.0", Max: "2.0"}})
+		if c.expectFound {
+			m.Require().NoError(err)
+			m.Require().NotEmpty(receivedInfo.Signature)
+			m.Require().Equal(responseInfo.Name, receivedInfo.Name)
+			m.Require().Equal(responseInfo.Address, receivedInfo.Address)
+		} else {
+			m.Require().Error(err)
+			m.Require().Equal("Failed to look up peers: Timeout exceeded", err.Error())
+		}
+
+		cancel()
+
+		// Stop the responder.
+		err = discovery.StopResponder()
+		m.Require().NoError(err)
+	}
+}
+
+func (m *multicastSuite) Test_Probe() {
+	// Use the loopback interface as it should always be there on any test system.
+	discovery := NewDiscovery("lo", 9444, nil, nil)
+
+	err := discovery.Respond(context.Background(), ServerInfo{Version: "2.0", Name: "foo", Address: "1.2.3.4", PassphraseWords: 6, Wordlist: "custom"}, 9443)
+	m.Require().NoError(err)
+
+	// Allow the responder to start.
+	time.Sleep(100 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+
+	// Probing doesn't require a fetcher as only the beacons are reported.
+	events, err := NewDiscovery("lo", 9444, nil, nil).Probe(ctx, Query{Versions: SupportedVersions})
+	m.Require().NoError(err)
+
+	received := []BeaconEvent{}
+	for event := range events {
+		received = append(received, event)
+	}
+
+	m.Require().NoError(discovery.StopResponder())
+
+	// The peer answers every query but is only reported once.
+	m.Require().Len(received, 1)
+	m.Require().NoError(received[0].Err)
+	m.Require().Equal("foo", received[0].Beacon.Name)
+	m.Require().Equal("1.2.3.4", received[0].Beacon.Address)
+	m.Require().Equal(int64(9443), received[0].Beacon.Port)
+	m.Require().Equal(uint8(6), received[0].Beacon.PassphraseWords)
+	m.Require().Equal("custom", received[0].Beacon.Wordlist)
+	m.Require().Positive(received[0].RTT)
+}
+
+func (m *multicastSuite) Test_LookupBeacon() {
+	signer, err := trust.NewHMACArgon2([]byte("foo"), []byte("salt"), trust.NewDefaultHMACConf("test"))
+	m.Require().NoError(err)
+
+	// Use the loopback interface as it should always be there on any test system.
+	discovery := NewDiscovery("lo", 9444, signer, nil)
+
+	err = discovery.Respond(context.Background(), ServerInfo{Version: "2.0", Name: "foo", Address: "1.2.3.4"}, 9443)
+	m.Require().NoError(err)
+
+	defer func() { m.Require().NoError(discovery.StopResponder()) }()
+
+	beacon, err := discovery.Beacon()
+	m.Require().NoError(err)
+
+	// The peer found earlier is resolved without browsing the network again.
+	query := Query{Versions: VersionRange{Min: "2.0", Max: "2.0"}}
+	event, err := NewDiscovery("lo", 9444, signer, fetchFrom(discovery)).LookupBeacon(context.Background(), query, *beacon)
+	m.Require().NoError(err)
+	m.Require().NoError(event.Err)
+	m.Require().Equal("2.0", event.Version)
+	m.Require().Equal("foo", event.Info.Name)
+	m.Require().NotEmpty(event.Info.Signature)
+
+	// A peer without any common version is reported with an error.
+	event, err = NewDiscovery("lo", 9444, signer, fetchFrom(discovery)).LookupBeacon(context.Background(), Query{Versions: VersionRange{Min: "3.0", Max: "3.0"}}, *beacon)
+	m.Require().NoError(err)
+	m.Require().Equal(&VersionMismatchError{Name: "foo", Versions: VersionRange{Min: "2.0", Max: "2.0"}, Supported: VersionRange{Min: "3.0", Max: "3.0"}}, event.Err)
+
+	// Fetching the info fails without knowing the passphrase.
+	otherSigner, err := trust.NewHMACArgon2([]byte("bar"), []byte("salt"), trust.NewDefaultHMACConf("test"))
+	m.Require().NoError(err)
+
+	_, err = NewDiscovery("lo", 9444, otherSigner, fetchFrom(discovery)).LookupBeacon(context.Background(), query, *beacon)
+	m.Require().Error(err)
+}
+
+func (m *multicastSuite) Test_RespondOldFormat() {
+	// Use the loopback interface as it should always be there on any test system.
+	discovery := NewDiscovery("lo", 9444, nil, nil)
+
+	queries := make(chan LookupEvent, 10)
+	discovery.ReportQueries(func(event LookupEvent) {
+		select {
+		case queries <- event:
+		default:
+		}
+	})
+
+	err := discovery.Respond(context.Background(), ServerInfo{Version: Version, MinVersion: MinVersion, Name: "foo", Address: "1.2.3.4"}, 9443)
+	m.Require().NoError(err)
+
+	// Allow the responder to start.
+	time.Sleep(100 * time.Millisecond)
+
+	iface, err := net.InterfaceByName("lo")
+	m.Require().NoError(err)
+
+	backend := NewGroupBackend("lo", GroupConfig{Port: 9444})
+	conns, err := listen(iface, 0, backend.groupV4, backend.groupV6, false, func(c groupConn) error {
+		return c.conn.SetMulticastInterface(iface)
+	})
+	m.Require().NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+
+	// Systems using version 2.0 send their server info containing only their version as the query.
+	replies := browseConns(ctx, conns, 9444, []byte(`{"version":"2.0"}`), 500, func(b []byte) ([]Beacon, error) {
+		beacon := Beacon{}
+		err := json.Unmarshal(b, &beacon)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Beacon{beacon}, nil
+	})
+
+	reply := <-replies
+	cancel()
+
+	m.Require().NoError(discovery.StopResponder())
+
+	// The reply doesn't pass as server info of version 2.0.
+	m.Require().NoError(reply.Err)
+	m.Require().Equal(Version, reply.Beacon.Version)
+	m.Require().NotEqual("2.0", reply.Beacon.Version)
+
+	m.Require().NotEmpty(queries)
+	query := <-queries
+	m.Require().Equal("2.0", query.Info.Version)
+	m.Require().Equal(&VersionMismatchError{Versions: VersionRange{Min: "2.0", Max: "2.0"}, Supported: SupportedVersions}, query.Err)
+	m.Require().Equal(`System "" supports versions "2.0" which are incompatible with the supported versions "3.0"`, query.Err.Error())
+}
+
+func (m *multicastSuite) Test_mdnsRecords() {
+	beacon := Beacon{
+		Version:         "2.0",
+		Name:            "foo",
+		Address:         "fd42::1",
+		Port:            9443,
+		Nonce:           "nonce",
+		PassphraseWords: 6,
+		Wordlist:        "custom",
+	}
+
+	answers, err := records(beacon)
+	m.Require().NoError(err)
+	m.Require().Len(answers, 3)
+
+	msg := dnsmessage.Message{
+		Header:  dnsmessage.Header{Response: true},
+		Answers: answers,
+	}
+
+	b, err := msg.Pack()
+	m.Require().NoError(err)
+
+	beacons, err := parseMDNSResponse(b)
+	m.Require().NoError(err)
+	m.Require().Equal([]Beacon{beacon}, beacons)
+}
+
+func (m *multicastSuite) Test_mdnsAnswers() {
+	resources, err := records(Beacon{Version: "2.0", Address: "10.0.0.1", Port: 9443})
+	m.Require().NoError(err)
+
+	service := dnsmessage.MustNewName(MDNSService)
+	instance := dnsmessage.MustNewName("10-0-0-1." + MDNSService)
+
+	cases := []struct {
+		desc              string
+		question          dnsmessage.Question
+		legacy            bool
+		expectAnswers     []dnsmessage.Type
+		expectAdditionals []dnsmessage.Type
+		expectTTL         uint32
+	}{
+		{
+			desc:              "PTR query is answered with the SRV and TXT records as additional records",
+			question:          dnsmessage.Question{Name: service, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET},
+			expectAnswers:     []dnsmessage.Type{dnsmessage.TypePTR},
+			expectAdditionals: []dnsmessage.Type{dnsmessage.TypeSRV, dnsmessage.TypeTXT},
+			expectTTL:         mdnsTTL,
+		},
+		{
+			desc:          "SRV query of the instance is answered",
+			question:      dnsmessage.Question{Name: instance, Type: dnsmessage.TypeSRV, Class: dnsmessage.ClassINET},
+			expectAnswers: []dnsmessage.Type{dnsmessage.TypeSRV},
+			expectTTL:     mdnsTTL,
+		},
+		{
+			desc:          "TXT query of the instance is answered with a lowered TTL for legacy unicast queries",
+			question:      dnsmessage.Question{Name: instance, Type: dnsmessage.TypeTXT, Class: dnsmessage.ClassINET},
+			legacy:        true,
+			expectAnswers: []dnsmessage.Type{dnsmessage.TypeTXT},
+			expectTTL:     mdnsLegacyUnicastTTL,
+		},
+		{
+			desc:     "Query of another service isn't answered",
+			question: dnsmessage.Question{Name: dnsmessage.MustNewName("_http._tcp.local."), Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET},
+		},
+	}
+
+	recordTypes := func(resources []dnsmessage.Resource, ttl uint32) []dnsmessage.Type {
+		result := make([]dnsmessage.Type, 0, len(resources))
+		for _, resource := range resources {
+			result = append(result, resource.Header.Type)
+			m.Equal(ttl, resource.Header.TTL)
+		}
+
+		return result
+	}
+
+	for i, c := range cases {
+		m.T().Logf("%d: %s", i, c.desc)
+
+		questions, answers, additionals := mdnsAnswers(resources, []dnsmessage.Question{c.question}, c.legacy)
+		if c.expectAnswers == nil {
+			m.Empty(questions)
+			m.Empty(answers)
+			continue
+		}
+
+		m.Equal([]dnsmessage.Question{c.question}, questions)
+		m.ElementsMatch(c.expectAnswers, recordTypes(answers, c.expectTTL))
+		m.ElementsMatch(c.expectAdditionals, recordTypes(additionals, c.expectTTL))
+	}
+}
+
+func (m *multicastSuite) Test_LookupStatic() {
+	signer, err := trust.NewHMACArgon2([]byte("foo"), []byte("salt"), trust.NewDefaultHMACConf("test"))
+	m.Require().NoError(err)
+
+	probes := make(chan Beacon)
+	joinerBackend := NewStaticBackend(nil, nil, probes)
+
+	// The joiner responds without announcing anything so that the initiator can fetch its beacon from its API.
+	joiner := NewDiscoveryWithBackend(joinerBackend, signer, nil)
+	err = joiner.Respond(context.Background(), ServerInfo{Version: "2.0", Name: "bar"}, 9443)
+	m.Require().NoError(err)
+
+	// Simulate the joiner's API which is reachable using only one of the seed addresses.
+	// It only forwards the probes authorized using the nonce of its beacon to its lookup.
+	probe := func(ctx context.Context, address string, beacon Beacon) (*ServerInfo, error) {
+		if address != "1.2.3.5" {
+			return nil, errors.New("Unreachable")
+		}
+
+		peer, err := joiner.Beacon()
+		if err != nil {
+			return nil, err
+		}
+
+		authorization, err := Authorization(signer, peer.Nonce)
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := joiner.Info(peer.Nonce, authorization)
+		if err != nil {
+			return nil, err
+		}
+
+		select {
+		case probes <- beacon:
+			return info, nil
+		default:
+			return nil, errors.New("No active lookup")
+		}
+	}
+
+	responseInfo := ServerInfo{
+		Version: "2.0",
+		Name:    "foo",
+		Address: "1.2.3.4",
+	}
+
+	initiator := NewDiscoveryWithBackend(NewStaticBackend([]string{"1.2.3.5", "1.2.3.6"}, probe, nil), signer, nil)
+	err = initiator.Respond(context.Background(), responseInfo, 9443)
+	m.Require().NoError(err)
+
+	ctx, cancel := context.WithTimeoutCause(context.Background(), 5*time.Second, errors.New("Timeout exceeded"))
+	defer cancel()
+
+	receivedInfo, err := NewDiscoveryWithBackend(joinerBackend, signer, fetchFrom(initiator)).Lookup(ctx, Query{Versions: VersionRange{Min: "2.0", Max: "2.0"}})
+	m.Require().NoError(err)
+	m.Require().NotEmpty(receivedInfo.Signature)
+	m.Require().Equal(responseInfo.Name, receivedInfo.Name)
+	m.Require().Equal(responseInfo.Address, receivedInfo.Address)
+
+	// Stop probing the unreachable seed address.
+	err = initiator.StopResponder()
+	m.Require().NoError(err)
+}
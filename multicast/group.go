@@ -0,0 +1,274 @@
+package multicast
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/canonical/lxd/shared/logger"
+)
+
+// GroupConfig is the configuration of the multicast group used by GroupBackend.
+type GroupConfig struct {
+	// Group replaces the default group of the same address family.
+	// The default group is used if not set.
+	Group net.IP
+
+	// Port is the port on which responders listen for queries.
+	Port int64
+
+	// TTL is the time to live (IPv4) or hop limit (IPv6) of the sent queries.
+	// The system's default is used if not set.
+	TTL int
+}
+
+// GroupBackend is a discovery backend which exchanges beacons as JSON datagrams on a custom multicast group.
+type GroupBackend struct {
+	iface           string
+	port            int64
+	ttl             int
+	groupV4         net.IP
+	groupV6         net.IP
+	responderConns  []groupConn
+	responderCancel context.CancelFunc
+	onQuery         func(query Beacon)
+}
+
+// NewGroupBackend returns a new instance of GroupBackend using the given interface and group configuration.
+func NewGroupBackend(iface string, config GroupConfig) *GroupBackend {
+	b := &GroupBackend{
+		iface: iface,
+		port:  config.Port,
+		ttl:   config.TTL,
+		// This uses an address of the organization-local scope which isn't reserved for any public protocol.
+		// See https://www.iana.org/assignments/multicast-addresses/multicast-addresses.xhtml#multicast-addresses-12.
+		groupV4: net.IPv4(239, 100, 100, 100),
+		// This uses an address of the IPv6 organization-local scope (ff08::/16) with a group ID not assigned to any protocol.
+		// See https://www.iana.org/assignments/ipv6-multicast-addresses/ipv6-multicast-addresses.xhtml.
+		groupV6: net.ParseIP("ff08::239:100:100:100"),
+	}
+
+	if config.Group.To4() != nil {
+		b.groupV4 = config.Group
+	} else if config.Group != nil {
+		b.groupV6 = config.Group
+	}
+
+	return b
+}
+
+// Respond starts a new server that listens for datagrams on the configured multicast group
+// and sends the given beacon in response until the context is cancelled.
+// The server listens on the group of each address family (IPv4 and IPv6) used by the addresses of the configured interface.
+func (b *GroupBackend) Respond(ctx context.Context, beacon Beacon) error {
+	iface, err := net.InterfaceByName(b.iface)
+	if err != nil {
+		return fmt.Errorf("Failed to resolve server interface %q: %w", b.iface, err)
+	}
+
+	// Share the port with the responders on other interfaces.
+	conns, err := listen(iface, b.port, b.groupV4, b.groupV6, true, func(c groupConn) error {
+		err := c.conn.JoinGroup(iface, &net.UDPAddr{IP: c.group})
+		if err != nil {
+			return fmt.Errorf("Failed to join multicast group %q: %w", c.group.String(), err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, b.responderCancel = context.WithCancel(ctx)
+	b.responderConns = conns
+
+	// Close the network endpoints if the outer context got cancelled.
+	// This allows existing the endpoint's blocking read using ReadFrom.
+	go func() {
+		<-ctx.Done()
+		err := closeConns(conns)
+		if err != nil {
+			logger.Error("Failed to close network endpoint after context got cancelled", logger.Ctx{"err": err})
+		}
+	}()
+
+	for _, c := range conns {
+		go b.respond(c, iface, beacon)
+	}
+
+	return nil
+}
+
+// ReportQueries sets a function which gets called with the beacon of each query of the responder's realm.
+// The query's address is set to the source address of the datagram unless the peer sent its own.
+func (b *GroupBackend) ReportQueries(f func(query Beacon)) {
+	b.onQuery = f
+}
+
+// respond sends the given beacon in response to each query received on the given connection.
+// It returns once the connection gets closed.
+func (b *GroupBackend) respond(c groupConn, iface *net.Interface, beacon Beacon) {
+	for {
+		// See the comment on the sender (browse) for the reasoning about using 500.
+		buf := make([]byte, 500)
+		n, cm, src, err := c.conn.ReadFrom(buf)
+		if err != nil {
+			// Ignore "use of closed network connection" errors as this happens normally
+			// if the outer context gets cancelled in the connection closer go routine.
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Error("Failed to read from network endpoint", logger.Ctx{"err": err})
+			}
+
+			return
+		}
+
+		// The socket receives the datagrams of the group from every interface.
+		// Only respond to the ones received on this responder's interface as the beacon contains its address.
+		if cm.IfIndex != 0 && cm.IfIndex != iface.Index {
+			continue
+		}
+
+		receivedBeacon := Beacon{}
+
+		// Reslice the byte slice with the actual amount of bytes read from the datagram.
+		err = json.Unmarshal(buf[:n], &receivedBeacon)
+		if err != nil {
+			logger.Error("Failed to parse received multicast beacon", logger.Ctx{"err": err})
+			continue
+		}
+
+		// Deployments sharing the same network are separated by their realm.
+		if receivedBeacon.Realm != beacon.Realm {
+			logger.Debug("Ignoring multicast beacon of a different realm", logger.Ctx{"source": src.String(), "realm": receivedBeacon.Realm})
+			continue
+		}
+
+		// Respond also if the peer doesn't support any of our versions.
+		// This allows the peer to report the version mismatch instead of not finding any system at all.
+		_, err = beacon.Versions().Negotiate(receivedBeacon.Versions())
+		if err != nil {
+			logger.Warnf("Received multicast beacon from %q using incompatible versions %q", src.String(), receivedBeacon.Versions().String())
+		}
+
+		if b.onQuery != nil {
+			query := receivedBeacon
+			if query.Address == "" {
+				udpAddr, ok := src.(*net.UDPAddr)
+				if ok {
+					query.Address = udpAddr.IP.String()
+				}
+			}
+
+			b.onQuery(query)
+		}
+
+		if !cm.Dst.IsMulticast() {
+			continue
+		}
+
+		if !cm.Dst.Equal(c.group) {
+			logger.Warnf("Received multicast message from non recognized group %q", cm.Dst.String())
+			continue
+		}
+
+		bytes, err := json.Marshal(beacon)
+		if err != nil {
+			logger.Error("Failed to marshal beacon", logger.Ctx{"err": err})
+			continue
+		}
+
+		// Send a unicast message back to the source.
+		_, err = c.conn.WriteTo(bytes, src)
+		if err != nil {
+			logger.Error("Failed to send reply", logger.Ctx{"dest": src.String(), "err": err})
+			continue
+		}
+	}
+}
+
+// StopResponder stops the responder server and cancels it's inner context.
+func (b *GroupBackend) StopResponder() error {
+	// Check if this backend has an active responder server connection.
+	if b.responderConns != nil {
+		// Ignore errors if the connections are already closed.
+		// This can happen if the responders context already got cancelled
+		// which also triggers a close of the connections.
+		err := closeConns(b.responderConns)
+		if err != nil {
+			return fmt.Errorf("Failed to stop responder: %w", err)
+		}
+
+		// Cancel the inner context too and release all routines of the responder.
+		if b.responderCancel != nil {
+			b.responderCancel()
+		}
+	}
+
+	return nil
+}
+
+// Browse repeatedly sends the given query to the configured multicast group and reports every beacon received in response.
+// Only responders of the query's realm respond.
+// The query is sent to the group of each address family (IPv4 and IPv6) used by the addresses of the configured interface.
+func (b *GroupBackend) Browse(ctx context.Context, query Query) (<-chan BeaconEvent, error) {
+	iface, err := net.InterfaceByName(b.iface)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to resolve lookup interface %q: %w", b.iface, err)
+	}
+
+	// Use a random port for sending the multicast message.
+	conns, err := listen(iface, 0, b.groupV4, b.groupV6, false, func(c groupConn) error {
+		err := c.conn.SetMulticastInterface(iface)
+		if err != nil {
+			return fmt.Errorf("Failed to set multicast interface %q: %w", iface.Name, err)
+		}
+
+		if b.ttl > 0 {
+			err = c.conn.SetMulticastTTL(b.ttl)
+			if err != nil {
+				return fmt.Errorf("Failed to set multicast TTL %d: %w", b.ttl, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The lookup beacon contains only our protocol versions, realm and the informational name and address.
+	// The response contains the beacon which allows fetching the peer's full info from its API.
+	lookupBeacon := Beacon{
+		Version:    query.Versions.Max,
+		MinVersion: query.Versions.Min,
+		Name:       query.Name,
+		Realm:      query.Realm,
+		Address:    query.Address,
+	}
+
+	lookupBeaconBytes, err := json.Marshal(lookupBeacon)
+	if err != nil {
+		_ = closeConns(conns)
+		return nil, fmt.Errorf("Failed to marshal lookup beacon: %w", err)
+	}
+
+	parse := func(buf []byte) ([]Beacon, error) {
+		receivedBeacon := Beacon{}
+		err := json.Unmarshal(buf, &receivedBeacon)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Beacon{receivedBeacon}, nil
+	}
+
+	// 500 bytes should always make it through the network regardless of the MTU setting
+	// as Internet Protocol requires hosts to be able to process datagrams of at least 576 bytes.
+	// Subtracting the maximum IP header of size 60 bytes and the UDP header of size 8 bytes we are
+	// left with 508 bytes for the actual payload.
+	// We expect a beacon that contains the versions, name, address, port and nonce.
+	// Its size doesn't depend on the peer's info which is fetched separately from the peer's API.
+	return browseConns(ctx, conns, int(b.port), lookupBeaconBytes, 500, parse), nil
+}
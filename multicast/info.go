@@ -12,7 +12,7 @@ type NetworkInfo struct {
 	Subnet    *net.IPNet
 }
 
-// GetNetworkInfo returns a slice of NetworkInfo.
+// GetNetworkInfo returns a slice of NetworkInfo for each global unicast IPv4 and IPv6 address.
 func GetNetworkInfo() ([]NetworkInfo, error) {
 	networks := []NetworkInfo{}
 	ifaces, err := net.Interfaces()
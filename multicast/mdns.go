@@ -0,0 +1,429 @@
+package multicast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/canonical/lxd/shared/logger"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// MDNSService is the DNS-SD service type used to announce MicroCloud.
+const MDNSService = "_microcloud._tcp.local."
+
+// mdnsPort is the well-known port used by mDNS.
+const mdnsPort = 5353
+
+// mdnsTTL is the TTL in seconds of the announced records.
+const mdnsTTL = 120
+
+// mdnsLegacyUnicastTTL is the maximum TTL in seconds of the records sent in reply to legacy unicast queries.
+// See https://www.rfc-editor.org/rfc/rfc6762#section-6.7.
+const mdnsLegacyUnicastTTL = 10
+
+// MDNSBackend is a discovery backend which announces and browses DNS-SD records using multicast DNS.
+type MDNSBackend struct {
+	iface           string
+	groupV4         net.IP
+	groupV6         net.IP
+	responderConns  []groupConn
+	responderCancel context.CancelFunc
+}
+
+// NewMDNSBackend returns a new instance of MDNSBackend using the given interface.
+func NewMDNSBackend(iface string) *MDNSBackend {
+	return &MDNSBackend{
+		iface: iface,
+		// See https://www.rfc-editor.org/rfc/rfc6762#section-3.
+		groupV4: net.IPv4(224, 0, 0, 251),
+		groupV6: net.ParseIP("ff02::fb"),
+	}
+}
+
+// Respond answers mDNS queries for the MicroCloud service with PTR, SRV and TXT records
+// describing the given beacon until the context is cancelled.
+// Queries for the SRV and TXT records of the announced instance are answered too.
+// The SRV record points to the port of the API announced in the beacon.
+// The responder listens on the group of each address family (IPv4 and IPv6) used by the addresses of the configured interface.
+func (b *MDNSBackend) Respond(ctx context.Context, beacon Beacon) error {
+	iface, err := net.InterfaceByName(b.iface)
+	if err != nil {
+		return fmt.Errorf("Failed to resolve server interface %q: %w", b.iface, err)
+	}
+
+	answers, err := records(beacon)
+	if err != nil {
+		return err
+	}
+
+	// Share the port with any other mDNS responder already running on the system.
+	conns, err := listen(iface, mdnsPort, b.groupV4, b.groupV6, true, func(c groupConn) error {
+		err := c.conn.JoinGroup(iface, &net.UDPAddr{IP: c.group})
+		if err != nil {
+			return fmt.Errorf("Failed to join multicast group %q: %w", c.group.String(), err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, b.responderCancel = context.WithCancel(ctx)
+	b.responderConns = conns
+
+	// Close the network endpoints if the outer context got cancelled.
+	// This allows existing the endpoint's blocking read using ReadFrom.
+	go func() {
+		<-ctx.Done()
+		err := closeConns(conns)
+		if err != nil {
+			logger.Error("Failed to close network endpoint after context got cancelled", logger.Ctx{"err": err})
+		}
+	}()
+
+	for _, c := range conns {
+		go b.respond(c, iface, answers)
+	}
+
+	return nil
+}
+
+// respond answers the queries received on the given connection using the given records.
+// It returns once the connection gets closed.
+func (b *MDNSBackend) respond(c groupConn, iface *net.Interface, resources []dnsmessage.Resource) {
+	for {
+		// See https://www.rfc-editor.org/rfc/rfc6762#section-17.
+		buf := make([]byte, 9000)
+		n, cm, src, err := c.conn.ReadFrom(buf)
+		if err != nil {
+			// Ignore "use of closed network connection" errors as this happens normally
+			// if the outer context gets cancelled in the connection closer go routine.
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Error("Failed to read from network endpoint", logger.Ctx{"err": err})
+			}
+
+			return
+		}
+
+		// Only respond to the queries received on this responder's interface as the records contain its address.
+		if cm.IfIndex != 0 && cm.IfIndex != iface.Index {
+			continue
+		}
+
+		var p dnsmessage.Parser
+		header, err := p.Start(buf[:n])
+		if err != nil || header.Response {
+			continue
+		}
+
+		questions, err := p.AllQuestions()
+		if err != nil {
+			continue
+		}
+
+		// Queries which aren't sent from the mDNS port are legacy unicast queries.
+		// This is the case for the lookup as it uses a random port.
+		legacy := true
+		udpAddr, ok := src.(*net.UDPAddr)
+		if ok && udpAddr.Port == mdnsPort {
+			legacy = false
+		}
+
+		matching, answers, additionals := mdnsAnswers(resources, questions, legacy)
+		if len(matching) == 0 {
+			continue
+		}
+
+		// Reply directly to the source of the query.
+		// As the lookup uses a random port, the reply has to repeat the query's ID and questions.
+		// See https://www.rfc-editor.org/rfc/rfc6762#section-6.7.
+		msg := dnsmessage.Message{
+			Header:      dnsmessage.Header{ID: header.ID, Response: true, Authoritative: true},
+			Questions:   matching,
+			Answers:     answers,
+			Additionals: additionals,
+		}
+
+		bytes, err := msg.Pack()
+		if err != nil {
+			logger.Error("Failed to pack mDNS response", logger.Ctx{"err": err})
+			continue
+		}
+
+		_, err = c.conn.WriteTo(bytes, src)
+		if err != nil {
+			logger.Error("Failed to send reply", logger.Ctx{"dest": src.String(), "err": err})
+			continue
+		}
+	}
+}
+
+// StopResponder stops the responder server and cancels it's inner context.
+func (b *MDNSBackend) StopResponder() error {
+	// Check if this backend has an active responder server connection.
+	if b.responderConns != nil {
+		// Ignore errors if the connections are already closed.
+		// This can happen if the responders context already got cancelled
+		// which also triggers a close of the connections.
+		err := closeConns(b.responderConns)
+		if err != nil {
+			return fmt.Errorf("Failed to stop responder: %w", err)
+		}
+
+		// Cancel the inner context too and release all routines of the responder.
+		if b.responderCancel != nil {
+			b.responderCancel()
+		}
+	}
+
+	return nil
+}
+
+// Browse repeatedly queries the MicroCloud service using mDNS and reports the beacon from every SRV and TXT record received.
+// The query isn't sent to the other systems as each system announces its own versions and realm in the TXT record.
+// The query is sent to the group of each address family (IPv4 and IPv6) used by the addresses of the configured interface.
+func (b *MDNSBackend) Browse(ctx context.Context, _ Query) (<-chan BeaconEvent, error) {
+	iface, err := net.InterfaceByName(b.iface)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to resolve lookup interface %q: %w", b.iface, err)
+	}
+
+	service, err := dnsmessage.NewName(MDNSService)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse service name %q: %w", MDNSService, err)
+	}
+
+	query := dnsmessage.Message{
+		Questions: []dnsmessage.Question{{Name: service, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET}},
+	}
+
+	queryBytes, err := query.Pack()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to pack mDNS query: %w", err)
+	}
+
+	// Use a random port for sending the queries.
+	// This causes responders to reply using unicast.
+	conns, err := listen(iface, 0, b.groupV4, b.groupV6, false, func(c groupConn) error {
+		err := c.conn.SetMulticastInterface(iface)
+		if err != nil {
+			return fmt.Errorf("Failed to set multicast interface %q: %w", iface.Name, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return browseConns(ctx, conns, mdnsPort, queryBytes, 9000, parseMDNSResponse), nil
+}
+
+// records returns the PTR, SRV and TXT records announcing the given beacon.
+func records(beacon Beacon) ([]dnsmessage.Resource, error) {
+	service, err := dnsmessage.NewName(MDNSService)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse service name %q: %w", MDNSService, err)
+	}
+
+	// The instance name is a single DNS label which cannot contain dots or colons.
+	// Derive it from the address as it's unique within the network.
+	label := strings.NewReplacer(".", "-", ":", "-").Replace(beacon.Address)
+
+	instance, err := dnsmessage.NewName(label + "." + MDNSService)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create mDNS instance name for %q: %w", beacon.Address, err)
+	}
+
+	host, err := dnsmessage.NewName(label + ".local.")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create mDNS host name for %q: %w", beacon.Address, err)
+	}
+
+	txt := []string{
+		"version=" + beacon.Version,
+		"min_version=" + beacon.MinVersion,
+		"realm=" + beacon.Realm,
+		"address=" + beacon.Address,
+		"nonce=" + beacon.Nonce,
+		"passphrase_words=" + strconv.Itoa(int(beacon.PassphraseWords)),
+		"wordlist=" + beacon.Wordlist,
+	}
+
+	// Each TXT entry is limited to 255 bytes, so omit names which don't fit.
+	// The name is only informational and also part of the full info.
+	nameEntry := "name=" + beacon.Name
+	if len(nameEntry) <= 255 {
+		txt = append(txt, nameEntry)
+	}
+
+	header := func(name dnsmessage.Name, recordType dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Type: recordType, Class: dnsmessage.ClassINET, TTL: mdnsTTL}
+	}
+
+	return []dnsmessage.Resource{
+		{Header: header(service, dnsmessage.TypePTR), Body: &dnsmessage.PTRResource{PTR: instance}},
+		{Header: header(instance, dnsmessage.TypeSRV), Body: &dnsmessage.SRVResource{Target: host, Port: uint16(beacon.Port)}},
+		{Header: header(instance, dnsmessage.TypeTXT), Body: &dnsmessage.TXTResource{TXT: txt}},
+	}, nil
+}
+
+// mdnsAnswers returns the questions answered by the given records together with the answers and additional records.
+// PTR questions for the service are answered with the instance's PTR record and its SRV and TXT records as additional records.
+// SRV and TXT questions for the instance are answered with the respective record.
+// The TTL of the records is lowered for legacy unicast queries.
+// See https://www.rfc-editor.org/rfc/rfc6763#section-12.1.
+func mdnsAnswers(records []dnsmessage.Resource, questions []dnsmessage.Question, legacy bool) ([]dnsmessage.Question, []dnsmessage.Resource, []dnsmessage.Resource) {
+	matching := make([]dnsmessage.Question, 0, len(questions))
+	answered := make(map[int]bool, len(records))
+	for _, q := range questions {
+		found := false
+		for i, record := range records {
+			if q.Type != dnsmessage.TypeALL && q.Type != record.Header.Type {
+				continue
+			}
+
+			if !strings.EqualFold(q.Name.String(), record.Header.Name.String()) {
+				continue
+			}
+
+			answered[i] = true
+			found = true
+		}
+
+		if found {
+			matching = append(matching, q)
+		}
+	}
+
+	if len(matching) == 0 {
+		return nil, nil, nil
+	}
+
+	// Answering the PTR record implies the instance's SRV and TXT records are needed too.
+	ptrAnswered := false
+	for i, record := range records {
+		if answered[i] && record.Header.Type == dnsmessage.TypePTR {
+			ptrAnswered = true
+		}
+	}
+
+	answers := make([]dnsmessage.Resource, 0, len(records))
+	additionals := make([]dnsmessage.Resource, 0, len(records))
+	for i, record := range records {
+		if legacy && record.Header.TTL > mdnsLegacyUnicastTTL {
+			record.Header.TTL = mdnsLegacyUnicastTTL
+		}
+
+		if answered[i] {
+			answers = append(answers, record)
+		} else if ptrAnswered {
+			additionals = append(additionals, record)
+		}
+	}
+
+	return matching, answers, additionals
+}
+
+// parseMDNSResponse returns the beacon of each MicroCloud service instance found in the SRV and TXT records of the given mDNS response.
+func parseMDNSResponse(b []byte) ([]Beacon, error) {
+	var p dnsmessage.Parser
+	header, err := p.Start(b)
+	if err != nil {
+		return nil, err
+	}
+
+	if !header.Response {
+		return nil, nil
+	}
+
+	err = p.SkipAllQuestions()
+	if err != nil {
+		return nil, err
+	}
+
+	answers, err := p.AllAnswers()
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.SkipAllAuthorities()
+	if err != nil {
+		return nil, err
+	}
+
+	// Responders may send the SRV and TXT records as additional records.
+	additionals, err := p.AllAdditionals()
+	if err != nil {
+		return nil, err
+	}
+
+	records := append(answers, additionals...)
+
+	// Collect the port of each instance from its SRV record first.
+	ports := make(map[string]int64, len(records))
+	for _, record := range records {
+		srv, ok := record.Body.(*dnsmessage.SRVResource)
+		if ok {
+			ports[strings.ToLower(record.Header.Name.String())] = int64(srv.Port)
+		}
+	}
+
+	beacons := make([]Beacon, 0, len(records))
+	for _, record := range records {
+		name := strings.ToLower(record.Header.Name.String())
+		txt, ok := record.Body.(*dnsmessage.TXTResource)
+		if !ok || !strings.HasSuffix(name, "."+MDNSService) {
+			continue
+		}
+
+		port, ok := ports[name]
+		if !ok {
+			continue
+		}
+
+		beacon := beaconFromTXT(txt.TXT)
+		beacon.Port = port
+		beacons = append(beacons, beacon)
+	}
+
+	return beacons, nil
+}
+
+// beaconFromTXT returns the beacon contained in the given TXT record entries.
+func beaconFromTXT(txt []string) Beacon {
+	beacon := Beacon{}
+	for _, entry := range txt {
+		key, value, _ := strings.Cut(entry, "=")
+
+		switch key {
+		case "version":
+			beacon.Version = value
+		case "min_version":
+			beacon.MinVersion = value
+		case "name":
+			beacon.Name = value
+		case "realm":
+			beacon.Realm = value
+		case "address":
+			beacon.Address = value
+		case "nonce":
+			beacon.Nonce = value
+		case "passphrase_words":
+			words, err := strconv.ParseUint(value, 10, 8)
+			if err == nil {
+				beacon.PassphraseWords = uint8(words)
+			}
+
+		case "wordlist":
+			beacon.Wordlist = value
+		}
+	}
+
+	return beacon
+}
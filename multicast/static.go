@@ -0,0 +1,121 @@
+package multicast
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+)
+
+// Prober hands the given beacon to the system at the given address and returns the info of that system.
+// The system has to be looking up peers using a StaticBackend which reports the beacon.
+type Prober func(ctx context.Context, address string, beacon Beacon) (*ServerInfo, error)
+
+// StaticBackend is a discovery backend which doesn't rely on multicast.
+// Instead of listening for queries, the responder actively probes a static list of seed addresses.
+// Lookups report the beacons received from those probes.
+type StaticBackend struct {
+	addresses       []string
+	probe           Prober
+	probes          <-chan Beacon
+	responderCancel context.CancelFunc
+	responderWg     sync.WaitGroup
+}
+
+// NewStaticBackend returns a new instance of StaticBackend.
+// The responder hands its beacon to each of the given addresses using probe.
+// Lookups report every beacon received on the probes channel.
+func NewStaticBackend(addresses []string, probe Prober, probes <-chan Beacon) *StaticBackend {
+	return &StaticBackend{
+		addresses: addresses,
+		probe:     probe,
+		probes:    probes,
+	}
+}
+
+// Respond repeatedly probes each of the seed addresses with the given beacon until the system at the address replies
+// or the context is cancelled.
+// It's a no-op if there aren't any seed addresses.
+func (b *StaticBackend) Respond(ctx context.Context, beacon Beacon) error {
+	if len(b.addresses) == 0 {
+		return nil
+	}
+
+	if b.probe == nil {
+		return errors.New("Cannot probe seed addresses without a prober")
+	}
+
+	ctx, b.responderCancel = context.WithCancel(ctx)
+
+	for _, address := range b.addresses {
+		b.responderWg.Add(1)
+
+		go func() {
+			defer b.responderWg.Done()
+
+			for {
+				probeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+				peer, err := b.probe(probeCtx, address, beacon)
+				cancel()
+
+				if err == nil {
+					logger.Info("Probed system at seed address", logger.Ctx{"address": address, "name": peer.Name, "version": peer.Version})
+					return
+				}
+
+				// The system might not yet have started its session, so retry until the responder gets stopped.
+				logger.Debug("Failed to probe seed address", logger.Ctx{"address": address, "err": err})
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Second):
+				}
+			}
+		}()
+	}
+
+	return nil
+}
+
+// StopResponder stops probing the seed addresses and waits for all pending probes to finish.
+func (b *StaticBackend) StopResponder() error {
+	if b.responderCancel != nil {
+		b.responderCancel()
+		b.responderWg.Wait()
+	}
+
+	return nil
+}
+
+// Browse reports the beacon of every system which probed this system until the context is cancelled.
+// The query isn't sent anywhere as each beacon includes the versions and realm of the probing system.
+func (b *StaticBackend) Browse(ctx context.Context, _ Query) (<-chan BeaconEvent, error) {
+	if b.probes == nil {
+		return nil, errors.New("Cannot receive probes without a probes channel")
+	}
+
+	events := make(chan BeaconEvent)
+
+	go func() {
+		defer close(events)
+
+		for {
+			select {
+			case beacon := <-b.probes:
+				select {
+				case events <- BeaconEvent{Beacon: beacon}:
+				case <-ctx.Done():
+					return
+				}
+
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
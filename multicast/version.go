@@ -1,4 +1,72 @@
 package multicast
 
+import (
+	"fmt"
+
+	"golang.org/x/mod/semver"
+)
+
 // Version is the current version of the multicast discovery format.
-const Version = "2.0"
+// Version 3.0 replaced the server info sent by version 2.0 with a beacon and fetches the info from the peer's API.
+const Version = "3.0"
+
+// MinVersion is the oldest version of the multicast discovery format which is still supported.
+// Version 2.0 isn't supported as its datagrams carry the full server info instead of a beacon.
+const MinVersion = "3.0"
+
+// SupportedVersions is the range of multicast discovery format versions supported by this system.
+var SupportedVersions = VersionRange{Min: MinVersion, Max: Version}
+
+// VersionRange is an inclusive range of multicast discovery format versions.
+type VersionRange struct {
+	Min string
+	Max string
+}
+
+// String returns the range in the form "min-max" or just the version if both ends are the same.
+func (r VersionRange) String() string {
+	if r.Min == r.Max {
+		return r.Max
+	}
+
+	return r.Min + "-" + r.Max
+}
+
+// Contains returns whether the given version is part of the range.
+func (r VersionRange) Contains(version string) bool {
+	return compareVersions(r.Min, version) <= 0 && compareVersions(version, r.Max) <= 0
+}
+
+// Negotiate returns the highest version supported by both the range and the given other range.
+func (r VersionRange) Negotiate(other VersionRange) (string, error) {
+	highest := r.Max
+	if compareVersions(other.Max, highest) < 0 {
+		highest = other.Max
+	}
+
+	if !r.Contains(highest) || !other.Contains(highest) {
+		return "", fmt.Errorf("No common version between %q and %q", r.String(), other.String())
+	}
+
+	return highest, nil
+}
+
+// NewVersionRange returns the range for the given versions.
+// Systems which don't announce a minimum version only support their own version.
+func NewVersionRange(minVersion string, maxVersion string) VersionRange {
+	if minVersion == "" {
+		minVersion = maxVersion
+	}
+
+	return VersionRange{Min: minVersion, Max: maxVersion}
+}
+
+// compareVersions compares the given versions in the form "major.minor".
+// Invalid versions are considered lower than any valid version.
+func compareVersions(a string, b string) int {
+	// semver.Compare returns
+	// * 0 in case a == b
+	// * 1 in case a > b
+	// * -1 in case a < b
+	return semver.Compare("v"+a, "v"+b)
+}
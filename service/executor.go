@@ -0,0 +1,171 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/canonical/microcloud/microcloud/api/types"
+)
+
+// RunOrder defines the order in which a hook runs across services.
+type RunOrder int
+
+const (
+	// RunParallel runs the hook on all services concurrently.
+	RunParallel RunOrder = iota
+
+	// RunDependenciesFirst runs the hook on each service after it succeeded on all of the service's dependencies.
+	// This is the order used when forming or joining a cluster.
+	RunDependenciesFirst
+
+	// RunDependentsFirst runs the hook on each service after it succeeded on all services which depend on it.
+	// This is the order used when removing a cluster member.
+	RunDependentsFirst
+)
+
+// ServiceErrors contains the error of each service on which a hook failed.
+type ServiceErrors map[types.ServiceType]error
+
+// Error returns the errors of all failed services sorted by the service type.
+func (e ServiceErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, serviceType := range e.services() {
+		messages = append(messages, fmt.Sprintf("%s: %v", serviceType, e[serviceType]))
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the errors of all failed services, which allows inspecting them using errors.Is and errors.As.
+func (e ServiceErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, serviceType := range e.services() {
+		errs = append(errs, e[serviceType])
+	}
+
+	return errs
+}
+
+// services returns the types of the failed services in a stable order.
+func (e ServiceErrors) services() []types.ServiceType {
+	services := make([]types.ServiceType, 0, len(e))
+	for serviceType := range e {
+		services = append(services, serviceType)
+	}
+
+	slices.Sort(services)
+
+	return services
+}
+
+// errDependencyFailed is returned for a service which got skipped because the hook failed on a service it waits for.
+var errDependencyFailed = errors.New("Skipped due to the failure of a preceding service")
+
+// runGraph runs the given hook on each of the given services once it succeeded on all services the service waits for.
+// Services which don't wait for each other run concurrently. If the hook fails on a service, all services waiting for it
+// are skipped. The returned error contains every failed and skipped service.
+func runGraph(services map[types.ServiceType]Service, waitsFor map[types.ServiceType][]types.ServiceType, f func(s Service) error) error {
+	err := checkGraph(services, waitsFor)
+	if err != nil {
+		return err
+	}
+
+	done := make(map[types.ServiceType]chan struct{}, len(services))
+	for serviceType := range services {
+		done[serviceType] = make(chan struct{})
+	}
+
+	errs := ServiceErrors{}
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for serviceType, s := range services {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done[serviceType])
+
+			for _, dependency := range waitsFor[serviceType] {
+				<-done[dependency]
+			}
+
+			mu.Lock()
+			for _, dependency := range waitsFor[serviceType] {
+				if errs[dependency] != nil {
+					errs[serviceType] = errDependencyFailed
+					mu.Unlock()
+					return
+				}
+			}
+
+			mu.Unlock()
+
+			err := f(s)
+			if err != nil {
+				mu.Lock()
+				errs[serviceType] = err
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// checkGraph verifies that each service only waits for the given services, and that no services wait for each other.
+func checkGraph(services map[types.ServiceType]Service, waitsFor map[types.ServiceType][]types.ServiceType) error {
+	// Remove services without pending dependencies until none are left. Any remaining services are part of a cycle.
+	pending := make(map[types.ServiceType]int, len(services))
+	for serviceType := range services {
+		for _, dependency := range waitsFor[serviceType] {
+			_, ok := services[dependency]
+			if !ok {
+				return fmt.Errorf("Service %q waits for unknown service %q", serviceType, dependency)
+			}
+		}
+
+		pending[serviceType] = len(waitsFor[serviceType])
+	}
+
+	for len(pending) > 0 {
+		ready := []types.ServiceType{}
+		for serviceType, count := range pending {
+			if count == 0 {
+				ready = append(ready, serviceType)
+			}
+		}
+
+		if len(ready) == 0 {
+			cycle := make([]string, 0, len(pending))
+			for serviceType := range pending {
+				cycle = append(cycle, string(serviceType))
+			}
+
+			slices.Sort(cycle)
+
+			return fmt.Errorf("Dependency cycle between services %s", strings.Join(cycle, ", "))
+		}
+
+		for _, serviceType := range ready {
+			delete(pending, serviceType)
+		}
+
+		for serviceType := range pending {
+			for _, dependency := range waitsFor[serviceType] {
+				if slices.Contains(ready, dependency) {
+					pending[serviceType]--
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/canonical/microcloud/microcloud/api/types"
+)
+
+type executorSuite struct {
+	suite.Suite
+}
+
+func TestExecutorSuite(t *testing.T) {
+	suite.Run(t, new(executorSuite))
+}
+
+// testService is a service which only knows its type.
+type testService struct {
+	Service
+
+	serviceType types.ServiceType
+}
+
+// Type returns the type of the service.
+func (s testService) Type() types.ServiceType {
+	return s.serviceType
+}
+
+func (s *executorSuite) Test_runGraph() {
+	services := map[types.ServiceType]Service{}
+	for _, serviceType := range []types.ServiceType{types.MicroCloud, types.MicroCeph, types.MicroOVN, types.LXD} {
+		services[serviceType] = testService{serviceType: serviceType}
+	}
+
+	dependencies := map[types.ServiceType][]types.ServiceType{
+		types.MicroCeph: {types.MicroCloud},
+		types.MicroOVN:  {types.MicroCloud},
+		types.LXD:       {types.MicroCloud, types.MicroCeph, types.MicroOVN},
+	}
+
+	cases := []struct {
+		desc           string
+		waitsFor       map[types.ServiceType][]types.ServiceType
+		fail           types.ServiceType
+		expectOrder    [][]types.ServiceType
+		expectFailed   []types.ServiceType
+		expectSkipped  []types.ServiceType
+		expectGraphErr bool
+	}{
+		{
+			desc:        "Dependencies run first",
+			waitsFor:    dependencies,
+			expectOrder: [][]types.ServiceType{{types.MicroCloud}, {types.MicroCeph, types.MicroOVN}, {types.LXD}},
+		},
+		{
+			desc:          "Failed service skips the services waiting for it",
+			waitsFor:      dependencies,
+			fail:          types.MicroCeph,
+			expectOrder:   [][]types.ServiceType{{types.MicroCloud}, {types.MicroCeph, types.MicroOVN}},
+			expectFailed:  []types.ServiceType{types.MicroCeph},
+			expectSkipped: []types.ServiceType{types.LXD},
+		},
+		{
+			desc:           "Cyclic dependencies",
+			waitsFor:       map[types.ServiceType][]types.ServiceType{types.MicroCloud: {types.LXD}, types.LXD: {types.MicroCloud}},
+			expectGraphErr: true,
+		},
+	}
+
+	for i, c := range cases {
+		s.T().Logf("%d: %s", i, c.desc)
+
+		mu := sync.Mutex{}
+		order := []types.ServiceType{}
+		err := runGraph(services, c.waitsFor, func(service Service) error {
+			mu.Lock()
+			order = append(order, service.Type())
+			mu.Unlock()
+
+			if service.Type() == c.fail {
+				return errors.New("Failed")
+			}
+
+			return nil
+		})
+
+		if c.expectGraphErr {
+			s.Error(err)
+			s.Empty(order)
+			continue
+		}
+
+		// Services within the same stage run in any order.
+		for _, stage := range c.expectOrder {
+			s.Require().GreaterOrEqual(len(order), len(stage))
+			s.ElementsMatch(stage, order[:len(stage)])
+			order = order[len(stage):]
+		}
+
+		s.Empty(order)
+
+		if len(c.expectFailed) == 0 && len(c.expectSkipped) == 0 {
+			s.NoError(err)
+			continue
+		}
+
+		serviceErrs := ServiceErrors{}
+		s.Require().ErrorAs(err, &serviceErrs)
+		s.Len(serviceErrs, len(c.expectFailed)+len(c.expectSkipped))
+		for serviceType, err := range serviceErrs {
+			if slices.Contains(c.expectSkipped, serviceType) {
+				s.ErrorIs(err, errDependencyFailed)
+			} else {
+				s.Contains(c.expectFailed, serviceType)
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v3/microcluster"
+	microTypes "github.com/canonical/microcluster/v3/microcluster/types"
+
+	"github.com/canonical/microcloud/microcloud/api/types"
+)
+
+// microclusterHealth returns the health of the dqlite database of the given microcluster on the member with the given name.
+// The state of other members is left to their own reports.
+// If the database isn't initialized yet, there is no health to report so it returns a 503 error.
+func microclusterHealth(ctx context.Context, m *microcluster.MicroCluster, name string) (types.HealthReport, error) {
+	report := types.NewHealthReport()
+
+	status, err := m.Status(ctx)
+	if err != nil {
+		return report, fmt.Errorf("Failed to get status: %w", err)
+	}
+
+	if !status.Ready {
+		return report, api.StatusErrorf(http.StatusServiceUnavailable, "Database is not initialized")
+	}
+
+	members, err := m.GetClusterMembers(ctx)
+	if err != nil {
+		return report, fmt.Errorf("Failed to get cluster members: %w", err)
+	}
+
+	for _, member := range members {
+		if member.Name != name {
+			continue
+		}
+
+		switch member.Status {
+		case microTypes.MemberOnline:
+		case microTypes.MemberNeedsUpgrade, microTypes.MemberUpgrading:
+			report.Add(types.HealthDegraded, fmt.Sprintf("Database member is %s", member.Status))
+		default:
+			report.Add(types.HealthUnhealthy, fmt.Sprintf("Database member is %s", member.Status))
+		}
+
+		return report, nil
+	}
+
+	report.Add(types.HealthUnhealthy, "Not a database member")
+
+	return report, nil
+}
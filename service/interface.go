@@ -4,6 +4,8 @@ import (
 	"context"
 	"crypto/x509"
 
+	"github.com/canonical/microcluster/v3/microcluster"
+
 	"github.com/canonical/microcloud/microcloud/api/types"
 )
 
@@ -19,7 +21,9 @@ type Service interface {
 	// RemoteClusterMembers is called during the pre-init phase of microcluster.
 	// It allows providing the certificate of the remote microcluster member for mTLS verification.
 	RemoteClusterMembers(ctx context.Context, cert *x509.Certificate, address string) (map[string]string, error)
-	DeleteClusterMember(ctx context.Context, name string, force bool) error
+	// DeleteClusterMember removes the given cluster member. If an address is given, the member is removed
+	// through the MicroCloud at that address instead of the local one.
+	DeleteClusterMember(ctx context.Context, name string, address string, force bool) error
 
 	Type() types.ServiceType
 	Name() string
@@ -29,4 +33,12 @@ type Service interface {
 	SupportsFeature(ctx context.Context, feature string) (bool, error)
 	GetVersion(ctx context.Context) (string, error)
 	IsInitialized(ctx context.Context) (bool, error)
+	Health(ctx context.Context) (types.HealthReport, error)
+}
+
+// MicroclusterService represents a service which is built on top of microcluster.
+type MicroclusterService interface {
+	Service
+
+	Microcluster() *microcluster.MicroCluster
 }
@@ -36,6 +36,29 @@ type LXDService struct {
 	config  map[string]string
 }
 
+// LXDDir is the path to the state directory of the LXD snap.
+const LXDDir = "/var/snap/lxd/common/lxd"
+
+func init() {
+	// LXD networks and storage pools use MicroOVN and MicroCeph, so LXD is set up after them and removed before them.
+	RegisterService(ServiceRegistration{
+		Type: types.LXD,
+		New: func(name string, addr string, cloudDir string) (Service, error) {
+			service, err := NewLXDService(name, addr, cloudDir)
+			if err != nil {
+				return nil, err
+			}
+
+			return service, nil
+		},
+		StateDir:     LXDDir,
+		Socket:       "unix.socket",
+		Port:         LXDPort,
+		Required:     true,
+		Dependencies: []types.ServiceType{types.MicroCloud, types.MicroCeph, types.MicroOVN},
+	})
+}
+
 // NewLXDService creates a new LXD service with a client attached.
 func NewLXDService(name string, addr string, cloudDir string) (*LXDService, error) {
 	client, err := microcluster.App(microcluster.Args{StateDir: cloudDir})
@@ -380,8 +403,15 @@ func (s LXDService) clusterMembers(client lxd.InstanceServer) (map[string]string
 }
 
 // DeleteClusterMember removes the given cluster member from the service.
-func (s LXDService) DeleteClusterMember(ctx context.Context, name string, force bool) error {
-	c, err := s.Client(ctx)
+func (s LXDService) DeleteClusterMember(ctx context.Context, name string, address string, force bool) error {
+	var c lxd.InstanceServer
+	var err error
+	if address != "" {
+		c, err = s.remoteClient(nil, address, CloudPort)
+	} else {
+		c, err = s.Client(ctx)
+	}
+
 	if err != nil {
 		return err
 	}
@@ -730,6 +760,58 @@ func (s LXDService) IsInitialized(ctx context.Context) (bool, error) {
 	return isInit, nil
 }
 
+// Health returns the health of LXD on this cluster member.
+// It reports the state of the cluster member and any new LXD warnings concerning it.
+// An evacuated member is only degraded as it was taken out of service on purpose.
+func (s LXDService) Health(ctx context.Context) (types.HealthReport, error) {
+	report := types.NewHealthReport()
+
+	c, err := s.Client(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	server, _, err := c.GetServer()
+	if err != nil {
+		return report, fmt.Errorf("Failed to get server: %w", err)
+	}
+
+	if server.Environment.ServerClustered {
+		member, _, err := c.GetClusterMember(s.name)
+		if err != nil {
+			return report, fmt.Errorf("Failed to get cluster member %q: %w", s.name, err)
+		}
+
+		switch member.Status {
+		case "Online":
+		case "Evacuated":
+			report.Add(types.HealthDegraded, fmt.Sprintf("Cluster member is %s: %s", member.Status, member.Message))
+		default:
+			report.Add(types.HealthUnhealthy, fmt.Sprintf("Cluster member is %s: %s", member.Status, member.Message))
+		}
+	}
+
+	warnings, err := c.GetWarnings()
+	if err != nil {
+		return report, fmt.Errorf("Failed to get warnings: %w", err)
+	}
+
+	for _, warning := range warnings {
+		// Acknowledged and resolved warnings don't require attention.
+		if warning.Status != "new" {
+			continue
+		}
+
+		if warning.Location != "" && warning.Location != s.name {
+			continue
+		}
+
+		report.Add(types.HealthDegraded, fmt.Sprintf("%s: %s", warning.Type, warning.LastMessage))
+	}
+
+	return report, nil
+}
+
 // isInitialized checks if LXD is initialized by fetching the storage pools, and cluster status.
 // If none exist, that means LXD has not yet been set up.
 func (s *LXDService) isInitialized(c lxd.InstanceServer) (bool, error) {
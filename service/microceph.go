@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
 	"time"
 
@@ -35,6 +36,27 @@ type CephService struct {
 	config  map[string]string
 }
 
+// MicroCephDir is the path to the state directory of the MicroCeph snap.
+const MicroCephDir = "/var/snap/microceph/common/state"
+
+func init() {
+	RegisterService(ServiceRegistration{
+		Type: types.MicroCeph,
+		New: func(name string, addr string, cloudDir string) (Service, error) {
+			service, err := NewCephService(name, addr, cloudDir)
+			if err != nil {
+				return nil, err
+			}
+
+			return service, nil
+		},
+		StateDir:     MicroCephDir,
+		Socket:       "control.socket",
+		Port:         CephPort,
+		Dependencies: []types.ServiceType{types.MicroCloud},
+	})
+}
+
 // NewCephService creates a new MicroCeph service with a client attached.
 func NewCephService(name string, addr string, cloudDir string) (*CephService, error) {
 	proxy := func(r *http.Request) (*url.URL, error) {
@@ -347,8 +369,22 @@ func (s CephService) ClusterMembers(ctx context.Context) (map[string]string, err
 }
 
 // DeleteClusterMember removes the given cluster member from the service.
-func (s CephService) DeleteClusterMember(ctx context.Context, name string, force bool) error {
-	return s.m.RemoveClusterMember(ctx, name, "", force)
+func (s CephService) DeleteClusterMember(ctx context.Context, name string, address string, force bool) error {
+	if address == "" {
+		return s.m.RemoveClusterMember(ctx, name, "", force)
+	}
+
+	c, err := s.m.RemoteClient(util.CanonicalNetworkAddress(address, CloudPort))
+	if err != nil {
+		return err
+	}
+
+	c, err = cloudClient.UseAuthProxy(c, types.MicroCeph, cloudClient.AuthConfig{})
+	if err != nil {
+		return err
+	}
+
+	return cloudClient.RemoveClusterMember(ctx, name, force, c)
 }
 
 // ClusterConfig returns the Ceph cluster configuration.
@@ -425,6 +461,90 @@ func (s CephService) IsInitialized(ctx context.Context) (bool, error) {
 	return status.Ready, nil
 }
 
+// Health returns the health of MicroCeph on this cluster member.
+// Besides the MicroCeph database, it reports whether the Ceph monitors can tolerate the loss of a member,
+// members with disks but without the OSD service, and pools which require more OSDs than exist in the cluster.
+// The MicroCeph API doesn't expose the Ceph health checks and the state of each OSD, so they are reported as unsupported.
+func (s CephService) Health(ctx context.Context) (types.HealthReport, error) {
+	report, err := microclusterHealth(ctx, s.m, s.name)
+	if err != nil || report.Status == types.HealthUnhealthy {
+		return report, err
+	}
+
+	report.AddUnsupported("Ceph health checks and the state of each OSD aren't exposed by the MicroCeph API")
+
+	services, err := s.GetServices(ctx, "")
+	if err != nil {
+		return report, err
+	}
+
+	monCount := 0
+	osdLocations := map[string]bool{}
+	for _, service := range services {
+		switch service.Service {
+		case "mon":
+			monCount++
+		case "osd":
+			osdLocations[service.Location] = true
+		}
+	}
+
+	if monCount == 0 {
+		report.Add(types.HealthUnhealthy, "No members run the Ceph monitor service")
+		return report, nil
+	}
+
+	members, err := s.m.GetClusterMembers(ctx)
+	if err != nil {
+		return report, fmt.Errorf("Failed to get cluster members: %w", err)
+	}
+
+	// Clusters with less than 3 members can't be fault tolerant in the first place, which `microcloud status` already reports.
+	if len(members) >= 3 && monCount < 3 {
+		report.Add(types.HealthDegraded, fmt.Sprintf("Ceph monitors run on %d members, 3 are required for fault tolerance", monCount))
+	}
+
+	disks, err := s.GetDisks(ctx, "", nil)
+	if err != nil {
+		return report, err
+	}
+
+	// A cluster without any OSDs is already reported by `microcloud status` itself.
+	if len(disks) == 0 {
+		return report, nil
+	}
+
+	diskCounts := map[string]int{}
+	for _, disk := range disks {
+		diskCounts[disk.Location]++
+	}
+
+	locations := make([]string, 0, len(diskCounts))
+	for location := range diskCounts {
+		locations = append(locations, location)
+	}
+
+	sort.Strings(locations)
+	for _, location := range locations {
+		if !osdLocations[location] {
+			report.Add(types.HealthDegraded, fmt.Sprintf("%s has %d disks but doesn't run the OSD service", location, diskCounts[location]))
+		}
+	}
+
+	pools, err := s.GetPools(ctx, "")
+	if err != nil {
+		return report, err
+	}
+
+	for _, pool := range pools {
+		if pool.Size > int64(len(disks)) {
+			report.Add(types.HealthDegraded, fmt.Sprintf("Pool %q requires %d OSDs but only %d exist", pool.Pool, pool.Size, len(disks)))
+		}
+	}
+
+	return report, nil
+}
+
 // SetConfig sets the config of this Service instance.
 func (s *CephService) SetConfig(config map[string]string) {
 	if s.config == nil {
@@ -12,6 +12,7 @@ import (
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/version"
 	cephTypes "github.com/canonical/microceph/microceph/api/types"
 	"github.com/canonical/microcluster/v3/microcluster"
 	microTypes "github.com/canonical/microcluster/v3/microcluster/types"
@@ -19,6 +20,7 @@ import (
 
 	"github.com/canonical/microcloud/microcloud/api/types"
 	cloudClient "github.com/canonical/microcloud/microcloud/client"
+	"github.com/canonical/microcloud/microcloud/multicast"
 )
 
 // CloudService is a MicroCloud service.
@@ -45,6 +47,22 @@ type Status struct {
 	Name string `json:"name"    yaml:"name"`
 }
 
+func init() {
+	RegisterService(ServiceRegistration{
+		Type: types.MicroCloud,
+		New: func(name string, addr string, cloudDir string) (Service, error) {
+			service, err := NewCloudService(name, addr, cloudDir)
+			if err != nil {
+				return nil, err
+			}
+
+			return service, nil
+		},
+		Port:     CloudPort,
+		Required: true,
+	})
+}
+
 // NewCloudService creates a new MicroCloud service with a client attached.
 func NewCloudService(name string, addr string, dir string) (*CloudService, error) {
 	client, err := microcluster.App(microcluster.Args{StateDir: dir})
@@ -168,10 +186,8 @@ func (s CloudService) remoteClient(cert *x509.Certificate, address string) (micr
 }
 
 // RequestJoin sends the signal to initiate a join to the remote system, or timeout after a maximum of 5 min.
-func (s CloudService) RequestJoin(ctx context.Context, name string, cert *x509.Certificate, joinConfig types.ServicesPut) error {
-	ctx, cancel := context.WithTimeout(ctx, time.Minute*5)
-	defer cancel()
-
+// The given progress function is called whenever the state of the join operation gets refreshed.
+func (s CloudService) RequestJoin(ctx context.Context, name string, cert *x509.Certificate, joinConfig types.ServicesPut, progress func(op types.Operation)) error {
 	var c microTypes.Client
 	var err error
 	if name == s.name {
@@ -191,7 +207,7 @@ func (s CloudService) RequestJoin(ctx context.Context, name string, cert *x509.C
 		}
 	}
 
-	return cloudClient.JoinServices(ctx, c, joinConfig)
+	return cloudClient.JoinServices(ctx, c, joinConfig, progress)
 }
 
 // RequestJoinIntent send the intent to join the remote cluster.
@@ -209,6 +225,80 @@ func (s CloudService) RequestJoinIntent(ctx context.Context, clusterAddress stri
 	return cloudClient.JoinIntent(ctx, c, intent)
 }
 
+// RemoteCertificate returns the certificate presented by the MicroCloud API of the system at the given address.
+// The certificate isn't verified, so the caller has to check its fingerprint.
+func (s CloudService) RemoteCertificate(ctx context.Context, address string) (*x509.Certificate, error) {
+	return shared.GetRemoteCertificate(ctx, "https://"+util.CanonicalNetworkAddress(address, CloudPort), version.UserAgent)
+}
+
+// SessionBeacon fetches the beacon announced by the session of the system at the given address.
+// The beacon's port is set to the port used for fetching it, as the full info is fetched from the same API.
+// The beacon isn't secret, so the certificate presented by the remote system is trusted as is.
+func (s CloudService) SessionBeacon(ctx context.Context, address string) (*multicast.Beacon, error) {
+	cert, err := s.RemoteCertificate(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get certificate of %q: %w", address, err)
+	}
+
+	c, err := s.remoteClient(cert, address)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err = cloudClient.UseAuthProxy(c, types.MicroCloud, cloudClient.AuthConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	beacon, err := cloudClient.GetSessionBeacon(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	beacon.Port = CloudPort
+
+	return beacon, nil
+}
+
+// ProbeSession hands the given beacon to the joining session of the system at the given address
+// and returns the info of that system.
+// The authorization derived from the nonce of the remote system's beacon is used in lieu of the HMAC.
+func (s CloudService) ProbeSession(ctx context.Context, address string, nonce string, authorization string, beacon multicast.Beacon) (*multicast.ServerInfo, error) {
+	c, err := s.sessionClient(util.CanonicalNetworkAddress(address, CloudPort), authorization)
+	if err != nil {
+		return nil, err
+	}
+
+	return cloudClient.ProbeSession(ctx, c, nonce, beacon)
+}
+
+// FetchSessionInfo fetches the info announced by the session of the system which sent the given beacon.
+// The authorization derived from the beacon is used in lieu of the HMAC.
+func (s CloudService) FetchSessionInfo(ctx context.Context, beacon multicast.Beacon, authorization string) (*multicast.ServerInfo, error) {
+	c, err := s.sessionClient(util.CanonicalNetworkAddress(beacon.Address, beacon.Port), authorization)
+	if err != nil {
+		return nil, err
+	}
+
+	return cloudClient.GetSessionInfo(ctx, c, beacon.Nonce)
+}
+
+// sessionClient returns an https client for the given address:port used for discovery during a trust establishment session.
+func (s CloudService) sessionClient(address string, authorization string) (microTypes.Client, error) {
+	c, err := s.client.RemoteClientWithCert(address, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := cloudClient.AuthConfig{
+		HMAC: authorization,
+		// The certificate of the remote system isn't yet known so we have to skip any TLS verification.
+		InsecureSkipVerify: true,
+	}
+
+	return cloudClient.UseAuthProxy(c, types.MicroCloud, conf)
+}
+
 // RemoteClusterMembers returns a map of cluster member names and addresses from the MicroCloud at the given address.
 // Provide the certificate of the remote server for mTLS.
 func (s CloudService) RemoteClusterMembers(ctx context.Context, cert *x509.Certificate, address string) (map[string]string, error) {
@@ -272,8 +362,22 @@ func clusterMembers(ctx context.Context, c microTypes.Client) (map[string]string
 }
 
 // DeleteClusterMember removes the given cluster member from the service.
-func (s CloudService) DeleteClusterMember(ctx context.Context, name string, force bool) error {
-	return s.client.RemoveClusterMember(ctx, name, "", force)
+func (s CloudService) DeleteClusterMember(ctx context.Context, name string, address string, force bool) error {
+	if address == "" {
+		return s.client.RemoveClusterMember(ctx, name, "", force)
+	}
+
+	c, err := s.client.RemoteClient(util.CanonicalNetworkAddress(address, CloudPort))
+	if err != nil {
+		return err
+	}
+
+	c, err = cloudClient.UseAuthProxy(c, types.MicroCloud, cloudClient.AuthConfig{})
+	if err != nil {
+		return err
+	}
+
+	return cloudClient.RemoveClusterMember(ctx, name, force, c)
 }
 
 // Type returns the type of Service.
@@ -336,6 +440,11 @@ func (s CloudService) IsInitialized(ctx context.Context) (bool, error) {
 	return status.Ready, nil
 }
 
+// Health returns the health of the MicroCloud database on this cluster member.
+func (s CloudService) Health(ctx context.Context) (types.HealthReport, error) {
+	return microclusterHealth(ctx, s.client, s.name)
+}
+
 // SupportsFeature checks if the specified API feature of this Service instance if supported.
 func (s *CloudService) SupportsFeature(ctx context.Context, feature string) (bool, error) {
 	server, err := s.client.Status(ctx)
@@ -32,6 +32,27 @@ type OVNService struct {
 	config  map[string]string
 }
 
+// MicroOVNDir is the path to the state directory of the MicroOVN snap.
+const MicroOVNDir = "/var/snap/microovn/common/state"
+
+func init() {
+	RegisterService(ServiceRegistration{
+		Type: types.MicroOVN,
+		New: func(name string, addr string, cloudDir string) (Service, error) {
+			service, err := NewOVNService(name, addr, cloudDir)
+			if err != nil {
+				return nil, err
+			}
+
+			return service, nil
+		},
+		StateDir:     MicroOVNDir,
+		Socket:       "control.socket",
+		Port:         OVNPort,
+		Dependencies: []types.ServiceType{types.MicroCloud},
+	})
+}
+
 // NewOVNService creates a new MicroOVN service with a client attached.
 func NewOVNService(name string, addr string, cloudDir string) (*OVNService, error) {
 	proxy := func(r *http.Request) (*url.URL, error) {
@@ -160,8 +181,22 @@ func (s OVNService) ClusterMembers(ctx context.Context) (map[string]string, erro
 }
 
 // DeleteClusterMember removes the given cluster member from the service.
-func (s OVNService) DeleteClusterMember(ctx context.Context, name string, force bool) error {
-	return s.m.RemoveClusterMember(ctx, name, "", force)
+func (s OVNService) DeleteClusterMember(ctx context.Context, name string, address string, force bool) error {
+	if address == "" {
+		return s.m.RemoveClusterMember(ctx, name, "", force)
+	}
+
+	c, err := s.m.RemoteClient(util.CanonicalNetworkAddress(address, CloudPort))
+	if err != nil {
+		return err
+	}
+
+	c, err = cloudClient.UseAuthProxy(c, types.MicroOVN, cloudClient.AuthConfig{})
+	if err != nil {
+		return err
+	}
+
+	return cloudClient.RemoveClusterMember(ctx, name, force, c)
 }
 
 // Type returns the type of Service.
@@ -222,6 +257,58 @@ func (s OVNService) IsInitialized(ctx context.Context) (bool, error) {
 	return status.Ready, nil
 }
 
+// Health returns the health of MicroOVN on this cluster member.
+// Besides the MicroOVN database, it reports whether the OVN Northbound and Southbound databases can tolerate the loss of a member.
+// The MicroOVN API doesn't expose the raft cluster status of the databases, so it's reported as unsupported.
+func (s OVNService) Health(ctx context.Context) (types.HealthReport, error) {
+	report, err := microclusterHealth(ctx, s.m, s.name)
+	if err != nil || report.Status == types.HealthUnhealthy {
+		return report, err
+	}
+
+	services, err := s.GetServices(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	// The OVN databases are clustered using raft across all members running the central service.
+	centralCount := 0
+	localCentral := false
+	for _, service := range services {
+		if service.Service == "central" {
+			centralCount++
+		}
+
+		if service.Service == "central" && service.Location == s.name {
+			localCentral = true
+		}
+	}
+
+	if centralCount == 0 {
+		report.Add(types.HealthUnhealthy, "No members run the OVN central service")
+		return report, nil
+	}
+
+	members, err := s.m.GetClusterMembers(ctx)
+	if err != nil {
+		return report, fmt.Errorf("Failed to get cluster members: %w", err)
+	}
+
+	// Clusters with less than 3 members can't be fault tolerant in the first place, which `microcloud status` already reports.
+	if len(members) >= 3 && centralCount < 3 {
+		report.Add(types.HealthDegraded, fmt.Sprintf("OVN databases run on %d members, 3 are required for fault tolerance", centralCount))
+	}
+
+	// Only members running the central service have a copy of the databases.
+	if !localCentral {
+		return report, nil
+	}
+
+	report.AddUnsupported("The raft cluster status of the OVN databases isn't exposed by the MicroOVN API")
+
+	return report, nil
+}
+
 // SetConfig sets the config of this Service instance.
 func (s *OVNService) SetConfig(config map[string]string) {
 	if s.config == nil {
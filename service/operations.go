@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcloud/microcloud/api/types"
+)
+
+// OperationRetention is the duration for which finished operations can still be queried.
+const OperationRetention = time.Hour
+
+// Operation is a long-running task which runs in the background of the MicroCloud daemon.
+// It reports its progress on each of the services it works on.
+type Operation struct {
+	lock   sync.RWMutex
+	op     types.Operation
+	err    error
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// SetStage updates the progress of the operation on the given service.
+func (o *Operation) SetStage(serviceType types.ServiceType, stage types.OperationStage) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.op.Services[serviceType] = stage
+	o.op.UpdatedAt = time.Now()
+}
+
+// Get returns the current state of the operation.
+func (o *Operation) Get() types.Operation {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	op := o.op
+	op.Services = maps.Clone(o.op.Services)
+
+	return op
+}
+
+// Err returns the error of a finished operation.
+func (o *Operation) Err() error {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	return o.err
+}
+
+// Cancel cancels the context of the operation. The operation finishes once its task returned.
+func (o *Operation) Cancel() {
+	o.cancel()
+}
+
+// Done returns a channel which is closed once the operation finished.
+func (o *Operation) Done() <-chan struct{} {
+	return o.done
+}
+
+// Wait waits until the operation finished or the given context is done.
+func (o *Operation) Wait(ctx context.Context) (types.Operation, error) {
+	select {
+	case <-o.done:
+	case <-ctx.Done():
+	}
+
+	return o.Get(), o.Err()
+}
+
+// finish records the result of the operation's task.
+// If the task failed, the services which the operation didn't reach are marked as skipped.
+func (o *Operation) finish(ctx context.Context, err error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.err = err
+	o.op.UpdatedAt = time.Now()
+	if err != nil {
+		for serviceType, stage := range o.op.Services {
+			if stage == types.OperationStagePending {
+				o.op.Services[serviceType] = types.OperationStageSkipped
+			}
+		}
+
+		code, ok := api.StatusErrorMatch(err)
+		if ok {
+			o.op.StatusCode = code
+		}
+	}
+
+	switch {
+	case err == nil:
+		o.op.Status = types.OperationSuccess
+	case errors.Is(context.Cause(ctx), context.Canceled):
+		o.op.Status = types.OperationCancelled
+		o.op.Error = err.Error()
+	default:
+		o.op.Status = types.OperationFailure
+		o.op.Error = err.Error()
+	}
+
+	close(o.done)
+}
+
+// Operations keeps track of the operations of the MicroCloud daemon.
+type Operations struct {
+	lock       sync.Mutex
+	operations map[string]*Operation
+}
+
+// NewOperations returns a new set of operations.
+func NewOperations() *Operations {
+	return &Operations{operations: map[string]*Operation{}}
+}
+
+// Start runs the given task as a new operation in the background.
+// The operation reports its progress on each of the given services, which start in the pending stage.
+// The task's context is cancelled when the operation gets cancelled.
+func (o *Operations) Start(description string, services []types.ServiceType, f func(ctx context.Context, op *Operation) error) *Operation {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	now := time.Now()
+	op := &Operation{
+		op: types.Operation{
+			ID:          rand.Text(),
+			Description: description,
+			Status:      types.OperationRunning,
+			Services:    make(map[types.ServiceType]types.OperationStage, len(services)),
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	for _, serviceType := range services {
+		op.op.Services[serviceType] = types.OperationStagePending
+	}
+
+	o.lock.Lock()
+	o.prune(now)
+	o.operations[op.op.ID] = op
+	o.lock.Unlock()
+
+	go func() {
+		defer cancel()
+
+		err := f(ctx, op)
+		op.finish(ctx, err)
+	}()
+
+	return op
+}
+
+// Get returns the operation with the given ID.
+func (o *Operations) Get(id string) (*Operation, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.prune(time.Now())
+
+	op, ok := o.operations[id]
+	if !ok {
+		return nil, api.StatusErrorf(http.StatusNotFound, "Operation %q not found", id)
+	}
+
+	return op, nil
+}
+
+// prune removes the operations which finished longer ago than the retention period.
+// The caller has to hold the lock.
+func (o *Operations) prune(now time.Time) {
+	for id, op := range o.operations {
+		state := op.Get()
+		if state.Done() && now.Sub(state.UpdatedAt) > OperationRetention {
+			delete(o.operations, id)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/canonical/microcloud/microcloud/api/types"
+)
+
+type operationsSuite struct {
+	suite.Suite
+}
+
+func TestOperationsSuite(t *testing.T) {
+	suite.Run(t, new(operationsSuite))
+}
+
+func (s *operationsSuite) Test_Start() {
+	cases := []struct {
+		desc       string
+		cancel     bool
+		err        error
+		status     types.OperationStatus
+		statusCode int
+	}{
+		{
+			desc:   "Successful operation",
+			status: types.OperationSuccess,
+		},
+		{
+			desc:   "Failed operation",
+			err:    errors.New("Failed"),
+			status: types.OperationFailure,
+		},
+		{
+			desc:       "Failed operation with an API error",
+			err:        api.StatusErrorf(http.StatusNotFound, "Not found"),
+			status:     types.OperationFailure,
+			statusCode: http.StatusNotFound,
+		},
+		{
+			desc:   "Cancelled operation",
+			cancel: true,
+			status: types.OperationCancelled,
+		},
+	}
+
+	for i, c := range cases {
+		s.T().Logf("%d: %s", i, c.desc)
+
+		operations := NewOperations()
+		started := make(chan struct{})
+		op := operations.Start("Test", []types.ServiceType{types.MicroCloud, types.LXD}, func(ctx context.Context, op *Operation) error {
+			op.SetStage(types.MicroCloud, types.OperationStageRunning)
+			close(started)
+
+			if c.cancel {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+
+			op.SetStage(types.MicroCloud, types.OperationStageDone)
+			if c.err == nil {
+				op.SetStage(types.LXD, types.OperationStageDone)
+			}
+
+			return c.err
+		})
+
+		<-started
+		if c.cancel {
+			op.Cancel()
+		}
+
+		result, err := op.Wait(context.Background())
+		s.Equal(c.status, result.Status)
+		s.True(result.Done())
+
+		if c.status == types.OperationSuccess {
+			s.NoError(err)
+			s.Equal(types.OperationStageDone, result.Services[types.MicroCloud])
+		} else {
+			s.Error(err)
+			s.Equal(err.Error(), result.Error)
+			s.Equal(c.statusCode, result.StatusCode)
+
+			// The operation didn't reach the second service.
+			s.Equal(types.OperationStageSkipped, result.Services[types.LXD])
+		}
+
+		stored, err := operations.Get(result.ID)
+		s.NoError(err)
+		s.Equal(result, stored.Get())
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"bufio"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// MinPassphraseWordCount is the minimum number of words in a passphrase.
+	MinPassphraseWordCount uint8 = 4
+
+	// MaxPassphraseWordCount is the maximum number of words in a passphrase.
+	MaxPassphraseWordCount uint8 = 8
+
+	// DefaultWordlist is the name of the built-in wordlist.
+	DefaultWordlist = "eff-short"
+
+	// WordlistsDir is the directory inside of MicroCloud's state directory containing additional wordlists.
+	// Each wordlist is stored in a file named after the wordlist with the ".txt" extension.
+	WordlistsDir = "wordlists"
+
+	// MinWordlistSize is the minimum number of unique words in an additional wordlist.
+	// This keeps the entropy of a passphrase close to the one of a passphrase using the built-in wordlist.
+	MinWordlistSize = 1024
+)
+
+// ValidatePassphraseWordCount checks whether a passphrase can consist of the given number of words.
+func ValidatePassphraseWordCount(count uint8) error {
+	if count < MinPassphraseWordCount || count > MaxPassphraseWordCount {
+		return fmt.Errorf("Passphrase has to contain between %d and %d words", MinPassphraseWordCount, MaxPassphraseWordCount)
+	}
+
+	return nil
+}
+
+// LoadWordlist returns the words of the wordlist with the given name.
+// An empty name selects the built-in wordlist. Any other wordlist is read from the wordlists directory
+// inside of the given state directory.
+func LoadWordlist(stateDir string, name string) ([]string, error) {
+	if name == "" || name == DefaultWordlist {
+		return Wordlist, nil
+	}
+
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return nil, fmt.Errorf("Invalid wordlist name %q", name)
+		}
+	}
+
+	f, err := os.Open(filepath.Join(stateDir, WordlistsDir, name+".txt"))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open wordlist %q: %w", name, err)
+	}
+
+	defer f.Close()
+
+	words, err := parseWordlist(f)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid wordlist %q: %w", name, err)
+	}
+
+	return words, nil
+}
+
+// parseWordlist reads a wordlist containing a single word per line.
+// Empty lines and lines starting with "#" are skipped. If a line contains multiple fields, for example the dice rolls
+// of the EFF wordlists, only the last field is used.
+func parseWordlist(r io.Reader) ([]string, error) {
+	words := []string{}
+	seen := map[string]bool{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		word := fields[len(fields)-1]
+		if seen[word] {
+			continue
+		}
+
+		seen[word] = true
+		words = append(words, word)
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(words) < MinWordlistSize {
+		return nil, fmt.Errorf("Wordlist contains %d unique words but requires at least %d", len(words), MinWordlistSize)
+	}
+
+	return words, nil
+}
+
+// GeneratePassphrase returns the given number of random words chosen from the given wordlist.
+// The words are separated by space.
+func GeneratePassphrase(wordlist []string, count uint8) (string, error) {
+	var randomWords = make([]string, count)
+	for i := range count {
+		randomNumber, err := rand.Int(rand.Reader, big.NewInt(int64(len(wordlist))))
+		if err != nil {
+			return "", fmt.Errorf("Failed to get random number: %w", err)
+		}
+
+		randomWords[i] = wordlist[randomNumber.Int64()]
+	}
+
+	return strings.Join(randomWords, " "), nil
+}
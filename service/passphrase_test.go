@@ -0,0 +1,78 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type passphraseSuite struct {
+	suite.Suite
+}
+
+func TestPassphraseSuite(t *testing.T) {
+	suite.Run(t, new(passphraseSuite))
+}
+
+// numberedWordlist returns a wordlist of the given number of unique words.
+func numberedWordlist(count int) []string {
+	words := make([]string, 0, count)
+	for i := range count {
+		words = append(words, fmt.Sprintf("word%d", i))
+	}
+
+	return words
+}
+
+func (s *passphraseSuite) Test_parseWordlist() {
+	cases := []struct {
+		desc        string
+		content     string
+		expectWords []string
+		expectErr   bool
+	}{
+		{
+			desc:        "Single word per line",
+			content:     strings.Join(numberedWordlist(MinWordlistSize), "\n"),
+			expectWords: numberedWordlist(MinWordlistSize),
+		},
+		{
+			desc:        "Dice rolls, comments and empty lines",
+			content:     "# Wordlist\n\n" + "1111\t" + strings.Join(numberedWordlist(MinWordlistSize), "\n1111\t") + "\n",
+			expectWords: numberedWordlist(MinWordlistSize),
+		},
+		{
+			desc:      "Too few unique words",
+			content:   strings.Join(numberedWordlist(MinWordlistSize-1), "\n") + "\nword0",
+			expectErr: true,
+		},
+	}
+
+	for i, c := range cases {
+		s.T().Logf("%d: %s", i, c.desc)
+
+		words, err := parseWordlist(strings.NewReader(c.content))
+		if c.expectErr {
+			s.Error(err)
+			continue
+		}
+
+		s.NoError(err)
+		s.Equal(c.expectWords, words)
+	}
+}
+
+func (s *passphraseSuite) Test_GeneratePassphrase() {
+	for count := MinPassphraseWordCount; count <= MaxPassphraseWordCount; count++ {
+		s.NoError(ValidatePassphraseWordCount(count))
+
+		passphrase, err := GeneratePassphrase(numberedWordlist(MinWordlistSize), count)
+		s.NoError(err)
+		s.Len(strings.Fields(passphrase), int(count))
+	}
+
+	s.Error(ValidatePassphraseWordCount(MinPassphraseWordCount - 1))
+	s.Error(ValidatePassphraseWordCount(MaxPassphraseWordCount + 1))
+}
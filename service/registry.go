@@ -0,0 +1,139 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/canonical/microcloud/microcloud/api/types"
+)
+
+// ServiceRegistration describes a service implementation which can be clustered by MicroCloud.
+type ServiceRegistration struct {
+	// Type is the unique type of the service.
+	Type types.ServiceType
+
+	// New returns a client for the service on the system with the given name and address.
+	// The given directory is the state directory of MicroCloud.
+	New func(name string, addr string, cloudDir string) (Service, error)
+
+	// StateDir is the state directory of the service on the local system.
+	// It's empty if the service is part of MicroCloud itself.
+	StateDir string
+
+	// Socket is the unix socket inside of the state directory which is probed to detect whether the service is installed.
+	Socket string
+
+	// Port is the default port of the service.
+	Port int64
+
+	// Required is true if MicroCloud cannot be set up without the service.
+	Required bool
+
+	// Dependencies are the services which have to be set up before this service when forming or joining a cluster.
+	// A cluster member is removed from this service before it's removed from its dependencies.
+	Dependencies []types.ServiceType
+}
+
+// Installed returns true if the service's unix socket exists in its state directory on the local system.
+func (r ServiceRegistration) Installed() bool {
+	if r.StateDir == "" {
+		return true
+	}
+
+	_, err := os.Stat(filepath.Join(r.StateDir, r.Socket))
+
+	return err == nil
+}
+
+var registryMu sync.RWMutex
+var registry = map[types.ServiceType]ServiceRegistration{}
+
+// RegisterService makes the given service implementation available to MicroCloud.
+// It's meant to be called during package initialization, so it panics if the registration is invalid
+// or conflicts with an already registered service.
+func RegisterService(r ServiceRegistration) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if r.Type == "" || r.New == nil {
+		panic("Service registration is missing its type or constructor")
+	}
+
+	_, ok := registry[r.Type]
+	if ok {
+		panic(fmt.Sprintf("Service %q is already registered", r.Type))
+	}
+
+	if slices.Contains(r.Dependencies, r.Type) {
+		panic(fmt.Sprintf("Service %q cannot depend on itself", r.Type))
+	}
+
+	registry[r.Type] = r
+}
+
+// LookupService returns the registration of the given service type.
+func LookupService(serviceType types.ServiceType) (ServiceRegistration, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	r, ok := registry[serviceType]
+
+	return r, ok
+}
+
+// RegisteredServices returns the registrations of all services sorted by their type.
+func RegisteredServices() []ServiceRegistration {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	registrations := make([]ServiceRegistration, 0, len(registry))
+	for _, r := range registry {
+		registrations = append(registrations, r)
+	}
+
+	slices.SortFunc(registrations, func(a ServiceRegistration, b ServiceRegistration) int {
+		return strings.Compare(string(a.Type), string(b.Type))
+	})
+
+	return registrations
+}
+
+// RequiredServices returns the types of all services which MicroCloud cannot be set up without.
+func RequiredServices() []types.ServiceType {
+	services := []types.ServiceType{}
+	for _, r := range RegisteredServices() {
+		if r.Required {
+			services = append(services, r.Type)
+		}
+	}
+
+	return services
+}
+
+// OptionalServices returns the registrations of all services which MicroCloud can be set up without.
+func OptionalServices() []ServiceRegistration {
+	registrations := []ServiceRegistration{}
+	for _, r := range RegisteredServices() {
+		if !r.Required {
+			registrations = append(registrations, r)
+		}
+	}
+
+	return registrations
+}
+
+// InstalledServices returns the types of all services installed on the local system.
+func InstalledServices() []types.ServiceType {
+	services := []types.ServiceType{}
+	for _, r := range RegisteredServices() {
+		if r.Installed() {
+			services = append(services, r.Type)
+		}
+	}
+
+	return services
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/canonical/microcloud/microcloud/api/types"
+)
+
+type registrySuite struct {
+	suite.Suite
+}
+
+func TestRegistrySuite(t *testing.T) {
+	suite.Run(t, new(registrySuite))
+}
+
+func (s *registrySuite) Test_builtinServices() {
+	s.Equal([]types.ServiceType{types.LXD, types.MicroCloud}, RequiredServices())
+
+	lxd, ok := LookupService(types.LXD)
+	s.True(ok)
+	s.ElementsMatch([]types.ServiceType{types.MicroCloud, types.MicroCeph, types.MicroOVN}, lxd.Dependencies)
+}
+
+func (s *registrySuite) Test_RegisterService() {
+	newService := func(name string, addr string, cloudDir string) (Service, error) {
+		return nil, nil
+	}
+
+	cases := []struct {
+		desc         string
+		registration ServiceRegistration
+	}{
+		{
+			desc:         "Missing constructor",
+			registration: ServiceRegistration{Type: "MicroK8s"},
+		},
+		{
+			desc:         "Already registered service",
+			registration: ServiceRegistration{Type: types.MicroCeph, New: newService},
+		},
+		{
+			desc:         "Service depending on itself",
+			registration: ServiceRegistration{Type: "MicroK8s", New: newService, Dependencies: []types.ServiceType{"MicroK8s"}},
+		},
+	}
+
+	for i, c := range cases {
+		s.T().Logf("%d: %s", i, c.desc)
+
+		s.Panics(func() { RegisterService(c.registration) })
+	}
+}
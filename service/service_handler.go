@@ -2,6 +2,7 @@ package service
 
 import (
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -10,9 +11,11 @@ import (
 	"time"
 
 	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
 
 	"github.com/canonical/microcloud/microcloud/api/types"
 	cloudClient "github.com/canonical/microcloud/microcloud/client"
+	"github.com/canonical/microcloud/microcloud/multicast"
 )
 
 const (
@@ -41,9 +44,31 @@ type Handler struct {
 	Name     string
 	Port     int64
 
+	// DiscoveryGroup is the default multicast group configuration used by sessions.
+	DiscoveryGroup multicast.GroupConfig
+
+	// DiscoveryRealm is the default realm used by sessions.
+	DiscoveryRealm string
+
+	// VersionPolicy defines the version skew of services allowed for joining systems.
+	VersionPolicy VersionPolicy
+
 	sessionLock sync.RWMutex
 	Session     *Session
 
+	// sessionStatePath is the file to which the state of initiating sessions is persisted.
+	// Sessions aren't persisted if it's empty.
+	sessionStatePath string
+
+	// SessionLog records the events of all sessions started on this handler.
+	SessionLog *SessionLog
+
+	// SessionObservers receives the messages of all sessions started on this handler.
+	SessionObservers *SessionObservers
+
+	// Operations are the long-running operations started on this handler.
+	Operations *Operations
+
 	initMu  sync.RWMutex
 	address string
 }
@@ -52,21 +77,12 @@ type Handler struct {
 func NewHandler(name string, addr string, stateDir string, services ...types.ServiceType) (*Handler, error) {
 	servicesMap := make(map[types.ServiceType]Service, len(services))
 	for _, serviceType := range services {
-		var service Service
-		var err error
-		switch serviceType {
-		case types.MicroCloud:
-			service, err = NewCloudService(name, addr, stateDir)
-		case types.MicroCeph:
-			service, err = NewCephService(name, addr, stateDir)
-		case types.MicroOVN:
-			service, err = NewOVNService(name, addr, stateDir)
-		case types.LXD:
-			service, err = NewLXDService(name, addr, stateDir)
-		default:
+		registration, ok := LookupService(serviceType)
+		if !ok {
 			return nil, api.StatusErrorf(http.StatusNotFound, "Invalid service type: %q", serviceType)
 		}
 
+		service, err := registration.New(name, addr, stateDir)
 		if err != nil {
 			return nil, fmt.Errorf("Failed to create %q service: %w", serviceType, err)
 		}
@@ -79,75 +95,160 @@ func NewHandler(name string, addr string, stateDir string, services ...types.Ser
 		Name:     name,
 		address:  addr,
 		Port:     CloudPort,
+
+		DiscoveryGroup: multicast.GroupConfig{Port: CloudMulticastPort},
+		VersionPolicy:  NewVersionPolicy(),
+		SessionLog:     &SessionLog{},
+
+		SessionObservers: NewSessionObservers(),
+		Operations:       NewOperations(),
 	}, nil
 }
 
-// RunConcurrent runs the given hook concurrently across all services.
-// If firstService or lastService are empty strings, they will be ignored and all services will run concurrently.
-func (s *Handler) RunConcurrent(firstService types.ServiceType, lastService types.ServiceType, f func(s Service) error) error {
-	errors := make([]error, 0, len(s.Services))
-	mut := sync.Mutex{}
-	wg := sync.WaitGroup{}
+// RunConcurrent runs the given hook across all services in the given order.
+// Services which don't depend on each other run concurrently. The returned error contains every service
+// on which the hook failed, or which got skipped because the hook failed on a service it depends on.
+func (s *Handler) RunConcurrent(order RunOrder, f func(s Service) error) error {
+	waitsFor := make(map[types.ServiceType][]types.ServiceType, len(s.Services))
+	if order != RunParallel {
+		for serviceType := range s.Services {
+			registration, ok := LookupService(serviceType)
+			if !ok {
+				return fmt.Errorf("Service %q isn't registered", serviceType)
+			}
 
-	first, ok := s.Services[firstService]
-	if ok {
-		err := f(first)
-		if err != nil {
-			return err
+			for _, dependency := range registration.Dependencies {
+				// Ignore dependencies which aren't installed.
+				_, ok := s.Services[dependency]
+				if !ok {
+					continue
+				}
+
+				if order == RunDependenciesFirst {
+					waitsFor[serviceType] = append(waitsFor[serviceType], dependency)
+				} else {
+					waitsFor[dependency] = append(waitsFor[dependency], serviceType)
+				}
+			}
 		}
 	}
 
-	for _, s := range s.Services {
-		if s.Type() == firstService || s.Type() == lastService {
-			continue
-		}
+	return runGraph(s.Services, waitsFor, f)
+}
 
-		wg.Add(1)
-		go func(s Service) {
-			defer wg.Done()
-			err := f(s)
-			if err != nil {
-				mut.Lock()
-				errors = append(errors, err)
-				mut.Unlock()
-				return
-			}
-		}(s)
+// StartSession starts a new local trust establishment session using the given start message.
+// If the session interrupted by the last restart of the daemon is still active, it's resumed if the start message
+// requests to resume it, or stopped if it requests to discard it. Otherwise an error is returned as starting a new
+// session would discard the join intents registered with the interrupted one.
+func (s *Handler) StartSession(role types.SessionRole, passphrase string, start types.Session, gw *cloudClient.WebsocketGateway) error {
+	s.sessionLock.Lock()
+	defer s.sessionLock.Unlock()
+
+	var interrupted *Session
+	if s.Session != nil && s.Session.Active() && s.Session.Detached() {
+		interrupted = s.Session
 	}
 
-	// Wait for all queries to complete and check for any errors.
-	wg.Wait()
-	for _, err := range errors {
+	if interrupted != nil && start.DiscardInterrupted {
+		err := interrupted.Stop(nil)
 		if err != nil {
-			return err
+			return fmt.Errorf("Failed to discard interrupted session: %w", err)
 		}
+
+		interrupted = nil
 	}
 
-	last, ok := s.Services[lastService]
-	if ok {
-		err := f(last)
-		if err != nil {
-			return err
+	if interrupted != nil {
+		if !start.Resume {
+			deadline, _ := interrupted.Deadline()
+			return fmt.Errorf("The session interrupted by a restart accepts joiners until %s, it has to be resumed or discarded explicitly", deadline.Format(time.RFC3339))
 		}
+
+		if interrupted.Role() != role {
+			return fmt.Errorf("Cannot resume the interrupted %s session as %s session", interrupted.Role(), role)
+		}
+
+		// Only the hash of the passphrase got persisted, so the client has to provide the passphrase again.
+		if passphrase == "" {
+			return errors.New("The passphrase of the interrupted session is required to resume it")
+		}
+
+		if !interrupted.matchesPassphrase(passphrase) {
+			return errors.New("Passphrase doesn't match the one of the interrupted session")
+		}
+
+		interrupted.attach(gw, passphrase)
+
+		return nil
 	}
 
-	return nil
-}
+	if start.Resume {
+		return errors.New("No interrupted session to resume")
+	}
 
-// StartSession starts a new local trust establishment session.
-func (s *Handler) StartSession(role types.SessionRole, passphrase string, gw *cloudClient.WebsocketGateway) error {
 	session, err := NewSession(role, passphrase, gw)
 	if err != nil {
 		return err
 	}
 
-	s.sessionLock.Lock()
+	// Only the initiator's session is persisted as a joiner has to look up the initiator again after a restart.
+	if role == types.SessionInitiating && s.sessionStatePath != "" {
+		session.enablePersistence(s.sessionStatePath)
+	}
+
 	s.Session = session
-	s.sessionLock.Unlock()
 
 	return nil
 }
 
+// RestoreSession persists the state of sessions inside of the given state directory and restores the trust
+// establishment session which got interrupted by a restart of the daemon.
+// The restored session keeps its join intents but only accepts new ones once a client resumed it using its passphrase.
+// It's stopped once it times out unless a client resumed it.
+// It returns nil if there isn't any session to restore.
+func (s *Handler) RestoreSession(stateDir string) (*Session, error) {
+	statePath := filepath.Join(stateDir, SessionStateFile)
+	state, err := loadSessionState(statePath, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	s.sessionLock.Lock()
+	defer s.sessionLock.Unlock()
+
+	s.sessionStatePath = statePath
+	if state == nil {
+		return nil, nil
+	}
+
+	session, err := restoreSession(statePath, state)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to restore interrupted session: %w", err)
+	}
+
+	s.Session = session
+	time.AfterFunc(time.Until(state.Deadline), func() {
+		s.expireSession(session)
+	})
+
+	return session, nil
+}
+
+// expireSession stops the given restored session if it wasn't resumed by a client before it timed out.
+func (s *Handler) expireSession(session *Session) {
+	s.sessionLock.Lock()
+	defer s.sessionLock.Unlock()
+
+	if s.Session != session || !session.Active() || !session.Detached() {
+		return
+	}
+
+	err := session.Stop(nil)
+	if err != nil {
+		logger.Warn("Failed to stop timed out session", logger.Ctx{"err": err})
+	}
+}
+
 // StopSession stops the current session started on this handler.
 // If there isn't an active session it's a no-op.
 func (s *Handler) StopSession(cause error) error {
@@ -174,6 +275,18 @@ func (s *Handler) ActiveSession() bool {
 	return err == nil
 }
 
+// InterruptedSession returns true if the active trust establishment session got interrupted by a restart
+// of the daemon and no client resumed it yet.
+func (s *Handler) InterruptedSession() bool {
+	interrupted := false
+	_ = s.SessionTransaction(true, func(session *Session) error {
+		interrupted = session.Detached()
+		return nil
+	})
+
+	return interrupted
+}
+
 // SessionTransaction allows running f within the current handler's session.
 // It allows running multiple operations on the handler's session struct without always
 // checking if the session is still alive.
@@ -188,7 +301,7 @@ func (s *Handler) SessionTransaction(readOnly bool, f func(session *Session) err
 		defer s.sessionLock.Unlock()
 	}
 
-	if s.Session == nil || s.Session != nil && s.Session.Passphrase() == "" {
+	if s.Session == nil || !s.Session.Active() {
 		return api.NewStatusError(http.StatusBadRequest, "No active session")
 	}
 
@@ -208,18 +321,6 @@ func (s *Handler) TemporaryTrustStore() map[string]x509.Certificate {
 	return trustStore
 }
 
-// Exists returns true if we can stat the unix socket in the state directory of the given service.
-func Exists(service types.ServiceType, stateDir string) bool {
-	socketPath := filepath.Join(stateDir, "control.socket")
-	if service == types.LXD {
-		socketPath = filepath.Join(stateDir, "unix.socket")
-	}
-
-	_, err := os.Stat(socketPath)
-
-	return err == nil
-}
-
 // Address gets the address used for the MicroCloud API.
 func (s *Handler) Address() string {
 	s.initMu.RLock()
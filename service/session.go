@@ -1,52 +1,71 @@
 package service
 
 import (
-	"crypto/rand"
+	"context"
 	"crypto/x509"
 	"errors"
 	"fmt"
-	"math/big"
+	"io/fs"
+	"os"
 	"slices"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/trust"
 
 	"github.com/canonical/microcloud/microcloud/api/types"
 	cloudClient "github.com/canonical/microcloud/microcloud/client"
 	"github.com/canonical/microcloud/microcloud/multicast"
 )
 
-// AllowedFailedJoinAttempts contains the number of allowed failed session join attempts.
+// AllowedFailedJoinAttempts contains the number of allowed failed session join attempts of all sources.
+// Each source is limited individually too, so this only stops the session if many sources fail.
 const AllowedFailedJoinAttempts uint8 = 50
 
+// maxIncompatibleSystems is the number of distinct incompatible systems reported during a session.
+// Further systems are only logged to not let unauthenticated queries grow the session's state.
+const maxIncompatibleSystems = 64
+
 // Session represents a local trust establishment session.
 type Session struct {
 	lock           sync.RWMutex
 	passphrase     string
+	stopped        bool
 	trustStore     map[string]x509.Certificate
 	failedAttempts uint8
+	limiter        *sourceLimiter
 	gw             *cloudClient.WebsocketGateway
 	role           types.SessionRole
-	discovery      *multicast.Discovery
+	discoveries    []*multicast.Discovery
+	realm          string
+
+	// ctx is the context in which the session's discovery responders run.
+	// The context of a restored session isn't tied to any client as it outlives the client which resumes it.
+	ctx      context.Context
+	cancel   context.CancelFunc
+	deadline time.Time
 
+	// start and info are the start message of the session and the info it announces during discovery.
+	start types.Session
+	info  multicast.ServerInfo
+
+	allowedFingerprints    []string
 	joinIntentFingerprints []string
+	registeredIntents      []types.SessionJoinPost
+	restoredIntents        []types.SessionJoinPost
 	joinIntents            chan types.SessionJoinPost
+	probes                 chan multicast.Beacon
+	incompatibleSystems    chan types.SessionSystem
+	reportedSystems        map[string]bool
 	exit                   chan bool
-}
-
-// generatePassphrase returns four random words chosen from wordlist.
-// The words are separated by space.
-func generatePassphrase() (string, error) {
-	var randomWords = make([]string, PassphraseWordCount)
-	for i := range PassphraseWordCount {
-		randomNumber, err := rand.Int(rand.Reader, big.NewInt(int64(len(Wordlist))))
-		if err != nil {
-			return "", fmt.Errorf("Failed to get random number: %w", err)
-		}
-
-		randomWords[i] = Wordlist[randomNumber.Int64()]
-	}
 
-	return strings.Join(randomWords, " "), nil
+	// statePath is the file to which the session's state is persisted so that it can be resumed after a restart.
+	// The state isn't persisted if it's empty.
+	// Only the hash of the passphrase is persisted, so a restored session doesn't know its passphrase until it's resumed.
+	statePath      string
+	passphraseHash *passphraseHash
 }
 
 // NewSession returns a new local trust establishment session.
@@ -54,24 +73,37 @@ func NewSession(role types.SessionRole, passphrase string, gw *cloudClient.Webso
 	var err error
 
 	if passphrase == "" {
-		passphrase, err = generatePassphrase()
+		passphrase, err = GeneratePassphrase(Wordlist, PassphraseWordCount)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	ctx, cancel := context.WithCancel(gw.Context())
+	deadline, _ := gw.Context().Deadline()
+
 	return &Session{
 		passphrase: passphrase,
 		trustStore: make(map[string]x509.Certificate),
+		limiter:    newSourceLimiter(),
 		gw:         gw,
 		role:       role,
+		ctx:        ctx,
+		cancel:     cancel,
+		deadline:   deadline,
 
 		joinIntents: make(chan types.SessionJoinPost),
+		probes:      make(chan multicast.Beacon),
 		exit:        make(chan bool),
+
+		// Each system is only reported once so the buffer never fills up.
+		incompatibleSystems: make(chan types.SessionSystem, maxIncompatibleSystems),
+		reportedSystems:     make(map[string]bool),
 	}, nil
 }
 
 // Passphrase returns the passphrase of the current trust establishment session.
+// It's empty if the session got restored after a restart of the daemon and no client resumed it yet.
 func (s *Session) Passphrase() string {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
@@ -79,6 +111,14 @@ func (s *Session) Passphrase() string {
 	return s.passphrase
 }
 
+// Active returns true until the current trust establishment session gets stopped.
+func (s *Session) Active() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return !s.stopped
+}
+
 // Role returns the role of the current trust establishment session.
 func (s *Session) Role() types.SessionRole {
 	s.lock.RLock()
@@ -87,29 +127,110 @@ func (s *Session) Role() types.SessionRole {
 	return s.role
 }
 
-// MulticastDiscovery starts a new multicast discovery listener in the current trust establishment session.
-func (s *Session) MulticastDiscovery(name string, address string, ifaceName string) error {
-	info := multicast.ServerInfo{
-		Version: multicast.Version,
-		Name:    name,
-		Address: address,
-	}
+// Detached returns true if the current trust establishment session got restored after a restart of the daemon
+// and no client resumed it yet.
+func (s *Session) Detached() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.gw == nil
+}
+
+// SetAnnouncement records the start message of the current trust establishment session and the info it announces
+// during discovery, so that the session can be announced again after a restart of the daemon.
+func (s *Session) SetAnnouncement(start types.Session, info multicast.ServerInfo) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Only the hash of the passphrase is persisted.
+	start.Passphrase = ""
 
-	s.discovery = multicast.NewDiscovery(ifaceName, CloudMulticastPort)
-	err := s.discovery.Respond(s.gw.Context(), info)
+	s.start = start
+	s.info = info
+	s.persist()
+}
+
+// Announcement returns the start message of the current trust establishment session and the info it announces
+// during discovery.
+func (s *Session) Announcement() (types.Session, multicast.ServerInfo) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.start, s.info
+}
+
+// MulticastDiscovery starts a new discovery listener using the given backend in the current trust establishment session.
+// It can be called multiple times to respond on several interfaces, each announcing the info with its own address.
+// The beacon points to the API on the given port which serves the full info, see DiscoveryInfo.
+// The info is signed using the given HMAC which should be derived from the session's passphrase.
+func (s *Session) MulticastDiscovery(info multicast.ServerInfo, port int64, backend multicast.Backend, signer trust.HMACFormatter) error {
+	discovery := multicast.NewDiscoveryWithBackend(backend, signer, nil)
+	discovery.ReportQueries(s.reportQuery)
+	err := discovery.Respond(s.ctx, info, port)
 	if err != nil {
 		return err
 	}
 
+	s.lock.Lock()
+	s.discoveries = append(s.discoveries, discovery)
+	s.realm = info.Realm
+	s.lock.Unlock()
+
 	return nil
 }
 
+// Realm returns the realm announced by the discovery listener of the current trust establishment session.
+func (s *Session) Realm() string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.realm
+}
+
+// DiscoveryBeacon returns the beacon announced by the discovery listener of the current trust establishment session.
+// It's fetched by joiners which already know the initiator's address, and by initiators probing this system using static discovery.
+func (s *Session) DiscoveryBeacon() (*multicast.Beacon, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if len(s.discoveries) == 0 {
+		return nil, errors.New("No active discovery")
+	}
+
+	// Static discovery doesn't support additional interfaces, so there is a single listener.
+	return s.discoveries[0].Beacon()
+}
+
+// DiscoveryInfo returns the info announced by the discovery listener of the current trust establishment session
+// if the given nonce and authorization are valid.
+func (s *Session) DiscoveryInfo(nonce string, authorization string) (*multicast.ServerInfo, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if len(s.discoveries) == 0 {
+		return nil, errors.New("No active discovery")
+	}
+
+	// Each listener uses its own nonce, so at most one of them accepts the request.
+	var err error
+	for _, discovery := range s.discoveries {
+		var info *multicast.ServerInfo
+		info, err = discovery.Info(nonce, authorization)
+		if err == nil {
+			return info, nil
+		}
+	}
+
+	return nil, err
+}
+
 // Allow grants access via the temporary trust store to the given certificate.
 func (s *Session) Allow(name string, cert x509.Certificate) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
 	s.trustStore[name] = cert
+	s.persist()
 }
 
 // TemporaryTrustStore returns the temporary truststore of the current trust establishment session.
@@ -126,31 +247,198 @@ func (s *Session) TemporaryTrustStore() map[string]x509.Certificate {
 	return trustStoreCopy
 }
 
-// RegisterIntent registers the intention to join during the current trust establishment session
+// RegisterIntent registers the given intention to join during the current trust establishment session
 // for the given fingerprint.
-func (s *Session) RegisterIntent(fingerprint string) error {
+func (s *Session) RegisterIntent(fingerprint string, intent types.SessionJoinPost) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	if len(s.allowedFingerprints) > 0 && !slices.Contains(s.allowedFingerprints, fingerprint) {
+		return fmt.Errorf("Fingerprint %q isn't allowed to join", fingerprint)
+	}
+
 	if slices.Contains(s.joinIntentFingerprints, fingerprint) {
 		return errors.New("Fingerprint already exists")
 	}
 
 	s.joinIntentFingerprints = append(s.joinIntentFingerprints, fingerprint)
+	s.registeredIntents = append(s.registeredIntents, intent)
+	s.persist()
+
 	return nil
 }
 
-// RegisterFailedAttempt registers a failed attempt trying to join the current trust establishment session.
-func (s *Session) RegisterFailedAttempt() error {
+// AllowFingerprints restricts the join intents accepted in the current trust establishment session
+// to the ones presenting a certificate with any of the given fingerprints.
+// If no fingerprints are given, intents with any certificate are accepted.
+func (s *Session) AllowFingerprints(fingerprints []string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.allowedFingerprints = fingerprints
+	s.persist()
+}
+
+// JoinIntentFingerprints returns the fingerprints of the join intents registered during the current trust establishment session.
+func (s *Session) JoinIntentFingerprints() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return slices.Clone(s.joinIntentFingerprints)
+}
+
+// FailedAttempts returns the number of failed attempts to join the current trust establishment session.
+func (s *Session) FailedAttempts() uint8 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.failedAttempts
+}
+
+// Deadline returns the time at which the current trust establishment session times out.
+// The returned bool is false if the session doesn't time out.
+func (s *Session) Deadline() (time.Time, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.deadline, !s.deadline.IsZero()
+}
+
+// SourceBannedUntil returns the time until which the given source is banned from the current trust establishment session.
+// The returned bool is false if the source isn't banned.
+func (s *Session) SourceBannedUntil(source string) (time.Time, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.limiter.bannedUntil(source, time.Now())
+}
+
+// RegisterFailedAttempt registers a failed attempt of the given source trying to join the current trust establishment session.
+// The returned bool is true if the source got banned temporarily due to exceeding its own failed attempts.
+// An error is returned if the number of failed attempts of all sources is exceeded.
+func (s *Session) RegisterFailedAttempt(source string) (bool, error) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	banned := s.limiter.fail(source, time.Now())
+
 	if s.failedAttempts == AllowedFailedJoinAttempts {
-		return errors.New("Exceeded the number of failed session join attempts")
+		return banned, errors.New("Exceeded the number of failed session join attempts")
 	}
 
 	s.failedAttempts++
-	return nil
+	return banned, nil
+}
+
+// RestoredIntents returns the join intents which were registered before the current trust establishment session
+// got resumed. They have to be offered to the client as neither the previous client nor this one confirmed them.
+func (s *Session) RestoredIntents() []types.SessionJoinPost {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return slices.Clone(s.restoredIntents)
+}
+
+// enablePersistence persists the state of the current trust establishment session to the given path
+// whenever it changes.
+// Failures are only logged as the session can continue without being resumable.
+func (s *Session) enablePersistence(path string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	hash, err := hashPassphrase(s.passphrase)
+	if err != nil {
+		logger.Warn("Failed to persist session state", logger.Ctx{"err": err})
+		return
+	}
+
+	s.statePath = path
+	s.passphraseHash = hash
+	s.persist()
+}
+
+// matchesPassphrase returns true if the given passphrase is the one of the trust establishment session
+// which got restored after a restart of the daemon.
+func (s *Session) matchesPassphrase(passphrase string) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.passphraseHash != nil && s.passphraseHash.matches(passphrase)
+}
+
+// restoreSession returns the trust establishment session interrupted by a restart of the daemon from the state
+// persisted at the given path. It keeps the join intents registered before the restart until it gets resumed.
+// As only the hash of its passphrase was persisted, it cannot verify any requests until a client resumes it.
+func restoreSession(path string, state *sessionState) (*Session, error) {
+	trustStore, err := decodeTrustStore(state.TrustStore)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Session{
+		trustStore:     trustStore,
+		limiter:        newSourceLimiter(),
+		role:           state.Role,
+		ctx:            ctx,
+		cancel:         cancel,
+		deadline:       state.Deadline,
+		start:          state.Start,
+		info:           state.Info,
+		statePath:      path,
+		passphraseHash: &state.PassphraseHash,
+
+		allowedFingerprints:    state.AllowedFingerprints,
+		joinIntentFingerprints: state.JoinIntentFingerprints,
+		registeredIntents:      state.JoinIntents,
+
+		joinIntents: make(chan types.SessionJoinPost),
+		probes:      make(chan multicast.Beacon),
+		exit:        make(chan bool),
+
+		incompatibleSystems: make(chan types.SessionSystem, maxIncompatibleSystems),
+		reportedSystems:     make(map[string]bool),
+	}, nil
+}
+
+// attach attaches the client of the given websocket to the current trust establishment session
+// which got restored after a restart of the daemon. The client has to provide the session's passphrase,
+// see matchesPassphrase.
+// The join intents registered so far are offered to the client, see RestoredIntents.
+func (s *Session) attach(gw *cloudClient.WebsocketGateway, passphrase string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.gw = gw
+	s.passphrase = passphrase
+	s.deadline, _ = gw.Context().Deadline()
+	s.restoredIntents = slices.Clone(s.registeredIntents)
+	s.persist()
+}
+
+// persist writes the state of the current trust establishment session to its state file.
+// Failures are only logged as the session can continue without being resumable.
+// The caller has to hold the session's lock.
+func (s *Session) persist() {
+	if s.statePath == "" || s.passphraseHash == nil {
+		return
+	}
+
+	err := writeSessionState(s.statePath, sessionState{
+		Role:                   s.role,
+		PassphraseHash:         *s.passphraseHash,
+		Start:                  s.start,
+		Info:                   s.info,
+		Deadline:               s.deadline,
+		TrustStore:             encodeTrustStore(s.trustStore),
+		AllowedFingerprints:    s.allowedFingerprints,
+		JoinIntentFingerprints: s.joinIntentFingerprints,
+		JoinIntents:            s.registeredIntents,
+	})
+	if err != nil {
+		logger.Warn("Failed to persist session state", logger.Ctx{"err": err})
+	}
 }
 
 // IntentCh returns a channel which allows publishing and consuming join intents.
@@ -158,6 +446,52 @@ func (s *Session) IntentCh() chan types.SessionJoinPost {
 	return s.joinIntents
 }
 
+// ProbeCh returns a channel which allows publishing and consuming the beacons of initiators
+// which probed this system using static discovery.
+// The channel doesn't get closed when stopping the session.
+func (s *Session) ProbeCh() chan multicast.Beacon {
+	return s.probes
+}
+
+// IncompatibleSystemCh returns a channel which allows consuming the systems which looked up this system
+// without supporting any of its versions.
+// Each system is reported once and the channel doesn't get closed when stopping the session.
+func (s *Session) IncompatibleSystemCh() chan types.SessionSystem {
+	return s.incompatibleSystems
+}
+
+// reportQuery publishes the system which sent the query of the given event if it doesn't support any of our versions.
+func (s *Session) reportQuery(event multicast.LookupEvent) {
+	var mismatchErr *multicast.VersionMismatchError
+	if !errors.As(event.Err, &mismatchErr) {
+		return
+	}
+
+	system := types.SessionSystem{
+		Name:       event.Info.Name,
+		Address:    event.Info.Address,
+		Version:    event.Info.Version,
+		MinVersion: event.Info.MinVersion,
+	}
+
+	key := strings.Join([]string{system.Name, system.Address, system.MinVersion, system.Version}, "/")
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.reportedSystems[key] {
+		return
+	}
+
+	if len(s.reportedSystems) >= maxIncompatibleSystems {
+		logger.Warn("Not reporting incompatible system", logger.Ctx{"name": system.Name, "address": system.Address, "versions": mismatchErr.Versions.String()})
+		return
+	}
+
+	s.reportedSystems[key] = true
+	s.incompatibleSystems <- system
+}
+
 // ExitCh returns a channel which allows waiting on the current trust establishment session.
 func (s *Session) ExitCh() chan bool {
 	return s.exit
@@ -169,25 +503,44 @@ func (s *Session) Stop(cause error) error {
 	defer s.lock.Unlock()
 
 	// If a cause is provided also write it onto the session's websocket
-	// to notify the client.
-	if cause != nil {
+	// to notify the client, if there is any.
+	if cause != nil && s.gw != nil {
 		err := s.gw.WriteClose(cause)
 		if err != nil {
 			return fmt.Errorf("Failed to write session stop cause to websocket: %w", err)
 		}
 	}
 
-	if s.discovery != nil {
-		err := s.discovery.StopResponder()
+	for _, discovery := range s.discoveries {
+		err := discovery.StopResponder()
 		if err != nil {
 			return fmt.Errorf("Failed to stop multicast discovery: %w", err)
 		}
 	}
 
+	s.discoveries = nil
+	s.cancel()
+
+	// The session ended so it cannot be resumed anymore.
+	if s.statePath != "" {
+		err := os.Remove(s.statePath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("Failed to remove session state: %w", err)
+		}
+
+		s.statePath = ""
+	}
+
+	s.stopped = true
 	s.passphrase = ""
+	s.passphraseHash = nil
 	s.trustStore = make(map[string]x509.Certificate, 0)
+	s.allowedFingerprints = nil
 	s.joinIntentFingerprints = []string{}
+	s.registeredIntents = nil
+	s.restoredIntents = nil
 	s.failedAttempts = 0
+	s.limiter = newSourceLimiter()
 
 	// For idempotency don't try to close the channels twice.
 	select {
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"time"
+)
+
+const (
+	// SourceFailedAttemptBurst is the number of failed session join attempts a single source can make in quick succession.
+	SourceFailedAttemptBurst = 5
+
+	// SourceFailedAttemptInterval is the interval after which a single source regains one failed session join attempt.
+	SourceFailedAttemptInterval = 10 * time.Second
+
+	// SourceBanDuration is the duration for which a source gets banned from a session after exceeding its failed attempts.
+	SourceBanDuration = 5 * time.Minute
+)
+
+// sourceBucket tracks the failed attempts of a single source.
+type sourceBucket struct {
+	tokens      float64
+	lastFailure time.Time
+	bannedUntil time.Time
+}
+
+// sourceLimiter limits the failed session join attempts of each source using a token bucket.
+// Each failed attempt takes a token from the source's bucket which gets refilled over time.
+// A source which takes the last token gets banned temporarily.
+// The number of tracked sources is bounded by the session's total number of allowed failed attempts
+// as banned sources are rejected before their attempts are counted.
+type sourceLimiter struct {
+	burst       float64
+	interval    time.Duration
+	banDuration time.Duration
+	buckets     map[string]*sourceBucket
+}
+
+// newSourceLimiter returns a new sourceLimiter using the default limits.
+func newSourceLimiter() *sourceLimiter {
+	return &sourceLimiter{
+		burst:       SourceFailedAttemptBurst,
+		interval:    SourceFailedAttemptInterval,
+		banDuration: SourceBanDuration,
+		buckets:     make(map[string]*sourceBucket),
+	}
+}
+
+// bannedUntil returns the time until which the given source is banned.
+// The returned bool is false if the source isn't banned at the given time.
+func (l *sourceLimiter) bannedUntil(source string, now time.Time) (time.Time, bool) {
+	bucket, ok := l.buckets[source]
+	if !ok || !now.Before(bucket.bannedUntil) {
+		return time.Time{}, false
+	}
+
+	return bucket.bannedUntil, true
+}
+
+// fail registers a failed attempt of the given source at the given time.
+// It returns true if the source got banned due to this attempt.
+func (l *sourceLimiter) fail(source string, now time.Time) bool {
+	bucket, ok := l.buckets[source]
+	if !ok {
+		bucket = &sourceBucket{tokens: l.burst}
+		l.buckets[source] = bucket
+	} else {
+		// Refill the bucket for the time passed since the last failure.
+		bucket.tokens += float64(now.Sub(bucket.lastFailure)) / float64(l.interval)
+		if bucket.tokens > l.burst {
+			bucket.tokens = l.burst
+		}
+	}
+
+	bucket.lastFailure = now
+	bucket.tokens--
+
+	if bucket.tokens >= 1 {
+		return false
+	}
+
+	// Start with a full bucket once the ban is lifted.
+	bucket.bannedUntil = now.Add(l.banDuration)
+	bucket.lastFailure = bucket.bannedUntil
+	bucket.tokens = l.burst
+
+	return true
+}
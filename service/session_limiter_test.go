@@ -0,0 +1,76 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type sourceLimiterSuite struct {
+	suite.Suite
+}
+
+func TestSourceLimiterSuite(t *testing.T) {
+	suite.Run(t, new(sourceLimiterSuite))
+}
+
+func (s *sourceLimiterSuite) Test_fail() {
+	start := time.Now()
+
+	cases := []struct {
+		desc         string
+		failures     []time.Duration
+		source       string
+		at           time.Duration
+		expectBanned bool
+	}{
+		{
+			desc:         "Fewer failures than the burst",
+			failures:     []time.Duration{0, 0, 0, 0},
+			source:       "10.0.0.1",
+			at:           0,
+			expectBanned: false,
+		},
+		{
+			desc:         "Failures exceeding the burst",
+			failures:     []time.Duration{0, 0, 0, 0, 0},
+			source:       "10.0.0.1",
+			at:           0,
+			expectBanned: true,
+		},
+		{
+			desc:         "Failures of another source",
+			failures:     []time.Duration{0, 0, 0, 0, 0},
+			source:       "10.0.0.2",
+			at:           0,
+			expectBanned: false,
+		},
+		{
+			desc:         "Failures spread over time",
+			failures:     []time.Duration{0, SourceFailedAttemptInterval, 2 * SourceFailedAttemptInterval, 3 * SourceFailedAttemptInterval, 4 * SourceFailedAttemptInterval, 5 * SourceFailedAttemptInterval},
+			source:       "10.0.0.1",
+			at:           5 * SourceFailedAttemptInterval,
+			expectBanned: false,
+		},
+		{
+			desc:         "Ban expired",
+			failures:     []time.Duration{0, 0, 0, 0, 0},
+			source:       "10.0.0.1",
+			at:           SourceBanDuration,
+			expectBanned: false,
+		},
+	}
+
+	for i, c := range cases {
+		s.T().Logf("%d: %s", i, c.desc)
+
+		limiter := newSourceLimiter()
+		for _, failure := range c.failures {
+			limiter.fail("10.0.0.1", start.Add(failure))
+		}
+
+		_, banned := limiter.bannedUntil(c.source, start.Add(c.at))
+		s.Equal(c.expectBanned, banned)
+	}
+}
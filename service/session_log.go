@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+	microTypes "github.com/canonical/microcluster/v3/microcluster/types"
+
+	"github.com/canonical/microcloud/microcloud/api/types"
+	"github.com/canonical/microcloud/microcloud/database"
+)
+
+// maxPendingSessionLogEntries limits the number of entries kept in memory while the database isn't available.
+// This prevents unauthenticated peers from growing the memory usage without bounds.
+const maxPendingSessionLogEntries = 1000
+
+// SessionLog records the events of trust establishment sessions in the database.
+// Sessions can run before the database is available, e.g. on a joiner.
+// In this case the entries are kept in memory until they can be written.
+type SessionLog struct {
+	mu      sync.Mutex
+	pending []database.SessionLogEntry
+}
+
+// Record adds the given entry to the session log.
+// Failing to write the entry isn't fatal as the entry is kept and retried on the next write.
+func (l *SessionLog) Record(ctx context.Context, state microTypes.State, entry types.SessionLogEntry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	logger.Info("Recording session event", logger.Ctx{"role": entry.Role, "event": entry.Event, "name": entry.Name, "address": entry.Address, "fingerprint": entry.Fingerprint, "message": entry.Message})
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.pending) >= maxPendingSessionLogEntries {
+		l.pending = l.pending[1:]
+	}
+
+	l.pending = append(l.pending, database.SessionLogEntry{
+		Time:        entry.Time,
+		Role:        string(entry.Role),
+		Event:       string(entry.Event),
+		Name:        entry.Name,
+		Address:     entry.Address,
+		Fingerprint: entry.Fingerprint,
+		Version:     entry.Version,
+		Message:     entry.Message,
+	})
+
+	err := l.flush(ctx, state)
+	if err != nil {
+		logger.Warn("Failed to write session log", logger.Ctx{"err": err})
+	}
+}
+
+// Flush writes all the pending entries to the database.
+// It's a no-op if the database isn't yet available.
+func (l *SessionLog) Flush(ctx context.Context, state microTypes.State) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.flush(ctx, state)
+}
+
+// flush writes all the pending entries to the database.
+// The caller has to hold the lock.
+func (l *SessionLog) flush(ctx context.Context, state microTypes.State) error {
+	if len(l.pending) == 0 || state.Database().IsOpen(ctx) != nil {
+		return nil
+	}
+
+	err := state.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		for _, entry := range l.pending {
+			_, err := database.CreateSessionLogEntry(ctx, tx, entry)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to write session log entries: %w", err)
+	}
+
+	l.pending = nil
+
+	return nil
+}
+
+// Entries returns the entries of the session log ordered by time.
+// If event isn't empty, only entries of the given event are returned.
+// Entries which couldn't yet be written to the database are included too.
+func (l *SessionLog) Entries(ctx context.Context, state microTypes.State, event types.SessionEvent) ([]types.SessionLogEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err := l.flush(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+
+	var dbEntries []database.SessionLogEntry
+	if state.Database().IsOpen(ctx) == nil {
+		filters := []database.SessionLogEntryFilter{}
+		if event != "" {
+			eventStr := string(event)
+			filters = append(filters, database.SessionLogEntryFilter{Event: &eventStr})
+		}
+
+		err = state.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+			dbEntries, err = database.GetSessionLogEntries(ctx, tx, filters...)
+
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read session log entries: %w", err)
+		}
+	}
+
+	entries := make([]types.SessionLogEntry, 0, len(dbEntries)+len(l.pending))
+	for _, entry := range append(dbEntries, l.pending...) {
+		if event != "" && entry.Event != string(event) {
+			continue
+		}
+
+		entries = append(entries, types.SessionLogEntry{
+			Time:        entry.Time,
+			Role:        types.SessionRole(entry.Role),
+			Event:       types.SessionEvent(entry.Event),
+			Name:        entry.Name,
+			Address:     entry.Address,
+			Fingerprint: entry.Fingerprint,
+			Version:     entry.Version,
+			Message:     entry.Message,
+		})
+	}
+
+	return entries, nil
+}
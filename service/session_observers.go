@@ -0,0 +1,76 @@
+package service
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/canonical/microcloud/microcloud/api/types"
+)
+
+// sessionObserverBuffer is the number of messages buffered for each observer.
+// Observers which don't keep up miss messages instead of blocking the session.
+const sessionObserverBuffer = 64
+
+// SessionObservers fans out the messages of the trust establishment sessions to any number of read-only observers.
+// Observers stay subscribed across sessions until they unsubscribe.
+type SessionObservers struct {
+	lock      sync.Mutex
+	observers map[chan types.Session]struct{}
+}
+
+// NewSessionObservers returns a new instance of SessionObservers.
+func NewSessionObservers() *SessionObservers {
+	return &SessionObservers{
+		observers: map[chan types.Session]struct{}{},
+	}
+}
+
+// Subscribe returns a channel receiving the messages of all sessions.
+// The returned function unsubscribes and closes the channel.
+func (o *SessionObservers) Subscribe() (<-chan types.Session, func()) {
+	ch := make(chan types.Session, sessionObserverBuffer)
+
+	o.lock.Lock()
+	o.observers[ch] = struct{}{}
+	o.lock.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			o.lock.Lock()
+			delete(o.observers, ch)
+			close(ch)
+			o.lock.Unlock()
+		})
+	}
+
+	return ch, unsubscribe
+}
+
+// Publish sends the given session message to every observer.
+// The passphrase is removed as observers only watch the session.
+func (o *SessionObservers) Publish(session types.Session) {
+	session.Passphrase = ""
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	for ch := range o.observers {
+		select {
+		case ch <- session:
+		default:
+		}
+	}
+}
+
+// PublishMessage publishes a message read from or written to the websocket of a session.
+// Messages which cannot be parsed as session messages are ignored.
+func (o *SessionObservers) PublishMessage(data []byte) {
+	session := types.Session{}
+	err := json.Unmarshal(data, &session)
+	if err != nil {
+		return
+	}
+
+	o.Publish(session)
+}
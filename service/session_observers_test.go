@@ -0,0 +1,60 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/canonical/microcloud/microcloud/api/types"
+)
+
+type sessionObserversSuite struct {
+	suite.Suite
+}
+
+func TestSessionObserversSuite(t *testing.T) {
+	suite.Run(t, new(sessionObserversSuite))
+}
+
+func (s *sessionObserversSuite) Test_publish() {
+	observers := NewSessionObservers()
+
+	first, unsubscribeFirst := observers.Subscribe()
+	second, unsubscribeSecond := observers.Subscribe()
+	defer unsubscribeSecond()
+
+	observers.PublishMessage([]byte(`{"passphrase":"a b c d","intent":{"name":"micro02"}}`))
+	observers.PublishMessage([]byte(`not a session`))
+
+	for _, ch := range []<-chan types.Session{first, second} {
+		session := <-ch
+		s.Equal("micro02", session.Intent.Name)
+		s.Empty(session.Passphrase)
+		s.Empty(ch)
+	}
+
+	// Unsubscribed observers don't receive any further messages.
+	unsubscribeFirst()
+	unsubscribeFirst()
+	observers.Publish(types.Session{Accepted: true})
+
+	_, ok := <-first
+	s.False(ok)
+
+	session := <-second
+	s.True(session.Accepted)
+}
+
+func (s *sessionObserversSuite) Test_publishSlowObserver() {
+	observers := NewSessionObservers()
+
+	ch, unsubscribe := observers.Subscribe()
+	defer unsubscribe()
+
+	// Publishing doesn't block if the observer doesn't keep up.
+	for range sessionObserverBuffer + 1 {
+		observers.Publish(types.Session{Error: "error"})
+	}
+
+	s.Len(ch, sessionObserverBuffer)
+}
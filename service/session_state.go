@@ -0,0 +1,156 @@
+package service
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/canonical/lxd/shared"
+
+	"github.com/canonical/microcloud/microcloud/api/types"
+	"github.com/canonical/microcloud/microcloud/multicast"
+)
+
+// SessionStateFile is the file inside of MicroCloud's state directory which contains the state of the active session.
+const SessionStateFile = "session.json"
+
+// sessionPassphraseIterations is the number of PBKDF2 iterations used to hash the passphrase of a persisted session.
+const sessionPassphraseIterations = 600000
+
+// sessionState is the state of a trust establishment session which is kept across daemon restarts.
+// Only a salted hash of the passphrase is stored, so resuming a session requires the client to provide the passphrase again.
+// Together with the session's start message and the announced info, this allows announcing the session
+// again once it's resumed.
+type sessionState struct {
+	Role                   types.SessionRole       `json:"role"`
+	PassphraseHash         passphraseHash          `json:"passphrase_hash"`
+	Start                  types.Session           `json:"start"`
+	Info                   multicast.ServerInfo    `json:"info"`
+	Deadline               time.Time               `json:"deadline"`
+	TrustStore             map[string]string       `json:"trust_store"`
+	AllowedFingerprints    []string                `json:"allowed_fingerprints"`
+	JoinIntentFingerprints []string                `json:"join_intent_fingerprints"`
+	JoinIntents            []types.SessionJoinPost `json:"join_intents"`
+}
+
+// passphraseHash is a salted hash of the passphrase of a trust establishment session.
+type passphraseHash struct {
+	Salt []byte `json:"salt"`
+	Hash []byte `json:"hash"`
+}
+
+// hashPassphrase returns the hash of the given passphrase using a new random salt.
+func hashPassphrase(passphrase string) (*passphraseHash, error) {
+	salt := make([]byte, 16)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to generate salt: %w", err)
+	}
+
+	hash, err := pbkdf2.Key(sha256.New, passphrase, salt, sessionPassphraseIterations, sha256.Size)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to hash passphrase: %w", err)
+	}
+
+	return &passphraseHash{Salt: salt, Hash: hash}, nil
+}
+
+// matches returns true if the given passphrase is the one from which the hash was derived.
+func (h passphraseHash) matches(passphrase string) bool {
+	if len(h.Salt) == 0 || len(h.Hash) == 0 {
+		return false
+	}
+
+	hash, err := pbkdf2.Key(sha256.New, passphrase, h.Salt, sessionPassphraseIterations, sha256.Size)
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(hash, h.Hash) == 1
+}
+
+// loadSessionState reads the persisted session state at the given path.
+// It returns nil if there isn't any persisted session, or if the session has already expired.
+func loadSessionState(path string, now time.Time) (*sessionState, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("Failed to read session state: %w", err)
+	}
+
+	state := &sessionState{}
+	err = json.Unmarshal(content, state)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse session state: %w", err)
+	}
+
+	if !now.Before(state.Deadline) {
+		err := os.Remove(path)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to remove expired session state: %w", err)
+		}
+
+		return nil, nil
+	}
+
+	return state, nil
+}
+
+// writeSessionState atomically writes the given session state to the given path.
+// The file is only readable by root as the trust store grants access to the system.
+func writeSessionState(path string, state sessionState) error {
+	content, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("Failed to encode session state: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	err = os.WriteFile(tmpPath, content, 0600)
+	if err != nil {
+		return fmt.Errorf("Failed to write session state: %w", err)
+	}
+
+	err = os.Rename(tmpPath, path)
+	if err != nil {
+		return fmt.Errorf("Failed to write session state: %w", err)
+	}
+
+	return nil
+}
+
+// encodeTrustStore returns the PEM encoded certificates of the given trust store.
+func encodeTrustStore(trustStore map[string]x509.Certificate) map[string]string {
+	encoded := make(map[string]string, len(trustStore))
+	for name, cert := range trustStore {
+		encoded[name] = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	}
+
+	return encoded
+}
+
+// decodeTrustStore parses the PEM encoded certificates of the given trust store.
+func decodeTrustStore(encoded map[string]string) (map[string]x509.Certificate, error) {
+	trustStore := make(map[string]x509.Certificate, len(encoded))
+	for name, content := range encoded {
+		cert, err := shared.ParseCert([]byte(content))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse certificate of %q: %w", name, err)
+		}
+
+		trustStore[name] = *cert
+	}
+
+	return trustStore, nil
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/canonical/microcloud/microcloud/api/types"
+)
+
+type sessionStateSuite struct {
+	suite.Suite
+}
+
+func TestSessionStateSuite(t *testing.T) {
+	suite.Run(t, new(sessionStateSuite))
+}
+
+func (s *sessionStateSuite) Test_loadSessionState() {
+	now := time.Now()
+
+	hash, err := hashPassphrase("one two three four")
+	s.Require().NoError(err)
+
+	cases := []struct {
+		desc        string
+		deadline    time.Time
+		passphrase  string
+		expectState bool
+		expectMatch bool
+	}{
+		{
+			desc:        "Active session with the same passphrase",
+			deadline:    now.Add(time.Minute),
+			passphrase:  "one two three four",
+			expectState: true,
+			expectMatch: true,
+		},
+		{
+			desc:        "Active session with a different passphrase",
+			deadline:    now.Add(time.Minute),
+			passphrase:  "one two three five",
+			expectState: true,
+		},
+		{
+			desc:       "Expired session",
+			deadline:   now.Add(-time.Minute),
+			passphrase: "one two three four",
+		},
+	}
+
+	for i, c := range cases {
+		s.T().Logf("%d: %s", i, c.desc)
+
+		path := filepath.Join(s.T().TempDir(), SessionStateFile)
+		err := writeSessionState(path, sessionState{
+			Role:                   types.SessionInitiating,
+			PassphraseHash:         *hash,
+			Start:                  types.Session{Address: "10.0.0.1", Interface: "eth0"},
+			Deadline:               c.deadline,
+			JoinIntentFingerprints: []string{"abcd"},
+			JoinIntents:            []types.SessionJoinPost{{Name: "micro02"}},
+		})
+		s.Require().NoError(err)
+
+		// The passphrase itself must never be written to disk.
+		content, err := os.ReadFile(path)
+		s.Require().NoError(err)
+		s.NotContains(string(content), "one two three four")
+		s.NotContains(string(content), `"passphrase"`)
+
+		state, err := loadSessionState(path, now)
+		s.Require().NoError(err)
+
+		if !c.expectState {
+			s.Nil(state)
+
+			// Expired sessions cannot be resumed anymore.
+			_, err = os.Stat(path)
+			s.True(os.IsNotExist(err))
+			continue
+		}
+
+		s.Require().NotNil(state)
+		s.Equal(types.SessionInitiating, state.Role)
+		s.Equal([]string{"abcd"}, state.JoinIntentFingerprints)
+		s.Equal("micro02", state.JoinIntents[0].Name)
+		s.Equal(c.expectMatch, state.PassphraseHash.matches(c.passphrase))
+		s.Equal("10.0.0.1", state.Start.Address)
+	}
+}
+
+func (s *sessionStateSuite) Test_loadSessionStateMissing() {
+	state, err := loadSessionState(filepath.Join(s.T().TempDir(), SessionStateFile), time.Now())
+	s.NoError(err)
+	s.Nil(state)
+}
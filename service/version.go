@@ -3,6 +3,7 @@ package service
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 
 	"golang.org/x/mod/semver"
@@ -94,3 +95,141 @@ func validateVersion(serviceType types.ServiceType, daemonVersion string) error
 
 	return nil
 }
+
+// VersionSkew is the level up to which the version of a service can differ between the initiator and a joiner.
+type VersionSkew string
+
+const (
+	// VersionSkewNone requires identical versions.
+	VersionSkewNone VersionSkew = "none"
+
+	// VersionSkewPatch allows different patch versions within the same minor version.
+	VersionSkewPatch VersionSkew = "patch"
+
+	// VersionSkewMinor allows different minor versions within the same major version.
+	VersionSkewMinor VersionSkew = "minor"
+)
+
+// VersionSkews contains all the supported version skew levels.
+var VersionSkews = []VersionSkew{VersionSkewNone, VersionSkewPatch, VersionSkewMinor}
+
+// versionNumberRegex matches the numeric part of a version string, e.g. 19.2.0 in "ceph-version: 19.2.0~git".
+var versionNumberRegex = regexp.MustCompile(`\d+(\.\d+){0,2}`)
+
+// VersionPolicy defines the version skew allowed between the services of the initiator and a joiner.
+type VersionPolicy struct {
+	// Default is the skew allowed for services without their own rule.
+	Default VersionSkew
+
+	// Services contains the skew allowed for individual services.
+	Services map[types.ServiceType]VersionSkew
+}
+
+// NewVersionPolicy returns a policy allowing patch-level skew for all services.
+func NewVersionPolicy() VersionPolicy {
+	return VersionPolicy{
+		Default:  VersionSkewPatch,
+		Services: map[types.ServiceType]VersionSkew{},
+	}
+}
+
+// ParseVersionPolicy returns a policy from the given rules.
+// A rule is either a skew level applying to all services (e.g. "minor") or a skew level applying to a single service
+// (e.g. "LXD=minor"). Services without a rule allow patch-level skew.
+func ParseVersionPolicy(rules []string) (VersionPolicy, error) {
+	policy := NewVersionPolicy()
+	for _, rule := range rules {
+		serviceName, level, hasService := strings.Cut(rule, "=")
+		if !hasService {
+			level = serviceName
+		}
+
+		skew := VersionSkew(strings.ToLower(level))
+		if !slices.Contains(VersionSkews, skew) {
+			return VersionPolicy{}, fmt.Errorf("Invalid version skew %q in rule %q (must be one of none, patch or minor)", level, rule)
+		}
+
+		if !hasService {
+			policy.Default = skew
+			continue
+		}
+
+		serviceType, err := parseServiceType(serviceName)
+		if err != nil {
+			return VersionPolicy{}, fmt.Errorf("Invalid version skew rule %q: %w", rule, err)
+		}
+
+		policy.Services[serviceType] = skew
+	}
+
+	return policy, nil
+}
+
+// parseServiceType returns the service type with the given case-insensitive name.
+func parseServiceType(name string) (types.ServiceType, error) {
+	for _, serviceType := range []types.ServiceType{types.MicroCloud, types.MicroCeph, types.MicroOVN, types.LXD} {
+		if strings.EqualFold(name, string(serviceType)) {
+			return serviceType, nil
+		}
+	}
+
+	return "", fmt.Errorf("Unknown service %q", name)
+}
+
+// rule returns the skew allowed for the given service and the rule defining it.
+func (p VersionPolicy) rule(serviceType types.ServiceType) (VersionSkew, string) {
+	skew, ok := p.Services[serviceType]
+	if ok {
+		return skew, fmt.Sprintf("%s=%s", serviceType, skew)
+	}
+
+	skew = p.Default
+	if skew == "" {
+		skew = VersionSkewPatch
+	}
+
+	return skew, fmt.Sprintf("default=%s", skew)
+}
+
+// Check returns an error naming the rule of the policy which rejects the joiner's version of the given service.
+func (p VersionPolicy) Check(serviceType types.ServiceType, initiatorVersion string, joinerVersion string) error {
+	if initiatorVersion == joinerVersion {
+		return nil
+	}
+
+	skew, rule := p.rule(serviceType)
+	if skew == VersionSkewNone {
+		return fmt.Errorf("%s version %q differs from %q (rule %q)", serviceType, joinerVersion, initiatorVersion, rule)
+	}
+
+	initiator := canonicalVersion(initiatorVersion)
+	joiner := canonicalVersion(joinerVersion)
+	if initiator == "" || joiner == "" {
+		return fmt.Errorf("%s versions %q and %q cannot be compared (rule %q)", serviceType, joinerVersion, initiatorVersion, rule)
+	}
+
+	switch skew {
+	case VersionSkewPatch:
+		if semver.MajorMinor(initiator) != semver.MajorMinor(joiner) {
+			return fmt.Errorf("%s version %q differs from %q by more than the patch version (rule %q)", serviceType, joinerVersion, initiatorVersion, rule)
+		}
+
+	case VersionSkewMinor:
+		if semver.Major(initiator) != semver.Major(joiner) {
+			return fmt.Errorf("%s version %q differs from %q by more than the minor version (rule %q)", serviceType, joinerVersion, initiatorVersion, rule)
+		}
+	}
+
+	return nil
+}
+
+// canonicalVersion returns the semantic version contained in the given version string.
+// An empty string is returned if the version string doesn't contain a version.
+func canonicalVersion(version string) string {
+	number := versionNumberRegex.FindString(version)
+	if number == "" {
+		return ""
+	}
+
+	return semver.Canonical("v" + cleanVersion(number))
+}
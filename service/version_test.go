@@ -148,3 +148,100 @@ func (s *versionSuite) Test_validateVersions() {
 		}
 	}
 }
+
+func (s *versionSuite) Test_VersionPolicy() {
+	cases := []struct {
+		desc             string
+		rules            []string
+		service          types.ServiceType
+		initiatorVersion string
+		joinerVersion    string
+		expectErr        string
+	}{
+		{
+			desc:             "Identical versions",
+			rules:            []string{"none"},
+			service:          types.LXD,
+			initiatorVersion: "5.21.2",
+			joinerVersion:    "5.21.2",
+		},
+		{
+			desc:             "Patch skew allowed by default",
+			service:          types.LXD,
+			initiatorVersion: "5.21.2",
+			joinerVersion:    "5.21.3",
+		},
+		{
+			desc:             "Minor skew rejected by default",
+			service:          types.LXD,
+			initiatorVersion: "5.21.2",
+			joinerVersion:    "5.22.0",
+			expectErr:        `rule "default=patch"`,
+		},
+		{
+			desc:             "Minor skew allowed for the service",
+			rules:            []string{"lxd=minor"},
+			service:          types.LXD,
+			initiatorVersion: "5.21.2",
+			joinerVersion:    "5.22.0",
+		},
+		{
+			desc:             "Minor skew allowed for another service",
+			rules:            []string{"MicroOVN=minor"},
+			service:          types.LXD,
+			initiatorVersion: "5.21.2",
+			joinerVersion:    "5.22.0",
+			expectErr:        `rule "default=patch"`,
+		},
+		{
+			desc:             "Major skew rejected",
+			rules:            []string{"minor"},
+			service:          types.LXD,
+			initiatorVersion: "5.21.2",
+			joinerVersion:    "6.1",
+			expectErr:        `rule "default=minor"`,
+		},
+		{
+			desc:             "Patch skew rejected for the service",
+			rules:            []string{"minor", "MicroCeph=none"},
+			service:          types.MicroCeph,
+			initiatorVersion: "ceph-version: 19.2.0~git",
+			joinerVersion:    "ceph-version: 19.2.1~git",
+			expectErr:        `rule "MicroCeph=none"`,
+		},
+		{
+			desc:             "Patch skew of MicroOVN with leading zeros",
+			service:          types.MicroOVN,
+			initiatorVersion: "24.03.1",
+			joinerVersion:    "24.03.2",
+		},
+		{
+			desc:             "Versions cannot be compared",
+			service:          types.MicroCloud,
+			initiatorVersion: "2.1.0",
+			joinerVersion:    "unknown",
+			expectErr:        "cannot be compared",
+		},
+	}
+
+	for i, c := range cases {
+		s.T().Logf("%d: %s", i, c.desc)
+
+		policy, err := ParseVersionPolicy(c.rules)
+		s.Require().NoError(err)
+
+		err = policy.Check(c.service, c.initiatorVersion, c.joinerVersion)
+		if c.expectErr != "" {
+			s.ErrorContains(err, c.expectErr)
+		} else {
+			s.NoError(err)
+		}
+	}
+}
+
+func (s *versionSuite) Test_ParseVersionPolicyInvalid() {
+	for _, rules := range [][]string{{"major"}, {"LXD=major"}, {"MicroK8s=minor"}} {
+		_, err := ParseVersionPolicy(rules)
+		s.Error(err)
+	}
+}
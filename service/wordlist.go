@@ -1311,5 +1311,5 @@ zucchini`
 // Wordlist is a slice of words from [wordlist] used for generating passphrases.
 var Wordlist []string = strings.Split(wordlist, "\n")
 
-// PassphraseWordCount is the number of words in a passphrase.
+// PassphraseWordCount is the default number of words in a passphrase.
 const PassphraseWordCount uint8 = 4